@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
-	"time"
+	"sync"
+	"sync/atomic"
 
 	"github.com/baswilson/pika/internal/ai"
 	"github.com/baswilson/pika/internal/config"
@@ -12,7 +14,27 @@ import (
 	"github.com/joho/godotenv"
 )
 
+// The backfill tool (re-)embeds every memory that has no embedding or whose
+// embedding was produced by a different model than the target one.
+//
+// It is safe to interrupt: finished memories are recorded with their model, so
+// running it again resumes with whatever is left.
+//
+// Usage:
+//
+//	go run ./cmd/backfill                          # embed missing memories with the configured model
+//	go run ./cmd/backfill -model mxbai-embed-large # migrate everything to a new model
+//	go run ./cmd/backfill -provider hash           # switch to the built-in offline embedder
+//	go run ./cmd/backfill -dry-run                 # report what would change
 func main() {
+	provider := flag.String("provider", "", "embedding provider to migrate to: ollama, openai or hash (default: configured EMBEDDING_PROVIDER)")
+	model := flag.String("model", "", "embedding model to migrate to (default: the provider's configured model)")
+	concurrency := flag.Int("concurrency", 4, "number of concurrent embedding requests")
+	batchSize := flag.Int("batch", 64, "number of memories fetched per batch")
+	embedBatch := flag.Int("embed-batch", 16, "number of memories embedded per request")
+	dryRun := flag.Bool("dry-run", false, "report what would be re-embedded without writing anything")
+	flag.Parse()
+
 	log.Println("PIKA Memory Embedding Backfill Tool")
 	log.Println("====================================")
 
@@ -20,6 +42,20 @@ func main() {
 	_ = godotenv.Load()
 	cfg := config.Load()
 
+	if *provider != "" {
+		cfg.EmbeddingProvider = *provider
+	}
+	if *model != "" {
+		cfg.OllamaEmbedModel = *model
+		cfg.EmbeddingModel = *model
+	}
+	if *concurrency < 1 {
+		*concurrency = 1
+	}
+	if *embedBatch < 1 {
+		*embedBatch = 1
+	}
+
 	// Connect to SQLite database
 	driver, err := database.NewSQLiteDriver(cfg.DatabasePath)
 	if err != nil {
@@ -38,51 +74,84 @@ func main() {
 	db := driver.DB()
 	memoryStore := memory.NewStore(db)
 	aiService := ai.NewService(cfg, memoryStore)
+	targetModel := aiService.EmbeddingModel()
 
 	ctx := context.Background()
-	batchSize := 50
-	processed := 0
-	failed := 0
-	delayBetweenRequests := 100 * time.Millisecond
 
-	log.Printf("Starting backfill (batch size: %d, delay: %v)", batchSize, delayBetweenRequests)
+	stats, err := memoryStore.EmbeddingStats(ctx)
+	if err != nil {
+		log.Fatalf("Failed to read embedding stats: %v", err)
+	}
+	pending := 0
+	for m, count := range stats {
+		name := m
+		if name == "" {
+			name = "(none)"
+		}
+		log.Printf("  %-24s %d memories", name, count)
+		if m != targetModel {
+			pending += count
+		}
+	}
+	log.Printf("Target model: %s (%d memories to embed)", targetModel, pending)
+
+	if *dryRun {
+		log.Println("Dry run - no changes written")
+		return
+	}
+
+	log.Printf("Starting backfill (batch size: %d, embed batch: %d, concurrency: %d)", *batchSize, *embedBatch, *concurrency)
+
+	var processed, failed int64
+	cursor := ""
 
 	for {
-		// Get memories without embeddings
-		memories, err := memoryStore.GetWithoutEmbedding(ctx, batchSize)
+		// Page by ID so memories that fail are not fetched again in this run
+		memories, err := memoryStore.GetNeedingEmbedding(ctx, targetModel, cursor, *batchSize)
 		if err != nil {
-			log.Fatalf("Failed to get memories without embeddings: %v", err)
+			log.Fatalf("Failed to get memories to embed: %v", err)
 		}
 
 		if len(memories) == 0 {
 			log.Println("No more memories to process")
 			break
 		}
+		cursor = memories[len(memories)-1].ID
 
 		log.Printf("Processing batch of %d memories...", len(memories))
 
-		for _, m := range memories {
-			// Generate embedding
-			embedding, err := aiService.GenerateEmbedding(ctx, m.Content)
-			if err != nil {
-				log.Printf("  FAILED [%s]: %v", m.ID[:8], err)
-				failed++
-				continue
-			}
-
-			// Update memory with embedding
-			if err := memoryStore.UpdateEmbedding(ctx, m.ID, embedding); err != nil {
-				log.Printf("  FAILED [%s]: failed to update: %v", m.ID[:8], err)
-				failed++
-				continue
+		jobs := make(chan []*memory.Memory)
+		var wg sync.WaitGroup
+		for i := 0; i < *concurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for chunk := range jobs {
+					embedChunk(ctx, aiService, memoryStore, targetModel, chunk, &processed, &failed)
+				}
+			}()
+		}
+		for start := 0; start < len(memories); start += *embedBatch {
+			end := start + *embedBatch
+			if end > len(memories) {
+				end = len(memories)
 			}
+			jobs <- memories[start:end]
+		}
+		close(jobs)
+		wg.Wait()
+	}
 
-			processed++
-			log.Printf("  OK [%s]: \"%s...\" (%d dims)", m.ID[:8], truncate(m.Content, 40), len(embedding))
-
-			// Rate limiting delay
-			time.Sleep(delayBetweenRequests)
+	// Switch the app over once every memory lives in the new vector space
+	switching := *provider != "" || *model != ""
+	if switching && failed == 0 {
+		if err := saveEmbeddingConfig(ctx, driver, *provider, *model); err != nil {
+			log.Printf("Failed to save embedding config: %v", err)
+		} else {
+			log.Printf("Configured embedding model set to %s", targetModel)
 		}
+	} else if switching {
+		log.Printf("Embedding model not switched: %d memories failed, run again to retry", failed)
 	}
 
 	log.Println("====================================")
@@ -91,6 +160,53 @@ func main() {
 	log.Printf("  Failed:    %d", failed)
 }
 
+// saveEmbeddingConfig persists the provider and model chosen on the command line
+func saveEmbeddingConfig(ctx context.Context, driver *database.SQLiteDriver, provider, model string) error {
+	if provider != "" {
+		if err := driver.SetConfig(ctx, "embedding_provider", provider); err != nil {
+			return err
+		}
+	}
+	if model != "" {
+		if err := driver.SetConfig(ctx, "ollama_embed_model", model); err != nil {
+			return err
+		}
+		if err := driver.SetConfig(ctx, "embedding_model", model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// embedChunk embeds a group of memories with one batch request and stores the results
+func embedChunk(ctx context.Context, embedder memory.EmbeddingGenerator, store *memory.Store, model string, chunk []*memory.Memory, processed, failed *int64) {
+	texts := make([]string, len(chunk))
+	for i, m := range chunk {
+		texts[i] = m.Content
+	}
+
+	embeddings, err := embedder.GenerateEmbeddings(ctx, texts)
+	if err != nil {
+		for _, m := range chunk {
+			log.Printf("  FAILED [%s]: %v", truncate(m.ID, 8), err)
+		}
+		atomic.AddInt64(failed, int64(len(chunk)))
+		return
+	}
+
+	for i, m := range chunk {
+		// Update memory with embedding
+		if err := store.UpdateEmbedding(ctx, m.ID, embeddings[i], model); err != nil {
+			log.Printf("  FAILED [%s]: failed to update: %v", truncate(m.ID, 8), err)
+			atomic.AddInt64(failed, 1)
+			continue
+		}
+
+		atomic.AddInt64(processed, 1)
+		log.Printf("  OK [%s]: \"%s...\" (%d dims)", truncate(m.ID, 8), truncate(m.Content, 40), len(embeddings[i]))
+	}
+}
+
 func truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s
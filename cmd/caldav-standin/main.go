@@ -0,0 +1,31 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/baswilson/pika/internal/calendar/caldavtest"
+)
+
+// The caldav-standin tool runs an in-memory CalDAV calendar for trying out
+// PIKA's CalDAV backend without a Fastmail or Nextcloud account. Events are
+// lost when it stops.
+//
+// Usage:
+//
+//	go run ./cmd/caldav-standin -addr :5232 -user pika -password pika
+//
+// and start PIKA with
+//
+//	CALENDAR_BACKEND=caldav CALDAV_URL=http://localhost:5232/calendars/pika/ \
+//	CALDAV_USERNAME=pika CALDAV_PASSWORD=pika make dev-web
+func main() {
+	addr := flag.String("addr", ":5232", "address to listen on")
+	user := flag.String("user", "", "basic auth username (default: no auth)")
+	password := flag.String("password", "", "basic auth password")
+	flag.Parse()
+
+	log.Printf("CalDAV stand-in listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, caldavtest.NewServer(*user, *password)))
+}
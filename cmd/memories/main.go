@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/baswilson/pika/internal/ai"
+	"github.com/baswilson/pika/internal/config"
+	"github.com/baswilson/pika/internal/database"
+	"github.com/baswilson/pika/internal/knowledge"
+	"github.com/baswilson/pika/internal/memory"
+	"github.com/joho/godotenv"
+)
+
+// The memories tool exports and imports memories in PIKA's portable
+// JSON Lines format, for moving them between machines or seeding a new install.
+//
+// Usage:
+//
+//	go run ./cmd/memories export -o memories.jsonl [-embeddings]
+//	go run ./cmd/memories export -format markdown -o memories.md
+//	go run ./cmd/memories import [-mode merge|dedup] [-extract=false] memories.jsonl
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	// Load environment
+	_ = godotenv.Load()
+	cfg := config.Load()
+
+	// Connect to SQLite database
+	driver, err := database.NewSQLiteDriver(cfg.DatabasePath)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer driver.Close()
+
+	// Initialize schema (no-op if already exists)
+	if err := driver.Initialize(context.Background()); err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	memoryStore := memory.NewStore(driver.DB())
+	ctx := context.Background()
+
+	switch os.Args[1] {
+	case "export":
+		runExport(ctx, memoryStore, os.Args[2:])
+	case "import":
+		// Imported memories without a usable embedding are embedded on the way in
+		aiService := ai.NewService(cfg, memoryStore)
+		memoryStore.SetEmbedder(aiService)
+		knowledgeStore := knowledge.NewStore(driver.DB())
+		knowledgeStore.SetExtractor(aiService)
+		runImport(ctx, memoryStore, knowledgeStore, os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func runExport(ctx context.Context, store *memory.Store, args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", "jsonl", "export format: jsonl or markdown")
+	output := fs.String("o", "", "output file (default: stdout)")
+	withEmbeddings := fs.Bool("embeddings", false, "include embeddings and their model name (jsonl only)")
+	fs.Parse(args)
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Fatalf("Failed to create %s: %v", *output, err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	var err error
+	switch *format {
+	case "jsonl":
+		err = store.Export(ctx, w, *withEmbeddings)
+	case "markdown", "md":
+		err = store.ExportMarkdown(ctx, w)
+	default:
+		log.Fatalf("Unknown format: %s", *format)
+	}
+	if err != nil {
+		log.Fatalf("Export failed: %v", err)
+	}
+
+	if *output != "" {
+		log.Printf("Memories exported to %s", *output)
+	}
+}
+
+func runImport(ctx context.Context, store *memory.Store, knowledgeStore *knowledge.Store, args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	mode := fs.String("mode", memory.ImportMerge, "import mode: merge (skip existing IDs) or dedup (also skip duplicate content)")
+	extract := fs.Bool("extract", true, "extract the knowledge graph from imported memories, as the server does for new ones")
+	fs.Parse(args)
+
+	if *extract {
+		knowledgeStore.Attach(store)
+	}
+
+	var r io.Reader = os.Stdin
+	if fs.NArg() > 0 {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			log.Fatalf("Failed to open %s: %v", fs.Arg(0), err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	result, err := store.Import(ctx, r, *mode)
+	if result == nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+	if *extract && result.Imported > 0 {
+		log.Printf("Extracting knowledge from %d imported memories...", result.Imported)
+		store.WaitHooks()
+	}
+	if err != nil {
+		log.Fatalf("Import failed after %d memories: %v", result.Imported, err)
+	}
+
+	log.Printf("Import complete!")
+	log.Printf("  Imported:    %d", result.Imported)
+	log.Printf("  Skipped:     %d", result.Skipped)
+	log.Printf("  Re-embedded: %d", result.Reembedded)
+	for _, e := range result.Errors {
+		log.Printf("  ERROR %s", e)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: memories export [-format jsonl|markdown] [-o file] [-embeddings]")
+	fmt.Fprintln(os.Stderr, "       memories import [-mode merge|dedup] [-extract=false] [file]")
+	os.Exit(2)
+}
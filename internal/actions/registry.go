@@ -3,10 +3,12 @@ package actions
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
 	"net/http"
+	"net/mail"
 	"net/url"
 	"strings"
 	"time"
@@ -15,6 +17,7 @@ import (
 	"github.com/baswilson/pika/internal/calendar"
 	"github.com/baswilson/pika/internal/memory"
 	"github.com/baswilson/pika/internal/reminder"
+	"github.com/baswilson/pika/internal/rrule"
 )
 
 // ActionType represents the type of action
@@ -24,6 +27,8 @@ const (
 	ActionSaveToCalendar   ActionType = "SAVE_TO_CALENDAR"
 	ActionEditCalendar     ActionType = "EDIT_CALENDAR_EVENT"
 	ActionDeleteCalendar   ActionType = "DELETE_CALENDAR_EVENT"
+	ActionFindFreeTime     ActionType = "FIND_FREE_TIME"
+	ActionListCalendar     ActionType = "LIST_CALENDAR_EVENTS"
 	ActionSaveMemory       ActionType = "SAVE_MEMORY"
 	ActionGetWeather       ActionType = "GET_WEATHER"
 	ActionSearchPokemon    ActionType = "SEARCH_POKEMON"
@@ -34,6 +39,8 @@ const (
 	ActionDeleteReminder   ActionType = "DELETE_REMINDER"
 	ActionListReminders    ActionType = "LIST_REMINDERS"
 	ActionCompleteReminder ActionType = "COMPLETE_REMINDER"
+	ActionSnoozeReminder   ActionType = "SNOOZE_REMINDER"
+	ActionAckReminder      ActionType = "ACKNOWLEDGE_REMINDER"
 	ActionStartGame        ActionType = "START_GAME"
 	ActionGameMove         ActionType = "GAME_MOVE"
 )
@@ -70,6 +77,8 @@ func NewRegistry(memoryStore *memory.Store, calendarService *calendar.Service, r
 	r.Register(ActionSaveToCalendar, r.handleSaveToCalendar)
 	r.Register(ActionEditCalendar, r.handleEditCalendar)
 	r.Register(ActionDeleteCalendar, r.handleDeleteCalendar)
+	r.Register(ActionFindFreeTime, r.handleFindFreeTime)
+	r.Register(ActionListCalendar, r.handleListCalendarEvents)
 	r.Register(ActionSaveMemory, r.handleSaveMemory)
 	r.Register(ActionGetWeather, r.handleGetWeather)
 	r.Register(ActionSearchPokemon, r.handleSearchPokemon)
@@ -79,6 +88,8 @@ func NewRegistry(memoryStore *memory.Store, calendarService *calendar.Service, r
 	r.Register(ActionDeleteReminder, r.handleDeleteReminder)
 	r.Register(ActionListReminders, r.handleListReminders)
 	r.Register(ActionCompleteReminder, r.handleCompleteReminder)
+	r.Register(ActionSnoozeReminder, r.handleSnoozeReminder)
+	r.Register(ActionAckReminder, r.handleAcknowledgeReminder)
 	r.Register(ActionStartGame, r.handleStartGame)
 	r.Register(ActionGameMove, r.handleGameMove)
 
@@ -92,7 +103,12 @@ func (r *Registry) Register(actionType ActionType, handler ActionHandler) {
 
 // Execute runs an action and returns the result
 func (r *Registry) Execute(action ai.Action) *ActionResult {
-	ctx := context.Background()
+	return r.ExecuteContext(context.Background(), action)
+}
+
+// ExecuteContext runs an action with a context carrying request details
+// such as the memory source
+func (r *Registry) ExecuteContext(ctx context.Context, action ai.Action) *ActionResult {
 	actionType := ActionType(action.Type)
 
 	handler, ok := r.handlers[actionType]
@@ -118,6 +134,9 @@ func (r *Registry) handleSaveToCalendar(ctx context.Context, data map[string]int
 	startTime, _ := data["start_time"].(string)
 	endTime, _ := data["end_time"].(string)
 	location, _ := data["location"].(string)
+	calendarName, _ := data["calendar"].(string)
+	allowConflicts, _ := data["allow_conflicts"].(bool)
+	rule, _ := data["recurrence"].(string)
 
 	if title == "" || startTime == "" {
 		return &ActionResult{
@@ -133,12 +152,28 @@ func (r *Registry) handleSaveToCalendar(ctx context.Context, data map[string]int
 		}
 	}
 
-	event, err := r.calendar.CreateEvent(ctx, title, description, startTime, endTime, location)
-	if err != nil {
-		return &ActionResult{
-			Success: false,
-			Error:   err.Error(),
+	opts := calendar.CreateOptions{Calendar: calendarName, AllowConflicts: allowConflicts}
+	if rule != "" {
+		recurrence, err := calendar.ParseRecurrence(rule)
+		if err != nil {
+			return &ActionResult{
+				Success: false,
+				Error:   err.Error(),
+			}
+		}
+		opts.Recurrence = recurrence
+	}
+	if attendees, ok := data["attendees"]; ok {
+		resolved, result := r.resolveAttendees(ctx, attendees)
+		if result != nil {
+			return result
 		}
+		opts.Attendees = resolved
+	}
+
+	event, err := r.calendar.CreateEventWithOptions(ctx, title, description, startTime, endTime, location, opts)
+	if err != nil {
+		return calendarErrorResult(err)
 	}
 
 	return &ActionResult{
@@ -147,6 +182,78 @@ func (r *Registry) handleSaveToCalendar(ctx context.Context, data map[string]int
 	}
 }
 
+// UnknownContactsResult names the attendees of a failed calendar change
+// whose email address is not known
+type UnknownContactsResult struct {
+	Names []string `json:"unknown_contacts"`
+}
+
+// resolveAttendees reads attendees given as a list or comma-separated
+// string of email addresses or names, looking names up in the contacts
+// remembered. The result is a failure naming any unknown contacts.
+func (r *Registry) resolveAttendees(ctx context.Context, value interface{}) ([]*calendar.Attendee, *ActionResult) {
+	var entries []string
+	switch v := value.(type) {
+	case string:
+		entries = strings.Split(v, ",")
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				entries = append(entries, s)
+			}
+		}
+	}
+
+	var attendees []*calendar.Attendee
+	var unknown []string
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if addr, err := mail.ParseAddress(entry); err == nil {
+			attendees = append(attendees, &calendar.Attendee{Email: addr.Address, Name: addr.Name})
+			continue
+		}
+
+		email, err := r.memory.FindEmail(ctx, entry)
+		if err != nil {
+			return nil, &ActionResult{
+				Success: false,
+				Error:   fmt.Sprintf("failed to look up %s: %v", entry, err),
+			}
+		}
+		if email == "" {
+			unknown = append(unknown, entry)
+			continue
+		}
+		attendees = append(attendees, &calendar.Attendee{Email: email, Name: entry})
+	}
+
+	if len(unknown) > 0 {
+		return nil, &ActionResult{
+			Success: false,
+			Data:    &UnknownContactsResult{Names: unknown},
+			Error:   fmt.Sprintf("no email address known for %s", strings.Join(unknown, " and ")),
+		}
+	}
+	return attendees, nil
+}
+
+// calendarErrorResult reports a failed calendar change. Conflicts carry the
+// overlapping events and alternatives so the user can be asked what to do.
+func calendarErrorResult(err error) *ActionResult {
+	result := &ActionResult{
+		Success: false,
+		Error:   err.Error(),
+	}
+	var conflict *calendar.ConflictError
+	if errors.As(err, &conflict) {
+		result.Data = conflict
+	}
+	return result
+}
+
 // handleSaveMemory saves a memory
 func (r *Registry) handleSaveMemory(ctx context.Context, data map[string]interface{}) *ActionResult {
 	content, _ := data["content"].(string)
@@ -173,7 +280,25 @@ func (r *Registry) handleSaveMemory(ctx context.Context, data map[string]interfa
 		}
 	}
 
-	mem, err := r.memory.Create(ctx, content, importance, tags)
+	// Temporary memories carry an expiry ("I'm working from home today")
+	var opts memory.CreateOptions
+	if v, ok := data["expires_at"].(string); ok && v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return &ActionResult{
+				Success: false,
+				Error:   fmt.Sprintf("invalid expires_at format: %v", err),
+			}
+		}
+		opts.ExpiresAt = &t
+	}
+
+	// Link the memory to the conversation turn that produced it
+	if src, ok := memory.SourceFromContext(ctx); ok {
+		opts.Source = &src
+	}
+
+	mem, err := r.memory.CreateWithOptions(ctx, content, importance, tags, opts)
 	if err != nil {
 		return &ActionResult{
 			Success: false,
@@ -191,8 +316,23 @@ func (r *Registry) handleSaveMemory(ctx context.Context, data map[string]interfa
 func (r *Registry) handleEditCalendar(ctx context.Context, data map[string]interface{}) *ActionResult {
 	eventID, _ := data["event_id"].(string)
 	searchTitle, _ := data["search_title"].(string)
+	scope, _ := data["scope"].(string)
 
-	// If no event_id provided, try to find by title
+	opts := calendar.UpdateOptions{Scope: scope}
+	opts.AllowConflicts, _ = data["allow_conflicts"].(bool)
+	if v, ok := data["occurrence_start"].(string); ok && v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return &ActionResult{
+				Success: false,
+				Error:   fmt.Sprintf("invalid occurrence_start format: %v", err),
+			}
+		}
+		opts.Occurrence = t
+	}
+
+	// If no event_id provided, try to find by title. A recurring event is
+	// found at its next occurrence.
 	if eventID == "" && searchTitle != "" {
 		events, err := r.calendar.FindEventByTitle(ctx, searchTitle)
 		if err != nil || len(events) == 0 {
@@ -202,6 +342,9 @@ func (r *Registry) handleEditCalendar(ctx context.Context, data map[string]inter
 			}
 		}
 		eventID = events[0].ID
+		if opts.Occurrence.IsZero() && len(events[0].Recurrence) > 0 {
+			opts.Occurrence = events[0].StartTime
+		}
 	}
 
 	if eventID == "" {
@@ -211,6 +354,60 @@ func (r *Registry) handleEditCalendar(ctx context.Context, data map[string]inter
 		}
 	}
 
+	// "none" ends a recurring event
+	if rule, ok := data["recurrence"].(string); ok && rule != "" {
+		var recurrence []string
+		if !strings.EqualFold(rule, "none") {
+			parsed, err := calendar.ParseRecurrence(rule)
+			if err != nil {
+				return &ActionResult{
+					Success: false,
+					Error:   err.Error(),
+				}
+			}
+			recurrence = parsed
+		}
+		opts.Recurrence = &recurrence
+	}
+
+	// attendees replaces the list, add_attendees extends it
+	if v, ok := data["attendees"]; ok {
+		attendees, result := r.resolveAttendees(ctx, v)
+		if result != nil {
+			return result
+		}
+		opts.Attendees = &attendees
+	}
+	if v, ok := data["add_attendees"]; ok {
+		added, result := r.resolveAttendees(ctx, v)
+		if result != nil {
+			return result
+		}
+		if opts.Attendees == nil {
+			event, err := r.calendar.GetEventByID(ctx, eventID)
+			if err != nil {
+				return &ActionResult{
+					Success: false,
+					Error:   fmt.Sprintf("event not found: %v", err),
+				}
+			}
+			opts.Attendees = &event.Attendees
+		}
+		attendees := *opts.Attendees
+		for _, a := range added {
+			invited := false
+			for _, existing := range attendees {
+				if strings.EqualFold(existing.Email, a.Email) {
+					invited = true
+				}
+			}
+			if !invited {
+				attendees = append(attendees, a)
+			}
+		}
+		opts.Attendees = &attendees
+	}
+
 	// Get optional update fields
 	var title, description, startTime, endTime, location *string
 	if v, ok := data["title"].(string); ok && v != "" {
@@ -229,12 +426,9 @@ func (r *Registry) handleEditCalendar(ctx context.Context, data map[string]inter
 		location = &v
 	}
 
-	event, err := r.calendar.UpdateEvent(ctx, eventID, title, description, startTime, endTime, location)
+	event, err := r.calendar.UpdateEventWithOptions(ctx, eventID, title, description, startTime, endTime, location, opts)
 	if err != nil {
-		return &ActionResult{
-			Success: false,
-			Error:   err.Error(),
-		}
+		return calendarErrorResult(err)
 	}
 
 	return &ActionResult{
@@ -280,6 +474,170 @@ func (r *Registry) handleDeleteCalendar(ctx context.Context, data map[string]int
 	}
 }
 
+// CalendarEventsResult lists the events found for a calendar query
+type CalendarEventsResult struct {
+	Events   []*calendar.Event `json:"events"`
+	From     time.Time         `json:"from"`
+	To       time.Time         `json:"to"`
+	Query    string            `json:"query,omitempty"`
+	Calendar string            `json:"calendar,omitempty"`
+}
+
+// handleListCalendarEvents lists the events in a time range, optionally
+// matching a text or in one calendar
+func (r *Registry) handleListCalendarEvents(ctx context.Context, data map[string]interface{}) *ActionResult {
+	startStr, _ := data["start"].(string)
+	endStr, _ := data["end"].(string)
+	query, _ := data["query"].(string)
+	calendarName, _ := data["calendar"].(string)
+
+	from := time.Now()
+	if startStr != "" {
+		t, err := time.Parse(time.RFC3339, startStr)
+		if err != nil {
+			return &ActionResult{
+				Success: false,
+				Error:   fmt.Sprintf("invalid start format: %v", err),
+			}
+		}
+		from = t
+	}
+
+	// Default to the coming week
+	to := from.AddDate(0, 0, 7)
+	if endStr != "" {
+		t, err := time.Parse(time.RFC3339, endStr)
+		if err != nil {
+			return &ActionResult{
+				Success: false,
+				Error:   fmt.Sprintf("invalid end format: %v", err),
+			}
+		}
+		to = t
+	}
+
+	limit := int(getFloat(data, "limit"))
+	if limit <= 0 {
+		limit = 20
+	}
+
+	events, err := r.calendar.SearchEvents(ctx, calendar.EventQuery{
+		From:     from,
+		To:       to,
+		Text:     query,
+		Calendar: calendarName,
+		Limit:    limit,
+	})
+	if err != nil {
+		return &ActionResult{
+			Success: false,
+			Error:   err.Error(),
+		}
+	}
+	if events == nil {
+		events = []*calendar.Event{}
+	}
+
+	return &ActionResult{
+		Success: true,
+		Data: &CalendarEventsResult{
+			Events:   events,
+			From:     from,
+			To:       to,
+			Query:    query,
+			Calendar: calendarName,
+		},
+	}
+}
+
+// FreeTimeResult lists the best slots found for an event, best first
+type FreeTimeResult struct {
+	Slots           []*calendar.Slot `json:"slots"`
+	DurationMinutes int              `json:"duration_minutes"`
+	From            time.Time        `json:"from"`
+	To              time.Time        `json:"to"`
+}
+
+// handleFindFreeTime finds free slots for an event of a given length
+func (r *Registry) handleFindFreeTime(ctx context.Context, data map[string]interface{}) *ActionResult {
+	startStr, _ := data["start"].(string)
+	endStr, _ := data["end"].(string)
+
+	from := time.Now()
+	if startStr != "" {
+		t, err := time.Parse(time.RFC3339, startStr)
+		if err != nil {
+			return &ActionResult{
+				Success: false,
+				Error:   fmt.Sprintf("invalid start format: %v", err),
+			}
+		}
+		from = t
+	}
+
+	// Default to searching the coming week
+	to := from.AddDate(0, 0, 7)
+	if endStr != "" {
+		t, err := time.Parse(time.RFC3339, endStr)
+		if err != nil {
+			return &ActionResult{
+				Success: false,
+				Error:   fmt.Sprintf("invalid end format: %v", err),
+			}
+		}
+		to = t
+	}
+
+	duration := int(getFloat(data, "duration_minutes"))
+	if duration <= 0 {
+		duration = 60
+	}
+
+	q := r.calendar.NewFreeTimeQuery(from, to, time.Duration(duration)*time.Minute)
+	for key, field := range map[string]*int{"work_start": &q.WorkStart, "work_end": &q.WorkEnd} {
+		if v, ok := data[key].(string); ok && v != "" {
+			minutes, err := calendar.ParseClock(v)
+			if err != nil {
+				return &ActionResult{
+					Success: false,
+					Error:   fmt.Sprintf("invalid %s: %v", key, err),
+				}
+			}
+			*field = minutes
+		}
+	}
+	if v, ok := data["buffer_minutes"].(float64); ok && v >= 0 {
+		q.Buffer = time.Duration(v) * time.Minute
+	}
+	if v, ok := data["include_weekends"].(bool); ok {
+		q.Weekends = v
+	}
+	if v := int(getFloat(data, "limit")); v > 0 {
+		q.Limit = v
+	}
+
+	slots, err := r.calendar.FindFreeTime(ctx, q)
+	if err != nil {
+		return &ActionResult{
+			Success: false,
+			Error:   err.Error(),
+		}
+	}
+	if slots == nil {
+		slots = []*calendar.Slot{}
+	}
+
+	return &ActionResult{
+		Success: true,
+		Data: &FreeTimeResult{
+			Slots:           slots,
+			DurationMinutes: duration,
+			From:            from,
+			To:              to,
+		},
+	}
+}
+
 // WeatherResponse represents weather data from Open-Meteo API
 type WeatherResponse struct {
 	Location    string  `json:"location"`
@@ -549,23 +907,88 @@ func (r *Registry) handleCreateReminder(ctx context.Context, data map[string]int
 	title, _ := data["title"].(string)
 	description, _ := data["description"].(string)
 	remindAtStr, _ := data["remind_at"].(string)
+	eventSearch, _ := data["event"].(string)
 
-	if title == "" || remindAtStr == "" {
+	if title == "" || (remindAtStr == "" && eventSearch == "") {
 		return &ActionResult{
 			Success: false,
-			Error:   "title and remind_at are required",
+			Error:   "title and remind_at or event are required",
 		}
 	}
 
-	remindAt, err := time.Parse(time.RFC3339, remindAtStr)
-	if err != nil {
-		return &ActionResult{
-			Success: false,
-			Error:   fmt.Sprintf("invalid remind_at format: %v", err),
+	var opts reminder.CreateOptions
+	var remindAt time.Time
+	var err error
+	if eventSearch != "" {
+		// Anchored to a calendar event, e.g. an hour before the board meeting
+		events, err := r.calendar.FindEventByTitle(ctx, eventSearch)
+		if err != nil || len(events) == 0 {
+			return &ActionResult{
+				Success: false,
+				Error:   fmt.Sprintf("could not find event matching '%s'", eventSearch),
+			}
+		}
+		event := nextEvent(events)
+
+		var offset time.Duration
+		if before, ok := data["before"].(string); ok && before != "" {
+			if offset, err = reminder.ParseOffset(before); err != nil {
+				return &ActionResult{
+					Success: false,
+					Error:   err.Error(),
+				}
+			}
+		}
+
+		opts.Event = &reminder.EventAnchor{EventID: event.ID, Offset: offset}
+		remindAt = opts.Event.RemindAt(event.StartTime)
+		if description == "" {
+			description = fmt.Sprintf("For %s", event.Title)
+		}
+	} else {
+		remindAt, err = time.Parse(time.RFC3339, remindAtStr)
+		if err != nil {
+			return &ActionResult{
+				Success: false,
+				Error:   fmt.Sprintf("invalid remind_at format: %v", err),
+			}
+		}
+	}
+
+	// Repeating reminders carry an RRULE, e.g. "FREQ=WEEKLY;BYDAY=TU"
+	if v, ok := data["recurrence"].(string); ok && v != "" {
+		rule, err := rrule.Parse(v, time.Local)
+		if err != nil {
+			return &ActionResult{
+				Success: false,
+				Error:   fmt.Sprintf("invalid recurrence: %v", err),
+			}
 		}
+		opts.Recurrence = rule
 	}
 
-	rem, err := r.reminder.Create(ctx, title, description, remindAt)
+	// Custom notification schedule, e.g. ["3d"] or ["at_time"]
+	if values, ok := getStrings(data, "notify_before"); ok {
+		offsets, err := reminder.ParseOffsetList(values)
+		if err != nil {
+			return &ActionResult{
+				Success: false,
+				Error:   err.Error(),
+			}
+		}
+		opts.Offsets = offsets
+	}
+
+	// Nag mode repeats the at-time announcement until acknowledged
+	if minutes := int(getFloat(data, "nag_minutes")); minutes > 0 {
+		opts.NagMinutes = minutes
+	}
+
+	opts.Priority, _ = data["priority"].(string)
+	opts.List, _ = data["list"].(string)
+	opts.Tags, _ = getStrings(data, "tags")
+
+	rem, err := r.reminder.CreateWithOptions(ctx, title, description, remindAt, opts)
 	if err != nil {
 		return &ActionResult{
 			Success: false,
@@ -579,6 +1002,18 @@ func (r *Registry) handleCreateReminder(ctx context.Context, data map[string]int
 	}
 }
 
+// nextEvent picks the first event that has not started yet from events
+// sorted by start time, or the latest one if all have started
+func nextEvent(events []*calendar.Event) *calendar.Event {
+	now := time.Now()
+	for _, e := range events {
+		if e.StartTime.After(now) {
+			return e
+		}
+	}
+	return events[len(events)-1]
+}
+
 // handleEditReminder updates an existing reminder
 func (r *Registry) handleEditReminder(ctx context.Context, data map[string]interface{}) *ActionResult {
 	id, _ := data["id"].(string)
@@ -624,7 +1059,39 @@ func (r *Registry) handleEditReminder(ctx context.Context, data map[string]inter
 		remindAt = &t
 	}
 
-	rem, err := r.reminder.Update(ctx, id, title, description, remindAt)
+	changes := reminder.Changes{Title: title, Description: description, RemindAt: remindAt}
+
+	if values, ok := getStrings(data, "notify_before"); ok {
+		offsets, err := reminder.ParseOffsetList(values)
+		if err != nil {
+			return &ActionResult{
+				Success: false,
+				Error:   err.Error(),
+			}
+		}
+		changes.Offsets = offsets
+	}
+
+	if _, ok := data["nag_minutes"]; ok {
+		minutes := int(getFloat(data, "nag_minutes"))
+		changes.NagMinutes = &minutes
+	}
+
+	// Priority, list and tags
+	if v, ok := data["priority"].(string); ok && v != "" {
+		changes.Priority = &v
+	}
+	if v, ok := data["list"].(string); ok {
+		changes.List = &v
+	}
+	if tags, ok := getStrings(data, "tags"); ok {
+		if tags == nil {
+			tags = []string{}
+		}
+		changes.Tags = tags
+	}
+
+	rem, err := r.reminder.Edit(ctx, id, changes)
 	if err != nil {
 		return &ActionResult{
 			Success: false,
@@ -675,11 +1142,20 @@ func (r *Registry) handleDeleteReminder(ctx context.Context, data map[string]int
 	}
 }
 
-// handleListReminders returns all active reminders
+// handleListReminders returns active reminders, optionally filtered by due
+// window, list, priority or tag
 func (r *Registry) handleListReminders(ctx context.Context, data map[string]interface{}) *ActionResult {
-	includeCompleted, _ := data["include_completed"].(bool)
+	var filter reminder.Filter
+	filter.IncludeCompleted, _ = data["include_completed"].(bool)
+	filter.Due, _ = data["due"].(string)
+	if overdue, _ := data["overdue"].(bool); overdue {
+		filter.Due = reminder.DueOverdue
+	}
+	filter.List, _ = data["list"].(string)
+	filter.Priority, _ = data["priority"].(string)
+	filter.Tag, _ = data["tag"].(string)
 
-	reminders, err := r.reminder.List(ctx, includeCompleted)
+	reminders, err := r.reminder.Query(ctx, filter)
 	if err != nil {
 		return &ActionResult{
 			Success: false,
@@ -717,19 +1193,114 @@ func (r *Registry) handleCompleteReminder(ctx context.Context, data map[string]i
 		}
 	}
 
-	if err := r.reminder.MarkCompleted(ctx, id); err != nil {
+	rem, err := r.reminder.Complete(ctx, id)
+	if err != nil {
 		return &ActionResult{
 			Success: false,
 			Error:   err.Error(),
 		}
 	}
 
+	// Recurring reminders roll to their next occurrence instead of completing
+	if !rem.Completed {
+		return &ActionResult{
+			Success: true,
+			Data: map[string]interface{}{
+				"completed": id,
+				"next_at":   rem.RemindAt,
+				"repeats":   rem.Repeats,
+			},
+		}
+	}
+
 	return &ActionResult{
 		Success: true,
 		Data:    map[string]string{"completed": id},
 	}
 }
 
+// handleSnoozeReminder postpones the next announcement of a reminder
+func (r *Registry) handleSnoozeReminder(ctx context.Context, data map[string]interface{}) *ActionResult {
+	id, _ := data["id"].(string)
+	searchTitle, _ := data["search_title"].(string)
+
+	// If no id provided, try to find by title
+	if id == "" && searchTitle != "" {
+		reminders, err := r.reminder.FindByTitle(ctx, searchTitle)
+		if err != nil || len(reminders) == 0 {
+			return &ActionResult{
+				Success: false,
+				Error:   fmt.Sprintf("could not find reminder matching '%s'", searchTitle),
+			}
+		}
+		id = reminders[0].ID
+	}
+
+	if id == "" {
+		return &ActionResult{
+			Success: false,
+			Error:   "id or search_title is required",
+		}
+	}
+
+	// Defaults to reminder.DefaultSnooze when no duration is given
+	minutes := getFloat(data, "minutes")
+	rem, err := r.reminder.Snooze(ctx, id, time.Duration(minutes*float64(time.Minute)))
+	if err != nil {
+		return &ActionResult{
+			Success: false,
+			Error:   err.Error(),
+		}
+	}
+
+	return &ActionResult{
+		Success: true,
+		Data: map[string]interface{}{
+			"snoozed": id,
+			"title":   rem.Title,
+			"until":   rem.SnoozedUntil,
+		},
+	}
+}
+
+// handleAcknowledgeReminder stops a reminder from nagging
+func (r *Registry) handleAcknowledgeReminder(ctx context.Context, data map[string]interface{}) *ActionResult {
+	id, _ := data["id"].(string)
+	searchTitle, _ := data["search_title"].(string)
+
+	// If no id provided, try to find by title
+	if id == "" && searchTitle != "" {
+		reminders, err := r.reminder.FindByTitle(ctx, searchTitle)
+		if err != nil || len(reminders) == 0 {
+			return &ActionResult{
+				Success: false,
+				Error:   fmt.Sprintf("could not find reminder matching '%s'", searchTitle),
+			}
+		}
+		id = reminders[0].ID
+	}
+
+	if id == "" {
+		return &ActionResult{
+			Success: false,
+			Error:   "id or search_title is required",
+		}
+	}
+
+	rem, err := r.reminder.Acknowledge(ctx, id)
+	if err != nil {
+		return &ActionResult{
+			Success: false,
+			Error:   err.Error(),
+		}
+	}
+
+	return &ActionResult{
+		Success: true,
+		Data:    rem,
+	}
+}
+
 // GameState represents the state of a higher/lower game
 type GameState struct {
 	GameType      string `json:"game_type"`
@@ -862,6 +1433,23 @@ func (r *Registry) handleGameMove(ctx context.Context, data map[string]interface
 	}
 }
 
+// getStrings reads a list of strings, accepting a single string as a one-item list
+func getStrings(data map[string]interface{}, key string) ([]string, bool) {
+	switch v := data[key].(type) {
+	case string:
+		return []string{v}, true
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values, true
+	}
+	return nil, false
+}
+
 // getFloat safely extracts a float64 from interface{} (handles both int and float)
 func getFloat(data map[string]interface{}, key string) float64 {
 	if v, ok := data[key].(float64); ok {
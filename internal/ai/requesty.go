@@ -10,6 +10,9 @@ import (
 	"time"
 
 	"github.com/baswilson/pika/internal/config"
+	"github.com/baswilson/pika/internal/embedding"
+	"github.com/baswilson/pika/internal/knowledge"
+	"github.com/baswilson/pika/internal/learning"
 	"github.com/baswilson/pika/internal/memory"
 	"github.com/sashabaranov/go-openai"
 )
@@ -29,10 +32,21 @@ func stripMarkdownCodeFences(content string) string {
 	return strings.TrimSpace(content)
 }
 
-// CalendarProvider interface for fetching calendar events
+// CalendarProvider interface for fetching calendar events. Events are read
+// from PIKA's own calendar, so they are available whether or not a remote
+// calendar is connected.
 type CalendarProvider interface {
 	ListEvents(ctx context.Context) ([]*CalendarEvent, error)
-	IsInitialized() bool
+	// Calendars lists the synced calendars, so the model can name one
+	Calendars(ctx context.Context) ([]*CalendarInfo, error)
+	// ReauthRequired reports whether the remote calendar has to be
+	// connected again, so the events may be out of date
+	ReauthRequired() bool
+}
+
+// KnowledgeProvider interface for fetching knowledge graph facts
+type KnowledgeProvider interface {
+	FactsForText(ctx context.Context, text string, limit int) ([]string, error)
 }
 
 // CalendarEvent represents a calendar event for AI context
@@ -41,6 +55,16 @@ type CalendarEvent struct {
 	StartTime time.Time
 	EndTime   time.Time
 	Location  string
+	AllDay    bool
+	Calendar  string // name of the calendar the event is in
+	Recurring bool
+	Attendees []string // names, or email addresses of attendees without one
+}
+
+// CalendarInfo describes a calendar for AI context
+type CalendarInfo struct {
+	Name    string
+	Default bool // new events go here unless another calendar is named
 }
 
 // AIResponse represents the structured response from the AI
@@ -66,32 +90,69 @@ type ResponsePayload struct {
 
 // Service handles AI interactions via requesty.ai
 type Service struct {
-	client      *openai.Client
-	embedClient *openai.Client // Local Ollama client for embeddings
-	embedModel  string
-	model       string
-	memory      *memory.Store
-	calendar    CalendarProvider
+	client     *openai.Client
+	embedder   embedding.Provider
+	embedCache *memory.EmbeddingCache
+	model      string
+	memory     *memory.Store
+	calendar   CalendarProvider
+	knowledge  KnowledgeProvider
 }
 
-// GenerateEmbedding creates a vector embedding for the given text using local Ollama.
-// Returns a 768-dimensional vector suitable for pgvector storage.
+// GenerateEmbedding creates a vector embedding for the given text using the
+// configured embedding provider.
+// Recently embedded text is served from the embedding cache.
 func (s *Service) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
-	req := openai.EmbeddingRequest{
-		Model: openai.EmbeddingModel(s.embedModel),
-		Input: []string{text},
+	embeddings, err := s.GenerateEmbeddings(ctx, []string{text})
+	if err != nil {
+		return nil, err
 	}
+	return embeddings[0], nil
+}
 
-	resp, err := s.embedClient.CreateEmbeddings(ctx, req)
+// GenerateEmbeddings creates embeddings for several texts with a single
+// provider request. Texts already in the embedding cache are not sent.
+func (s *Service) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	model := s.embedder.EmbeddingModel()
+	results := make([][]float32, len(texts))
+
+	// Collect the texts that still need embedding
+	var missing []string
+	var missingIdx []int
+	for i, text := range texts {
+		if emb, ok := s.embedCache.Get(model, text); ok {
+			results[i] = emb
+			continue
+		}
+		missing = append(missing, text)
+		missingIdx = append(missingIdx, i)
+	}
+
+	if len(missing) == 0 {
+		return results, nil
+	}
+
+	embeddings, err := s.embedder.GenerateEmbeddings(ctx, missing)
 	if err != nil {
-		return nil, fmt.Errorf("embedding request failed: %w", err)
+		return nil, err
 	}
 
-	if len(resp.Data) == 0 {
-		return nil, fmt.Errorf("no embedding returned from model")
+	for i, emb := range embeddings {
+		results[missingIdx[i]] = emb
+		s.embedCache.Put(model, missing[i], emb)
 	}
 
-	return resp.Data[0].Embedding, nil
+	return results, nil
+}
+
+// Model returns the name of the chat model
+func (s *Service) Model() string {
+	return s.model
+}
+
+// EmbeddingModel returns the name of the model used by GenerateEmbedding.
+func (s *Service) EmbeddingModel() string {
+	return s.embedder.EmbeddingModel()
 }
 
 // NewService creates a new AI service
@@ -99,55 +160,213 @@ func NewService(cfg *config.Config, memoryStore *memory.Store) *Service {
 	clientConfig := openai.DefaultConfig(cfg.RequestyAPIKey)
 	clientConfig.BaseURL = cfg.RequestyBaseURL
 
-	// Local Ollama client for fast embeddings
-	embedConfig := openai.DefaultConfig("ollama") // Key not required for Ollama
-	embedConfig.BaseURL = cfg.OllamaURL + "/v1"
-
 	return &Service{
-		client:      openai.NewClientWithConfig(clientConfig),
-		embedClient: openai.NewClientWithConfig(embedConfig),
-		embedModel:  cfg.OllamaEmbedModel,
-		model:       cfg.RequestyModel,
-		memory:      memoryStore,
+		client:     openai.NewClientWithConfig(clientConfig),
+		embedder:   embedding.New(cfg),
+		embedCache: memory.NewEmbeddingCache(cfg.EmbeddingCacheSize),
+		model:      cfg.RequestyModel,
+		memory:     memoryStore,
 	}
 }
 
+// calendarContext formats the next 10 calendar events for the system prompt
+func (s *Service) calendarContext(ctx context.Context) []string {
+	if s.calendar == nil {
+		return nil
+	}
+
+	events, err := s.calendar.ListEvents(ctx)
+	if err != nil {
+		log.Printf("Failed to fetch calendar events: %v", err)
+		return nil
+	}
+
+	var calendarEvents []string
+
+	// With several calendars, tell the model which it can put events on
+	calendars, err := s.calendar.Calendars(ctx)
+	if err != nil {
+		log.Printf("Failed to fetch calendars: %v", err)
+	}
+	if len(calendars) > 1 {
+		names := make([]string, len(calendars))
+		for i, c := range calendars {
+			names[i] = c.Name
+			if c.Default {
+				names[i] += " (default)"
+			}
+		}
+		calendarEvents = append(calendarEvents, "Calendars: "+strings.Join(names, ", "))
+	}
+	if s.calendar.ReauthRequired() {
+		calendarEvents = append(calendarEvents, "Google Calendar access was revoked or expired, so these events may be out of date and changes are not synced. Tell the user to connect Google Calendar again from the Connect Calendar notice.")
+	}
+	header := len(calendarEvents)
+
+	for _, e := range events {
+		if len(calendarEvents) >= header+10 {
+			break
+		}
+		var eventStr string
+		if e.AllDay {
+			// All-day events are stored at midnight UTC of their date
+			eventStr = fmt.Sprintf("%s (all day): %s", e.StartTime.UTC().Format("Mon Jan 2"), e.Title)
+		} else {
+			eventStr = fmt.Sprintf("%s: %s", e.StartTime.Local().Format("Mon Jan 2 3:04 PM"), e.Title)
+		}
+		if e.Recurring {
+			eventStr += " (repeats)"
+		}
+		if e.Location != "" {
+			eventStr += " at " + e.Location
+		}
+		if len(e.Attendees) > 0 {
+			eventStr += " with " + strings.Join(e.Attendees, ", ")
+		}
+		if e.Calendar != "" {
+			eventStr += " [" + e.Calendar + " calendar]"
+		}
+		calendarEvents = append(calendarEvents, eventStr)
+	}
+	log.Printf("Calendar context: %d events loaded", len(calendarEvents)-header)
+	return calendarEvents
+}
+
 // SetCalendar sets the calendar provider (called after calendar service is created)
 func (s *Service) SetCalendar(cal CalendarProvider) {
 	s.calendar = cal
 }
 
-// ProcessCommand sends a command to the AI and returns the response
-func (s *Service) ProcessCommand(text string) (*ResponsePayload, []Action, error) {
-	// Add timeout for AI requests
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+// SetKnowledge sets the knowledge graph provider used for prompt facts
+func (s *Service) SetKnowledge(k KnowledgeProvider) {
+	s.knowledge = k
+}
 
-	log.Printf("Processing command: %s", text)
-	log.Printf("Using model: %s", s.model)
+// knowledgeFacts returns knowledge graph facts about entities mentioned in text
+func (s *Service) knowledgeFacts(ctx context.Context, text string) []string {
+	if s.knowledge == nil {
+		return nil
+	}
+	facts, err := s.knowledge.FactsForText(ctx, text, 10)
+	if err != nil {
+		log.Printf("Failed to fetch knowledge facts: %v", err)
+		return nil
+	}
+	log.Printf("Knowledge context: %d facts loaded", len(facts))
+	return facts
+}
+
+// ExtractEntities asks the model for the entities and relations in a memory
+func (s *Service) ExtractEntities(ctx context.Context, text string) (*knowledge.Extraction, error) {
+	req := openai.ChatCompletionRequest{
+		Model: s.model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: EntityExtractionPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: "Text: " + text},
+		},
+		ResponseFormat: &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+		},
+	}
+
+	resp, err := s.client.CreateChatCompletion(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("entity extraction request failed: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("no response from AI")
+	}
 
-	// Get relevant memories using vector similarity search
+	var extraction knowledge.Extraction
+	if err := json.Unmarshal([]byte(stripMarkdownCodeFences(resp.Choices[0].Message.Content)), &extraction); err != nil {
+		return nil, fmt.Errorf("failed to parse entity extraction: %w", err)
+	}
+	return &extraction, nil
+}
+
+// ExtractMemories asks the model for facts worth remembering in a conversation transcript
+func (s *Service) ExtractMemories(ctx context.Context, transcript []learning.Turn) ([]learning.Candidate, error) {
+	var sb strings.Builder
+	for _, t := range transcript {
+		speaker := "User"
+		if t.Role == "assistant" {
+			speaker = "PIKA"
+		}
+		sb.WriteString(speaker + ": " + t.Content + "\n")
+	}
+
+	req := openai.ChatCompletionRequest{
+		Model: s.model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: MemoryExtractionPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: sb.String()},
+		},
+		ResponseFormat: &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+		},
+	}
+
+	resp, err := s.client.CreateChatCompletion(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("memory extraction request failed: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("no response from AI")
+	}
+
+	var result struct {
+		Memories []learning.Candidate `json:"memories"`
+	}
+	if err := json.Unmarshal([]byte(stripMarkdownCodeFences(resp.Choices[0].Message.Content)), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse memory extraction: %w", err)
+	}
+	return result.Memories, nil
+}
+
+// relevantMemories returns the memories most related to text by vector
+// similarity, falling back to keyword search when the query cannot be
+// embedded or no memory has an embedding from the active model
+func (s *Service) relevantMemories(ctx context.Context, text string) []string {
 	var memories []string
 
 	// Generate embedding for the query to enable semantic search
 	queryEmbedding, err := s.GenerateEmbedding(ctx, text)
 	if err != nil {
 		log.Printf("Failed to generate query embedding, falling back to keyword search: %v", err)
-		// Fall back to keyword search if embedding fails
 		memories, _ = s.memory.SearchRelevant(ctx, text, 5)
-	} else {
-		// Use vector similarity search for semantic matching
-		vectorResults, err := s.memory.SearchByVector(ctx, queryEmbedding, 5)
-		if err != nil {
-			log.Printf("Vector search failed, falling back to keyword search: %v", err)
-			memories, _ = s.memory.SearchRelevant(ctx, text, 5)
-		} else {
-			for _, m := range vectorResults {
-				memories = append(memories, m.Content)
-			}
-			log.Printf("Vector search returned %d results", len(memories))
-		}
+		return memories
+	}
+
+	// Use vector similarity search for semantic matching
+	vectorResults, err := s.memory.SearchByVector(ctx, queryEmbedding, 5)
+	if err != nil {
+		log.Printf("Vector search failed, falling back to keyword search: %v", err)
+		memories, _ = s.memory.SearchRelevant(ctx, text, 5)
+		return memories
+	}
+	if len(vectorResults) == 0 {
+		log.Printf("No memories embedded with %s, falling back to keyword search", s.embedder.EmbeddingModel())
+		memories, _ = s.memory.SearchRelevant(ctx, text, 5)
+		return memories
 	}
+	for _, m := range vectorResults {
+		memories = append(memories, m.Content)
+	}
+	log.Printf("Vector search returned %d results", len(memories))
+	return memories
+}
+
+// ProcessCommand sends a command to the AI and returns the response
+func (s *Service) ProcessCommand(text string) (*ResponsePayload, []Action, error) {
+	// Add timeout for AI requests
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	log.Printf("Processing command: %s", text)
+	log.Printf("Using model: %s", s.model)
+
+	// Get relevant memories
+	memories := s.relevantMemories(ctx, text)
 
 	// Also get top important memories (ensures personal info is always included)
 	topMemories, err := s.memory.GetTopImportant(ctx, 5)
@@ -170,30 +389,11 @@ func (s *Service) ProcessCommand(text string) (*ResponsePayload, []Action, error
 	log.Printf("Memory context: %d memories loaded", len(memories))
 
 	// Get upcoming calendar events
-	var calendarEvents []string
-	log.Printf("Calendar check: calendar=%v, initialized=%v", s.calendar != nil, s.calendar != nil && s.calendar.IsInitialized())
-	if s.calendar != nil && s.calendar.IsInitialized() {
-		events, err := s.calendar.ListEvents(ctx)
-		if err != nil {
-			log.Printf("Failed to fetch calendar events: %v", err)
-		} else {
-			for _, e := range events {
-				if len(calendarEvents) >= 10 {
-					break
-				}
-				eventStr := fmt.Sprintf("%s: %s", e.StartTime.Format("Mon Jan 2 3:04 PM"), e.Title)
-				if e.Location != "" {
-					eventStr += " at " + e.Location
-				}
-				calendarEvents = append(calendarEvents, eventStr)
-			}
-			log.Printf("Calendar context: %d events loaded", len(calendarEvents))
-		}
-	}
+	calendarEvents := s.calendarContext(ctx)
 
 	// Build system prompt with context
 	currentTime := time.Now().Format("Monday, January 2, 2006 3:04 PM MST")
-	systemPrompt := BuildPromptWithContext(memories, calendarEvents, currentTime)
+	systemPrompt := BuildPromptWithContext(memories, s.knowledgeFacts(ctx, text), calendarEvents, currentTime)
 
 	// Create chat completion request
 	// Note: Some models via OpenAI-compatible APIs don't support ResponseFormat
@@ -283,7 +483,7 @@ func (s *Service) ProcessCommandStream(text string, onChunk StreamCallback) (*Re
 	}
 
 	currentTime := time.Now().Format("Monday, January 2, 2006 3:04 PM MST")
-	systemPrompt := BuildPromptWithContext(memories, nil, currentTime)
+	systemPrompt := BuildPromptWithContext(memories, s.knowledgeFacts(ctx, text), nil, currentTime)
 
 	req := openai.ChatCompletionRequest{
 		Model: s.model,
@@ -348,27 +548,8 @@ func (s *Service) ProcessCommandWithHistory(text string, history []openai.ChatCo
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	// Get relevant memories using vector similarity search
-	var memories []string
-
-	// Generate embedding for the query to enable semantic search
-	queryEmbedding, err := s.GenerateEmbedding(ctx, text)
-	if err != nil {
-		log.Printf("Failed to generate query embedding, falling back to keyword search: %v", err)
-		memories, _ = s.memory.SearchRelevant(ctx, text, 5)
-	} else {
-		// Use vector similarity search for semantic matching
-		vectorResults, err := s.memory.SearchByVector(ctx, queryEmbedding, 5)
-		if err != nil {
-			log.Printf("Vector search failed, falling back to keyword search: %v", err)
-			memories, _ = s.memory.SearchRelevant(ctx, text, 5)
-		} else {
-			for _, m := range vectorResults {
-				memories = append(memories, m.Content)
-			}
-			log.Printf("Vector search returned %d results", len(memories))
-		}
-	}
+	// Get relevant memories
+	memories := s.relevantMemories(ctx, text)
 
 	// Also get top important memories (ensures personal info is always included)
 	topMemories, err := s.memory.GetTopImportant(ctx, 5)
@@ -390,29 +571,11 @@ func (s *Service) ProcessCommandWithHistory(text string, history []openai.ChatCo
 	log.Printf("Memory context: %d memories loaded", len(memories))
 
 	// Get upcoming calendar events
-	var calendarEvents []string
-	if s.calendar != nil && s.calendar.IsInitialized() {
-		events, err := s.calendar.ListEvents(ctx)
-		if err != nil {
-			log.Printf("Failed to fetch calendar events: %v", err)
-		} else {
-			for _, e := range events {
-				if len(calendarEvents) >= 10 {
-					break
-				}
-				eventStr := fmt.Sprintf("%s: %s", e.StartTime.Local().Format("Mon Jan 2 3:04 PM"), e.Title)
-				if e.Location != "" {
-					eventStr += " at " + e.Location
-				}
-				calendarEvents = append(calendarEvents, eventStr)
-			}
-			log.Printf("Calendar context: %d events loaded", len(calendarEvents))
-		}
-	}
+	calendarEvents := s.calendarContext(ctx)
 
 	// Build system prompt
 	currentTime := time.Now().Format("Monday, January 2, 2006 3:04 PM MST")
-	systemPrompt := BuildPromptWithContext(memories, calendarEvents, currentTime)
+	systemPrompt := BuildPromptWithContext(memories, s.knowledgeFacts(ctx, text), calendarEvents, currentTime)
 
 	// Build messages with history
 	messages := []openai.ChatCompletionMessage{
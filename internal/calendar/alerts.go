@@ -0,0 +1,197 @@
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultAlerts are the minutes before a timed event that it is announced
+// when neither it nor its calendar has alerts of its own
+var defaultAlerts = []int{15, 5}
+
+// defaultAllDayAlert is when all-day events are announced on their day, in
+// minutes after midnight
+const defaultAllDayAlert = 8 * 60
+
+// alertHorizon is how far ahead checkReminders looks for events to announce
+const alertHorizon = 7 * 24 * time.Hour
+
+// alertGrace is how late an alert is still announced, such as one that
+// came due while PIKA was not running
+const alertGrace = 5 * time.Minute
+
+// deliveredRetention is how long announced alerts are remembered after
+// the start of their event
+const deliveredRetention = 2 * 24 * time.Hour
+
+// alert is a time an occurrence of an event is announced
+type alert struct {
+	at time.Time
+	// minutes before the start; negative for the morning-of announcement
+	// of an all-day event
+	minutes int
+}
+
+// SetDefaultAlerts sets the minutes before a timed event that it is
+// announced when neither it nor its calendar has alerts of its own
+func (s *Service) SetDefaultAlerts(minutes []int) {
+	s.alertMinutes = minutes
+}
+
+// SetAllDayAlert sets the time of day all-day events are announced, such
+// as "08:00"; "off" or "" turns the announcement off
+func (s *Service) SetAllDayAlert(clock string) error {
+	clock = strings.TrimSpace(clock)
+	if clock == "" || strings.EqualFold(clock, "off") {
+		s.allDayAlert = -1
+		return nil
+	}
+	minutes, err := ParseClock(clock)
+	if err != nil {
+		return err
+	}
+	if minutes >= 24*60 {
+		return fmt.Errorf("invalid time of day %q, expected e.g. 08:00", clock)
+	}
+	s.allDayAlert = minutes
+	return nil
+}
+
+// ParseAlertMinutes parses a list of alert offsets such as "15,5"; "none"
+// or "" is no alerts
+func ParseAlertMinutes(list string) ([]int, error) {
+	list = strings.TrimSpace(list)
+	if list == "" || strings.EqualFold(list, "none") {
+		return []int{}, nil
+	}
+	var minutes []int
+	for _, part := range strings.Split(list, ",") {
+		m, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || m < 0 {
+			return nil, fmt.Errorf("invalid alert minutes %q, expected e.g. 15,5", list)
+		}
+		minutes = append(minutes, m)
+	}
+	return minutes, nil
+}
+
+// checkReminders announces the event alerts that came due. Announced
+// alerts are recorded so that they are not repeated, even after a restart;
+// of several alerts of an event that came due together only the latest is
+// announced.
+func (s *Service) checkReminders() {
+	if s.onReminder == nil {
+		return
+	}
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	events, err := s.eventsBetween(ctx, now.Add(-alertGrace), now.Add(alertHorizon))
+	if err != nil {
+		fmt.Printf("Failed to check event alerts: %v\n", err)
+		return
+	}
+	calendarAlerts := s.calendarAlerts(ctx)
+
+	for _, e := range events {
+		var due []alert
+		for _, a := range s.alertsFor(e, calendarAlerts) {
+			if !a.at.After(now) && a.at.After(now.Add(-alertGrace)) {
+				due = append(due, a)
+			}
+		}
+		sort.Slice(due, func(i, j int) bool { return due[i].at.Before(due[j].at) })
+
+		for i, a := range due {
+			delivered, err := s.markAlertDelivered(ctx, e, a.minutes)
+			if err != nil {
+				fmt.Printf("Failed to record alert for %s: %v\n", e.Title, err)
+				continue
+			}
+			if delivered && i == len(due)-1 {
+				s.onReminder(e, a.minutes)
+			}
+		}
+	}
+
+	s.pruneDeliveredAlerts(ctx, now.Add(-deliveredRetention))
+}
+
+// alertsFor returns when an occurrence of an event is announced: its own
+// alarms, else its calendar's default alerts, else the configured ones.
+// All-day events are announced on the morning of their day instead of at
+// midnight, and by their own alarms before the day.
+func (s *Service) alertsFor(e *Event, calendarAlerts map[string][]int) []alert {
+	var alerts []alert
+	if e.AllDay {
+		// All-day events start at midnight UTC of their date
+		y, m, d := e.StartTime.UTC().Date()
+		day := time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+		for _, minutes := range e.Alarms {
+			if minutes > 0 {
+				alerts = append(alerts, alert{at: day.Add(-time.Duration(minutes) * time.Minute), minutes: minutes})
+			}
+		}
+		if s.allDayAlert >= 0 && (e.Alarms == nil || len(e.Alarms) > 0) {
+			alerts = append(alerts, alert{at: day.Add(time.Duration(s.allDayAlert) * time.Minute), minutes: -s.allDayAlert})
+		}
+		return alerts
+	}
+
+	offsets := e.Alarms
+	if offsets == nil {
+		offsets = calendarAlerts[e.CalendarID]
+	}
+	if offsets == nil {
+		offsets = s.alertMinutes
+	}
+	for _, minutes := range offsets {
+		alerts = append(alerts, alert{at: e.StartTime.Add(-time.Duration(minutes) * time.Minute), minutes: minutes})
+	}
+	return alerts
+}
+
+// calendarAlerts maps the calendars with default alerts to them
+func (s *Service) calendarAlerts(ctx context.Context) map[string][]int {
+	calendars, err := s.storedCalendars(ctx)
+	if err != nil {
+		return nil
+	}
+	alerts := make(map[string][]int)
+	for _, cal := range calendars {
+		if cal.DefaultAlerts != nil {
+			alerts[cal.ID] = cal.DefaultAlerts
+		}
+	}
+	return alerts
+}
+
+// markAlertDelivered records that an alert of an occurrence is announced.
+// It reports false when it already was.
+func (s *Service) markAlertDelivered(ctx context.Context, e *Event, minutes int) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `
+		INSERT OR IGNORE INTO calendar_event_alerts (event_id, occurrence_start, minutes_before)
+		VALUES (?, ?, ?)
+	`, e.ID, e.StartTime.UTC().Format("2006-01-02 15:04:05"), minutes)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// pruneDeliveredAlerts forgets the alerts of occurrences that started
+// before a time
+func (s *Service) pruneDeliveredAlerts(ctx context.Context, before time.Time) {
+	_, err := s.db.ExecContext(ctx,
+		"DELETE FROM calendar_event_alerts WHERE occurrence_start < ?",
+		before.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		fmt.Printf("Failed to prune event alerts: %v\n", err)
+	}
+}
@@ -0,0 +1,107 @@
+package calendar
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Attendee is a person invited to an event
+type Attendee struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+	// Status is the attendee's response, one of the Response constants
+	Status string `json:"status,omitempty"`
+	// Organizer is the attendee who owns the event
+	Organizer bool `json:"organizer,omitempty"`
+}
+
+// Attendee responses, as Google Calendar names them
+const (
+	ResponseNeedsAction = "needsAction"
+	ResponseAccepted    = "accepted"
+	ResponseDeclined    = "declined"
+	ResponseTentative   = "tentative"
+)
+
+// partStats maps responses to iCalendar PARTSTAT values
+var partStats = map[string]string{
+	ResponseNeedsAction: "NEEDS-ACTION",
+	ResponseAccepted:    "ACCEPTED",
+	ResponseDeclined:    "DECLINED",
+	ResponseTentative:   "TENTATIVE",
+}
+
+// formatAttendees encodes attendees for storage
+func formatAttendees(attendees []*Attendee) interface{} {
+	if len(attendees) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(attendees)
+	if err != nil {
+		return nil
+	}
+	return string(data)
+}
+
+// parseAttendees decodes stored attendees
+func parseAttendees(data string) []*Attendee {
+	if data == "" {
+		return nil
+	}
+	var attendees []*Attendee
+	if err := json.Unmarshal([]byte(data), &attendees); err != nil {
+		return nil
+	}
+	return attendees
+}
+
+// attendeeFromICal reads an ATTENDEE property such as
+// ATTENDEE;CN=Sarah;PARTSTAT=ACCEPTED:mailto:sarah@example.com
+func attendeeFromICal(p *icalProp) *Attendee {
+	email := p.Value
+	if len(email) > 7 && strings.EqualFold(email[:7], "mailto:") {
+		email = email[7:]
+	}
+	if email == "" {
+		return nil
+	}
+
+	attendee := &Attendee{Email: email, Name: p.Params["CN"], Status: ResponseNeedsAction}
+	for status, partStat := range partStats {
+		if strings.EqualFold(p.Params["PARTSTAT"], partStat) {
+			attendee.Status = status
+		}
+	}
+	return attendee
+}
+
+// setICalAttendees replaces the ATTENDEE properties of a VEVENT with the
+// event's attendees. Properties of attendees still invited are kept as they
+// are, since their PARTSTAT and other parameters belong to the attendee;
+// new ones are asked to reply unless their response is known.
+func setICalAttendees(c *icalComponent, attendees []*Attendee) {
+	existing := make(map[string]*icalProp)
+	for _, p := range c.Props {
+		if p.Name == "ATTENDEE" {
+			if a := attendeeFromICal(p); a != nil {
+				existing[strings.ToLower(a.Email)] = p
+			}
+		}
+	}
+	c.remove("ATTENDEE")
+
+	for _, a := range attendees {
+		if p := existing[strings.ToLower(a.Email)]; p != nil {
+			c.Props = append(c.Props, p)
+			continue
+		}
+		params := map[string]string{"PARTSTAT": partStats[ResponseNeedsAction], "RSVP": "TRUE"}
+		if partStat, ok := partStats[a.Status]; ok && a.Status != ResponseNeedsAction {
+			params = map[string]string{"PARTSTAT": partStat}
+		}
+		if a.Name != "" {
+			params["CN"] = a.Name
+		}
+		c.Props = append(c.Props, &icalProp{Name: "ATTENDEE", Params: params, Value: "mailto:" + a.Email})
+	}
+}
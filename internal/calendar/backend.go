@@ -0,0 +1,121 @@
+package calendar
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/baswilson/pika/internal/config"
+)
+
+// Backend names accepted in CALENDAR_BACKEND
+const (
+	BackendAuto   = "auto"
+	BackendLocal  = "local"
+	BackendGoogle = "google"
+	BackendCalDAV = "caldav"
+)
+
+// PrimaryCalendar is the ID of the account's main calendar. Backends with a
+// single calendar use it for that calendar.
+const PrimaryCalendar = "primary"
+
+// Backend is a remote calendar account the local event cache is synced with.
+// Events passed to and returned from a backend carry its ID for the event in
+// RemoteID and the calendar it is in in CalendarID; the local ID is owned by
+// the Service.
+type Backend interface {
+	// Name returns the backend name stored with cached events, e.g. "google"
+	Name() string
+	// Ready reports whether the backend is configured and authorized
+	Ready() bool
+	// Calendars lists the calendars of the account. Selected marks the
+	// calendars synced by default.
+	Calendars(ctx context.Context) ([]*Calendar, error)
+	// List returns the events of a calendar starting between from and to
+	List(ctx context.Context, calendarID string, from, to time.Time) ([]*Event, error)
+	// Create adds an event to event.CalendarID and returns its remote ID
+	Create(ctx context.Context, event *Event) (string, error)
+	// Update replaces the event with event.RemoteID
+	Update(ctx context.Context, event *Event) error
+	// Delete removes the event with the given remote ID from a calendar
+	Delete(ctx context.Context, calendarID, remoteID string) error
+	// Sync returns the events of a calendar starting between from and to.
+	// Given the token of an earlier result, a backend that supports
+	// incremental sync returns only what changed since then, which may
+	// include events outside the window.
+	Sync(ctx context.Context, calendarID string, from, to time.Time, token string) (*SyncResult, error)
+}
+
+// SyncResult is the outcome of a backend sync
+type SyncResult struct {
+	// Events are the new and changed events
+	Events []*Event
+	// Deleted are the remote IDs of events deleted since the sync token. The
+	// occurrences of a deleted recurring event go with it.
+	Deleted []string
+	// Complete is true when Events holds every event in the window, so cached
+	// events missing from it were deleted remotely
+	Complete bool
+	// Token is passed to the next Sync to fetch only later changes; empty
+	// when the backend has no incremental sync
+	Token string
+}
+
+// newBackend creates the calendar backend selected in the config.
+// With "auto" (the default) CalDAV is used when a CalDAV URL is set, Google
+// when Google credentials are, and the local calendar otherwise.
+func newBackend(cfg *config.Config, db *sql.DB) Backend {
+	switch strings.ToLower(cfg.CalendarBackend) {
+	case BackendLocal:
+		return LocalBackend{}
+	case BackendGoogle:
+		return NewGoogleBackend(cfg, db)
+	case BackendCalDAV:
+		return NewCalDAVBackend(cfg.CalDAVURL, cfg.CalDAVUsername, cfg.CalDAVPassword)
+	}
+
+	switch {
+	case cfg.CalDAVURL != "":
+		return NewCalDAVBackend(cfg.CalDAVURL, cfg.CalDAVUsername, cfg.CalDAVPassword)
+	case cfg.GoogleClientID != "":
+		return NewGoogleBackend(cfg, db)
+	default:
+		return LocalBackend{}
+	}
+}
+
+// LocalBackend keeps the calendar only in PIKA's database. Events are
+// shared with other apps through the ICS export and feed.
+type LocalBackend struct{}
+
+// Name returns the backend name
+func (LocalBackend) Name() string { return BackendLocal }
+
+// Ready is always true; there is nothing to connect
+func (LocalBackend) Ready() bool { return true }
+
+// Calendars returns the single local calendar
+func (LocalBackend) Calendars(ctx context.Context) ([]*Calendar, error) {
+	return []*Calendar{{ID: PrimaryCalendar, Name: "PIKA", Primary: true, Selected: true}}, nil
+}
+
+// List returns nothing; the local cache is the calendar
+func (LocalBackend) List(ctx context.Context, calendarID string, from, to time.Time) ([]*Event, error) {
+	return nil, nil
+}
+
+// Create returns no remote ID
+func (LocalBackend) Create(ctx context.Context, event *Event) (string, error) { return "", nil }
+
+// Update does nothing
+func (LocalBackend) Update(ctx context.Context, event *Event) error { return nil }
+
+// Delete does nothing
+func (LocalBackend) Delete(ctx context.Context, calendarID, remoteID string) error { return nil }
+
+// Sync returns an incomplete, empty result so no cached event is removed
+func (LocalBackend) Sync(ctx context.Context, calendarID string, from, to time.Time, token string) (*SyncResult, error) {
+	return &SyncResult{}, nil
+}
@@ -0,0 +1,402 @@
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CalDAVBackend syncs with a CalDAV calendar collection (RFC 4791), such as
+// Fastmail, Nextcloud or Radicale. The collection is the account's only
+// calendar, with the ID "primary". Remote IDs are the hrefs of the calendar
+// object resources.
+type CalDAVBackend struct {
+	url      *url.URL
+	username string
+	password string
+	client   *http.Client
+}
+
+// NewCalDAVBackend creates a backend for the calendar collection at
+// collectionURL, e.g. https://caldav.fastmail.com/dav/calendars/user/me@example.com/Default/
+func NewCalDAVBackend(collectionURL, username, password string) *CalDAVBackend {
+	b := &CalDAVBackend{
+		username: username,
+		password: password,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+	if collectionURL != "" {
+		u, err := url.Parse(collectionURL)
+		if err != nil {
+			fmt.Printf("Invalid CALDAV_URL %q: %v\n", collectionURL, err)
+		} else {
+			// Resources are resolved relative to the collection
+			if !strings.HasSuffix(u.Path, "/") {
+				u.Path += "/"
+			}
+			b.url = u
+		}
+	}
+	return b
+}
+
+// Name returns the backend name
+func (b *CalDAVBackend) Name() string {
+	return BackendCalDAV
+}
+
+// Ready reports whether a collection URL is configured
+func (b *CalDAVBackend) Ready() bool {
+	return b.url != nil
+}
+
+// davMultistatus is the body of a 207 Multi-Status response
+type davMultistatus struct {
+	Responses []struct {
+		Href      string `xml:"DAV: href"`
+		Propstats []struct {
+			Status string `xml:"DAV: status"`
+			Prop   struct {
+				ETag          string `xml:"DAV: getetag"`
+				DisplayName   string `xml:"DAV: displayname"`
+				CalendarColor string `xml:"http://apple.com/ns/ical/ calendar-color"`
+				CalendarData  string `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+			} `xml:"DAV: prop"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+// collectionProps asks for the name and color of the calendar collection
+const collectionProps = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:" xmlns:a="http://apple.com/ns/ical/">
+	<d:prop>
+		<d:displayname/>
+		<a:calendar-color/>
+	</d:prop>
+</d:propfind>`
+
+// Calendars returns the collection as the only calendar, named after its
+// display name or, when the server has none, the last segment of its URL
+func (b *CalDAVBackend) Calendars(ctx context.Context) ([]*Calendar, error) {
+	if !b.Ready() {
+		return nil, fmt.Errorf("caldav calendar is not configured")
+	}
+
+	cal := &Calendar{
+		ID:       PrimaryCalendar,
+		Name:     path.Base(b.url.Path),
+		Primary:  true,
+		Selected: true,
+	}
+
+	req, err := b.request(ctx, "PROPFIND", b.url, strings.NewReader(collectionProps))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	req.Header.Set("Depth", "0")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("caldav propfind failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// The name is a nicety; servers without PROPFIND still sync
+	var ms davMultistatus
+	if resp.StatusCode == http.StatusMultiStatus && xml.NewDecoder(resp.Body).Decode(&ms) == nil {
+		for _, r := range ms.Responses {
+			for _, ps := range r.Propstats {
+				if ps.Prop.DisplayName != "" {
+					cal.Name = ps.Prop.DisplayName
+				}
+				if ps.Prop.CalendarColor != "" {
+					// Apple colors are #RRGGBBAA
+					cal.Color = ps.Prop.CalendarColor[:min(len(ps.Prop.CalendarColor), 7)]
+				}
+			}
+		}
+	}
+	return []*Calendar{cal}, nil
+}
+
+// calendarQuery is a calendar-query REPORT for the events overlapping a time
+// range, with recurring events expanded into their occurrences
+const calendarQuery = `<?xml version="1.0" encoding="utf-8"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+	<d:prop>
+		<d:getetag/>
+		<c:calendar-data>
+			<c:expand start="%[1]s" end="%[2]s"/>
+		</c:calendar-data>
+	</d:prop>
+	<c:filter>
+		<c:comp-filter name="VCALENDAR">
+			<c:comp-filter name="VEVENT">
+				<c:time-range start="%[1]s" end="%[2]s"/>
+			</c:comp-filter>
+		</c:comp-filter>
+	</c:filter>
+</c:calendar-query>`
+
+// List returns the events overlapping from and to. Occurrences of recurring
+// events get the remote ID "<href>#<RECURRENCE-ID>".
+func (b *CalDAVBackend) List(ctx context.Context, calendarID string, from, to time.Time) ([]*Event, error) {
+	if !b.Ready() {
+		return nil, fmt.Errorf("caldav calendar is not configured")
+	}
+
+	body := fmt.Sprintf(calendarQuery, from.UTC().Format(icalUTC), to.UTC().Format(icalUTC))
+	req, err := b.request(ctx, "REPORT", b.url, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	req.Header.Set("Depth", "1")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("caldav report failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, davError("report", resp)
+	}
+
+	var ms davMultistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("invalid caldav response: %w", err)
+	}
+
+	var events []*Event
+	for _, r := range ms.Responses {
+		for _, ps := range r.Propstats {
+			if ps.Prop.CalendarData == "" || !strings.Contains(ps.Status, " 200 ") {
+				continue
+			}
+			parsed, err := eventsFromICal(r.Href, ps.Prop.CalendarData)
+			if err != nil {
+				fmt.Printf("Skipping caldav object %s: %v\n", r.Href, err)
+				continue
+			}
+			events = append(events, parsed...)
+		}
+	}
+	return events, nil
+}
+
+// eventsFromICal reads the events of a calendar object resource
+func eventsFromICal(href, data string) ([]*Event, error) {
+	cal, err := parseICal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*Event
+	for _, vevent := range cal.children("VEVENT") {
+		event, err := veventToEvent(vevent)
+		if err != nil {
+			return nil, err
+		}
+		event.RemoteID = href
+		if rid := vevent.get("RECURRENCE-ID"); rid != nil {
+			event.RemoteID += "#" + rid.Value
+		}
+		event.CalendarID = PrimaryCalendar
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// Sync lists every event in the window; there is no incremental sync yet
+func (b *CalDAVBackend) Sync(ctx context.Context, calendarID string, from, to time.Time, token string) (*SyncResult, error) {
+	events, err := b.List(ctx, calendarID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return &SyncResult{Events: events, Complete: true}, nil
+}
+
+// Create stores the event as a new calendar object resource
+func (b *CalDAVBackend) Create(ctx context.Context, event *Event) (string, error) {
+	if !b.Ready() {
+		return "", fmt.Errorf("caldav calendar is not configured")
+	}
+
+	// The resource is named after the local ID, which is always URL safe,
+	// while an imported event keeps its own UID
+	name := event.ID
+	if name == "" {
+		name = uuid.New().String()
+	}
+	uid := event.UID
+	if uid == "" {
+		uid = name
+	}
+	target := b.url.ResolveReference(&url.URL{Path: name + ".ics"})
+
+	// If-None-Match keeps us from overwriting an existing resource
+	cal := newVCalendar()
+	cal.Components = append(cal.Components, eventToVEvent(uid, event))
+	if _, err := b.put(ctx, target, cal.encode(), "If-None-Match", "*"); err != nil {
+		return "", err
+	}
+	return target.Path, nil
+}
+
+// Update rewrites the event's calendar object, keeping the properties PIKA
+// does not manage such as alarms
+func (b *CalDAVBackend) Update(ctx context.Context, event *Event) error {
+	target, err := b.resource(event.RemoteID)
+	if err != nil {
+		return err
+	}
+
+	data, etag, err := b.get(ctx, target)
+	if err != nil {
+		return err
+	}
+	cal, err := parseICal(data)
+	if err != nil {
+		return fmt.Errorf("invalid calendar object %s: %w", event.RemoteID, err)
+	}
+
+	var vevent *icalComponent
+	for _, c := range cal.children("VEVENT") {
+		if c.get("RECURRENCE-ID") == nil {
+			vevent = c
+			break
+		}
+	}
+	if vevent == nil {
+		return fmt.Errorf("calendar object %s has no event", event.RemoteID)
+	}
+	applyEventToVEvent(vevent, event)
+	sequence := 0
+	if p := vevent.get("SEQUENCE"); p != nil {
+		fmt.Sscanf(p.Value, "%d", &sequence)
+	}
+	vevent.set("SEQUENCE", fmt.Sprint(sequence+1), nil)
+
+	// If-Match makes the update fail rather than overwrite a concurrent change
+	header, value := "", ""
+	if etag != "" {
+		header, value = "If-Match", etag
+	}
+	_, err = b.put(ctx, target, cal.encode(), header, value)
+	return err
+}
+
+// Delete removes the event's calendar object resource
+func (b *CalDAVBackend) Delete(ctx context.Context, calendarID, remoteID string) error {
+	target, err := b.resource(remoteID)
+	if err != nil {
+		return err
+	}
+
+	req, err := b.request(ctx, http.MethodDelete, target, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("caldav delete failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Already gone is as good as deleted
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound {
+		return davError("delete", resp)
+	}
+	return nil
+}
+
+// resource resolves a remote ID to the URL of its calendar object
+func (b *CalDAVBackend) resource(remoteID string) (*url.URL, error) {
+	if !b.Ready() {
+		return nil, fmt.Errorf("caldav calendar is not configured")
+	}
+	if strings.Contains(remoteID, "#") {
+		return nil, fmt.Errorf("changing a single occurrence of a recurring event is not supported")
+	}
+	ref, err := url.Parse(remoteID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid caldav href %q: %w", remoteID, err)
+	}
+	return b.url.ResolveReference(ref), nil
+}
+
+// get fetches a calendar object and its ETag
+func (b *CalDAVBackend) get(ctx context.Context, target *url.URL) (data, etag string, err error) {
+	req, err := b.request(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return "", "", err
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("caldav get failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", davError("get", resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", "", err
+	}
+	return string(body), resp.Header.Get("ETag"), nil
+}
+
+// put stores a calendar object, with an optional precondition header
+func (b *CalDAVBackend) put(ctx context.Context, target *url.URL, data, header, value string) (string, error) {
+	req, err := b.request(ctx, http.MethodPut, target, bytes.NewBufferString(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "text/calendar; charset=utf-8")
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("caldav put failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return "", davError("put", resp)
+	}
+	return resp.Header.Get("ETag"), nil
+}
+
+// request builds an authenticated request
+func (b *CalDAVBackend) request(ctx context.Context, method string, target *url.URL, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if b.username != "" || b.password != "" {
+		req.SetBasicAuth(b.username, b.password)
+	}
+	return req, nil
+}
+
+// davError describes a failed CalDAV response
+func davError(op string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	msg := strings.TrimSpace(string(body))
+	if msg == "" {
+		return fmt.Errorf("caldav %s failed: %s", op, resp.Status)
+	}
+	return fmt.Errorf("caldav %s failed: %s: %s", op, resp.Status, msg)
+}
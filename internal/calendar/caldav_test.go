@@ -0,0 +1,224 @@
+package calendar
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baswilson/pika/internal/calendar/caldavtest"
+)
+
+// newTestCalDAV returns a backend talking to an in-memory CalDAV stand-in.
+// The handler wraps the stand-in, so tests can interfere with requests.
+func newTestCalDAV(t *testing.T, wrap func(*caldavtest.Server, http.Handler) http.Handler) (*CalDAVBackend, *caldavtest.Server) {
+	t.Helper()
+
+	standin := caldavtest.NewServer("pika", "secret")
+	var handler http.Handler = standin
+	if wrap != nil {
+		handler = wrap(standin, standin)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return NewCalDAVBackend(server.URL+"/calendars/pika/Work", "pika", "secret"), standin
+}
+
+func TestCalDAVCalendars(t *testing.T) {
+	backend, _ := newTestCalDAV(t, nil)
+
+	calendars, err := backend.Calendars(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The stand-in has no PROPFIND, so the name comes from the URL
+	if len(calendars) != 1 {
+		t.Fatalf("got %d calendars, want 1", len(calendars))
+	}
+	cal := calendars[0]
+	if cal.ID != PrimaryCalendar || cal.Name != "Work" || !cal.Primary || !cal.Selected {
+		t.Errorf("got calendar %+v, want primary calendar named Work", cal)
+	}
+}
+
+func TestCalDAVCreateListUpdateDelete(t *testing.T) {
+	backend, standin := newTestCalDAV(t, nil)
+	ctx := context.Background()
+
+	start := time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC)
+	event := &Event{
+		ID:         "event-1",
+		CalendarID: PrimaryCalendar,
+		Title:      "Standup",
+		Location:   "Room 1",
+		StartTime:  start,
+		EndTime:    start.Add(15 * time.Minute),
+	}
+
+	remoteID, err := backend.Create(ctx, event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remoteID != "/calendars/pika/Work/event-1.ics" {
+		t.Errorf("got remote ID %q", remoteID)
+	}
+
+	// Creating the same resource again must not overwrite it
+	if _, err := backend.Create(ctx, event); err == nil {
+		t.Error("creating an existing resource succeeded")
+	}
+
+	events, err := backend.List(ctx, PrimaryCalendar, start.Add(-time.Hour), start.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("listed %d events, want 1", len(events))
+	}
+	got := events[0]
+	if got.RemoteID != remoteID || got.UID != "event-1" || got.Title != "Standup" ||
+		got.Location != "Room 1" || !got.StartTime.Equal(start) {
+		t.Errorf("listed %+v", got)
+	}
+
+	// Outside the window nothing is listed
+	events, err = backend.List(ctx, PrimaryCalendar, start.Add(time.Hour), start.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Errorf("listed %d events outside the window", len(events))
+	}
+
+	got.Title = "Daily standup"
+	got.StartTime = start.Add(30 * time.Minute)
+	got.EndTime = got.StartTime.Add(15 * time.Minute)
+	if err := backend.Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err = backend.List(ctx, PrimaryCalendar, start.Add(-time.Hour), start.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Title != "Daily standup" || !events[0].StartTime.Equal(got.StartTime) {
+		t.Fatalf("after update listed %+v", events)
+	}
+
+	if err := backend.Delete(ctx, PrimaryCalendar, remoteID); err != nil {
+		t.Fatal(err)
+	}
+	if paths := standin.Paths(); len(paths) != 0 {
+		t.Errorf("objects left after delete: %v", paths)
+	}
+
+	// Deleting again is not an error
+	if err := backend.Delete(ctx, PrimaryCalendar, remoteID); err != nil {
+		t.Errorf("deleting a missing event: %v", err)
+	}
+}
+
+func TestCalDAVUpdateKeepsOtherProperties(t *testing.T) {
+	backend, standin := newTestCalDAV(t, nil)
+	ctx := context.Background()
+
+	standin.Put("/calendars/pika/Work/imported.ics", strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:imported@example.com",
+		"DTSTART:20261020T090000Z",
+		"DTEND:20261020T100000Z",
+		"SUMMARY:Dentist",
+		"SEQUENCE:2",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"TRIGGER:-PT30M",
+		"END:VALARM",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n"))
+
+	start := time.Date(2026, 10, 20, 11, 0, 0, 0, time.UTC)
+	err := backend.Update(ctx, &Event{
+		RemoteID:  "/calendars/pika/Work/imported.ics",
+		Title:     "Dentist",
+		StartTime: start,
+		EndTime:   start.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, _, err := backend.get(ctx, backend.url.ResolveReference(&url.URL{Path: "imported.ics"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"UID:imported@example.com", "DTSTART:20261020T110000Z", "SEQUENCE:3", "TRIGGER:-PT30M"} {
+		if !strings.Contains(data, want) {
+			t.Errorf("updated object lacks %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestCalDAVUpdateFailsOnConcurrentChange(t *testing.T) {
+	const path = "/calendars/pika/Work/shared.ics"
+	object := func(summary string) string {
+		return strings.Join([]string{
+			"BEGIN:VCALENDAR",
+			"VERSION:2.0",
+			"BEGIN:VEVENT",
+			"UID:shared",
+			"DTSTART:20261020T090000Z",
+			"DTEND:20261020T100000Z",
+			"SUMMARY:" + summary,
+			"END:VEVENT",
+			"END:VCALENDAR",
+		}, "\r\n")
+	}
+
+	// Another client changes the event between our GET and PUT
+	backend, standin := newTestCalDAV(t, func(standin *caldavtest.Server, next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+			if r.Method == http.MethodGet {
+				standin.Put(path, object("Changed elsewhere"))
+			}
+		})
+	})
+	standin.Put(path, object("Review"))
+
+	start := time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC)
+	err := backend.Update(context.Background(), &Event{
+		RemoteID:  path,
+		Title:     "Review (moved)",
+		StartTime: start,
+		EndTime:   start.Add(time.Hour),
+	})
+	if err == nil || !strings.Contains(err.Error(), "412") {
+		t.Fatalf("got error %v, want a failed precondition", err)
+	}
+
+	events, err := backend.List(context.Background(), PrimaryCalendar, start.Add(-time.Hour), start.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Title != "Changed elsewhere" {
+		t.Errorf("the concurrent change was overwritten: %+v", events)
+	}
+}
+
+func TestCalDAVRequiresAuth(t *testing.T) {
+	backend, _ := newTestCalDAV(t, nil)
+	backend.password = "wrong"
+
+	start := time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC)
+	_, err := backend.List(context.Background(), PrimaryCalendar, start, start.Add(time.Hour))
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("got error %v, want unauthorized", err)
+	}
+}
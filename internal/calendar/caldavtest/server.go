@@ -0,0 +1,238 @@
+// Package caldavtest provides an in-memory CalDAV server that stands in for
+// Fastmail, Nextcloud and friends when testing the CalDAV backend or trying
+// it out locally.
+//
+// It implements just what calendar.CalDAVBackend uses: calendar-query
+// REPORT, GET, PUT with If-Match / If-None-Match, and DELETE. Recurring
+// events are returned as stored rather than expanded.
+package caldavtest
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is an in-memory CalDAV calendar collection
+type Server struct {
+	username string
+	password string
+
+	mu      sync.Mutex
+	objects map[string]*object // by path
+}
+
+type object struct {
+	data string
+	etag string
+}
+
+// NewServer creates an empty calendar. When username or password is set,
+// requests must use HTTP basic auth with them.
+func NewServer(username, password string) *Server {
+	return &Server{
+		username: username,
+		password: password,
+		objects:  make(map[string]*object),
+	}
+}
+
+// Put stores a calendar object directly, as if another client had created it
+func (s *Server) Put(path, data string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[path] = &object{data: data, etag: etagOf(data)}
+}
+
+// Remove deletes a calendar object directly, as if another client had deleted it
+func (s *Server) Remove(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, path)
+}
+
+// Paths returns the paths of the stored calendar objects
+func (s *Server) Paths() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sortedKeys(s.objects)
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.username != "" || s.password != "" {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != s.username || pass != s.password {
+			w.Header().Set("WWW-Authenticate", `Basic realm="caldavtest"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	switch r.Method {
+	case "REPORT":
+		s.handleReport(w, r)
+	case http.MethodGet:
+		s.handleGet(w, r)
+	case http.MethodPut:
+		s.handlePut(w, r)
+	case http.MethodDelete:
+		s.handleDelete(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// calendarQuery holds the parts of a calendar-query REPORT the server uses
+type calendarQuery struct {
+	TimeRange struct {
+		Start string `xml:"start,attr"`
+		End   string `xml:"end,attr"`
+	} `xml:"filter>comp-filter>comp-filter>time-range"`
+}
+
+func (s *Server) handleReport(w http.ResponseWriter, r *http.Request) {
+	var q calendarQuery
+	if err := xml.NewDecoder(r.Body).Decode(&q); err != nil {
+		http.Error(w, "Invalid calendar-query", http.StatusBadRequest)
+		return
+	}
+	start, _ := time.Parse("20060102T150405Z", q.TimeRange.Start)
+	end, _ := time.Parse("20060102T150405Z", q.TimeRange.End)
+
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>` + "\n")
+	b.WriteString(`<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">` + "\n")
+
+	s.mu.Lock()
+	for _, path := range sortedKeys(s.objects) {
+		obj := s.objects[path]
+		if !inRange(obj.data, start, end) {
+			continue
+		}
+		fmt.Fprintf(&b, `<d:response><d:href>%s</d:href><d:propstat><d:prop>`+
+			`<d:getetag>%s</d:getetag><c:calendar-data>%s</c:calendar-data>`+
+			`</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`+"\n",
+			html.EscapeString(path), html.EscapeString(obj.etag), html.EscapeString(obj.data))
+	}
+	s.mu.Unlock()
+
+	b.WriteString(`</d:multistatus>`)
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	io.WriteString(w, b.String())
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	obj, ok := s.objects[r.URL.Path]
+	s.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("ETag", obj.etag)
+	io.WriteString(w, obj.data)
+}
+
+func (s *Server) handlePut(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read body", http.StatusBadRequest)
+		return
+	}
+	data := string(body)
+	if !strings.Contains(data, "BEGIN:VCALENDAR") {
+		http.Error(w, "Not an iCalendar object", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, exists := s.objects[r.URL.Path]
+	if r.Header.Get("If-None-Match") == "*" && exists {
+		http.Error(w, "Resource exists", http.StatusPreconditionFailed)
+		return
+	}
+	if match := r.Header.Get("If-Match"); match != "" && (!exists || match != existing.etag) {
+		http.Error(w, "ETag mismatch", http.StatusPreconditionFailed)
+		return
+	}
+
+	obj := &object{data: data, etag: etagOf(data)}
+	s.objects[r.URL.Path] = obj
+	w.Header().Set("ETag", obj.etag)
+	if exists {
+		w.WriteHeader(http.StatusNoContent)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.objects[r.URL.Path]; !ok {
+		http.NotFound(w, r)
+		return
+	}
+	delete(s.objects, r.URL.Path)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// inRange reports whether an object's first DTSTART falls in [start, end).
+// Objects whose start can't be read are always included.
+func inRange(data string, start, end time.Time) bool {
+	if start.IsZero() || end.IsZero() {
+		return true
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		if !strings.HasPrefix(line, "DTSTART") {
+			continue
+		}
+		_, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return true
+		}
+		var t time.Time
+		var err error
+		switch len(value) {
+		case len("20060102"):
+			t, err = time.Parse("20060102", value)
+		case len("20060102T150405Z"):
+			t, err = time.Parse("20060102T150405Z", value)
+		default:
+			t, err = time.ParseInLocation("20060102T150405", value, time.Local)
+		}
+		if err != nil {
+			return true
+		}
+		return !t.Before(start) && t.Before(end)
+	}
+	return true
+}
+
+func etagOf(data string) string {
+	sum := sha1.Sum([]byte(data))
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+func sortedKeys(m map[string]*object) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
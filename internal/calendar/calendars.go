@@ -0,0 +1,367 @@
+package calendar
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Calendar is one of the calendars of the backend account
+type Calendar struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color,omitempty"`
+	// Primary is the account's main calendar
+	Primary bool `json:"primary"`
+	// Selected calendars are synced
+	Selected bool `json:"selected"`
+	// Default is where new events go unless another calendar is named
+	Default bool `json:"default"`
+	// ReadOnly calendars cannot take new events
+	ReadOnly bool `json:"read_only,omitempty"`
+	// DefaultAlerts are the alerts, in minutes before the start, of timed
+	// events without alarms of their own; nil for the configured ones
+	DefaultAlerts []int `json:"default_alerts,omitempty"`
+}
+
+// calendarColumns is the column list scanned by scanCalendar
+const calendarColumns = "id, name, color, is_primary, selected, is_default, read_only, default_alerts"
+
+// Calendars lists the calendars of the current backend, primary first,
+// fetching them from the backend the first time
+func (s *Service) Calendars(ctx context.Context) ([]*Calendar, error) {
+	calendars, err := s.storedCalendars(ctx)
+	if err != nil || len(calendars) > 0 || !s.IsInitialized() {
+		return calendars, err
+	}
+	if err := s.refreshCalendars(ctx); err != nil {
+		return nil, err
+	}
+	return s.storedCalendars(ctx)
+}
+
+// storedCalendars lists the calendars saved for the current backend
+func (s *Service) storedCalendars(ctx context.Context) ([]*Calendar, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+calendarColumns+`
+		FROM calendars
+		WHERE backend = ?
+		ORDER BY is_primary DESC, name COLLATE NOCASE ASC
+	`, s.backend.Name())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var calendars []*Calendar
+	for rows.Next() {
+		cal, err := scanCalendar(rows)
+		if err != nil {
+			return nil, err
+		}
+		calendars = append(calendars, cal)
+	}
+	return calendars, rows.Err()
+}
+
+// scanCalendar reads a calendar selected with calendarColumns
+func scanCalendar(row scanner) (*Calendar, error) {
+	cal := &Calendar{}
+	var color, alerts sql.NullString
+	if err := row.Scan(&cal.ID, &cal.Name, &color, &cal.Primary, &cal.Selected, &cal.Default, &cal.ReadOnly, &alerts); err != nil {
+		return nil, err
+	}
+	cal.Color = color.String
+	if alerts.Valid {
+		json.Unmarshal([]byte(alerts.String), &cal.DefaultAlerts)
+	}
+	return cal, nil
+}
+
+// refreshCalendars updates the saved calendars from the backend. New
+// calendars start out selected as the backend suggests; the user's choices
+// are kept for known ones. Calendars removed from the account are dropped
+// with their events.
+func (s *Service) refreshCalendars(ctx context.Context) error {
+	remote, err := s.backend.Calendars(ctx)
+	if err != nil {
+		return err
+	}
+	known, err := s.storedCalendars(ctx)
+	if err != nil {
+		return err
+	}
+	previous := make(map[string]*Calendar, len(known))
+	for _, cal := range known {
+		previous[cal.ID] = cal
+	}
+
+	backend := s.backend.Name()
+	for _, cal := range remote {
+		_, err := s.db.ExecContext(ctx, `
+			INSERT INTO calendars (backend, id, name, color, is_primary, selected, read_only, default_alerts, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, datetime('now'))
+			ON CONFLICT (backend, id) DO UPDATE SET
+				name = excluded.name,
+				color = excluded.color,
+				is_primary = excluded.is_primary,
+				read_only = excluded.read_only,
+				default_alerts = excluded.default_alerts,
+				updated_at = datetime('now')
+		`, backend, cal.ID, cal.Name, nullString(cal.Color), cal.Primary, cal.Selected, cal.ReadOnly, formatAlarms(cal.DefaultAlerts))
+		if err != nil {
+			return fmt.Errorf("failed to save calendar %s: %w", cal.Name, err)
+		}
+
+		// Events showing the calendar's old color follow it
+		if old := previous[cal.ID]; old != nil && old.Color != cal.Color {
+			_, _ = s.db.ExecContext(ctx, `
+				UPDATE calendar_events SET color = ?
+				WHERE backend = ? AND calendar_id = ? AND COALESCE(color, '') = ?
+			`, nullString(cal.Color), backend, cal.ID, old.Color)
+		}
+		delete(previous, cal.ID)
+	}
+
+	for id, cal := range previous {
+		fmt.Printf("Calendar removed from %s account: %s\n", backend, cal.Name)
+		s.removeCachedEvents(ctx, s.calendarEventIDs(ctx, id))
+		_, _ = s.db.ExecContext(ctx, "DELETE FROM calendars WHERE backend = ? AND id = ?", backend, id)
+	}
+
+	return s.ensureDefaultCalendar(ctx)
+}
+
+// ensureDefaultCalendar makes the primary calendar the default, and synced,
+// when no calendar is the default, e.g. on first use or after the default
+// was removed
+func (s *Service) ensureDefaultCalendar(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE calendars SET is_default = 1, selected = 1
+		WHERE backend = ? AND is_primary = 1
+			AND NOT EXISTS (SELECT 1 FROM calendars WHERE backend = ? AND is_default = 1)
+	`, s.backend.Name(), s.backend.Name())
+	return err
+}
+
+// calendarEventIDs returns the IDs of the cached events of a calendar
+func (s *Service) calendarEventIDs(ctx context.Context, calendarID string) []string {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id FROM calendar_events WHERE backend = ? AND calendar_id = ? AND remote_id IS NOT NULL",
+		s.backend.Name(), calendarID)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if rows.Scan(&id) == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// UpdateCalendar changes whether a calendar is synced and whether it is the
+// default for new events. Nil leaves a setting unchanged.
+func (s *Service) UpdateCalendar(ctx context.Context, id string, selected, isDefault *bool) (*Calendar, error) {
+	cal, err := s.calendarByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if isDefault != nil && *isDefault {
+		if cal.ReadOnly {
+			return nil, fmt.Errorf("calendar %s is read-only", cal.Name)
+		}
+		// New events need to show up, so the default is always synced
+		t := true
+		selected = &t
+	}
+	if selected != nil && !*selected && cal.Default {
+		return nil, fmt.Errorf("calendar %s is the default for new events; choose another default first", cal.Name)
+	}
+
+	backend := s.backend.Name()
+	if selected != nil && *selected != cal.Selected {
+		// Clearing the sync state makes the next sync list the calendar in full
+		_, err := s.db.ExecContext(ctx, `
+			UPDATE calendars SET selected = ?, sync_token = NULL, window_start = NULL, window_end = NULL, updated_at = datetime('now')
+			WHERE backend = ? AND id = ?
+		`, *selected, backend, id)
+		if err != nil {
+			return nil, err
+		}
+		if !*selected {
+			// Still there remotely, so this is not reported as deletions
+			_, err = s.db.ExecContext(ctx,
+				"DELETE FROM calendar_events WHERE backend = ? AND calendar_id = ? AND remote_id IS NOT NULL", backend, id)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if isDefault != nil && *isDefault != cal.Default {
+		if *isDefault {
+			_, err = s.db.ExecContext(ctx, "UPDATE calendars SET is_default = (id = ?) WHERE backend = ?", id, backend)
+		} else {
+			_, err = s.db.ExecContext(ctx, "UPDATE calendars SET is_default = 0 WHERE backend = ? AND id = ?", backend, id)
+			if err == nil {
+				err = s.ensureDefaultCalendar(ctx)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if selected != nil && *selected && !cal.Selected {
+		go s.sync()
+	}
+	return s.calendarByID(ctx, id)
+}
+
+// calendarByID returns a saved calendar of the current backend
+func (s *Service) calendarByID(ctx context.Context, id string) (*Calendar, error) {
+	cal, err := scanCalendar(s.db.QueryRowContext(ctx, `
+		SELECT `+calendarColumns+`
+		FROM calendars
+		WHERE backend = ? AND id = ?
+	`, s.backend.Name(), id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("calendar %q not found", id)
+	}
+	return cal, err
+}
+
+// ResolveCalendar finds a calendar by ID or name, e.g. "family" or "the
+// family calendar". An empty name gives the default calendar.
+func (s *Service) ResolveCalendar(ctx context.Context, name string) (*Calendar, error) {
+	calendars, err := s.Calendars(ctx)
+	if err != nil || len(calendars) == 0 {
+		if name == "" {
+			// Not connected yet; events are created locally for now
+			return &Calendar{ID: PrimaryCalendar, Primary: true, Default: true}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("no calendars available")
+	}
+
+	if name == "" {
+		for _, cal := range calendars {
+			if cal.Default {
+				return cal, nil
+			}
+		}
+		return calendars[0], nil
+	}
+
+	for _, cal := range calendars {
+		if cal.ID == name {
+			return cal, nil
+		}
+	}
+
+	query := strings.ToLower(strings.TrimSpace(name))
+	query = strings.TrimPrefix(query, "the ")
+	query = strings.TrimPrefix(query, "my ")
+	query = strings.TrimSpace(strings.TrimSuffix(query, "calendar"))
+	for _, cal := range calendars {
+		if strings.EqualFold(cal.Name, query) {
+			return cal, nil
+		}
+	}
+	for _, cal := range calendars {
+		if query != "" && strings.Contains(strings.ToLower(cal.Name), query) {
+			return cal, nil
+		}
+	}
+
+	names := make([]string, len(calendars))
+	for i, cal := range calendars {
+		names[i] = cal.Name
+	}
+	return nil, fmt.Errorf("no calendar named %q; calendars are: %s", name, strings.Join(names, ", "))
+}
+
+// nameCalendars fills in the calendar name of events of the current backend
+func (s *Service) nameCalendars(ctx context.Context, events []*Event) {
+	calendars, err := s.storedCalendars(ctx)
+	if err != nil || len(calendars) == 0 {
+		return
+	}
+	names := make(map[string]string, len(calendars))
+	for _, cal := range calendars {
+		names[cal.ID] = cal.Name
+	}
+
+	backend := s.backend.Name()
+	for _, e := range events {
+		// Events not (yet) on a backend are in the current one's calendar
+		if e.Backend == "" || e.Backend == backend {
+			e.Calendar = names[e.CalendarID]
+		}
+	}
+}
+
+// calendarSync is the sync progress of one calendar
+type calendarSync struct {
+	token       string
+	windowStart time.Time
+	windowEnd   time.Time
+}
+
+// covers reports whether the last full sync still covers the window from
+// to, allowing the end to fall behind by syncWindowSlack
+func (cs *calendarSync) covers(from, to time.Time) bool {
+	if cs.windowStart.IsZero() || cs.windowEnd.IsZero() {
+		return false
+	}
+	return !cs.windowStart.After(from) && !cs.windowEnd.Before(to.Add(-syncWindowSlack))
+}
+
+// loadCalendarSync reads the sync progress of a calendar
+func (s *Service) loadCalendarSync(ctx context.Context, calendarID string) (*calendarSync, error) {
+	var token, windowStart, windowEnd sql.NullString
+	err := s.db.QueryRowContext(ctx,
+		"SELECT sync_token, window_start, window_end FROM calendars WHERE backend = ? AND id = ?",
+		s.backend.Name(), calendarID,
+	).Scan(&token, &windowStart, &windowEnd)
+	if err == sql.ErrNoRows {
+		return &calendarSync{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &calendarSync{
+		token:       token.String,
+		windowStart: parseTimeString(windowStart.String),
+		windowEnd:   parseTimeString(windowEnd.String),
+	}, nil
+}
+
+// saveCalendarSync records the sync token of a calendar and, after a full
+// sync, the window it covered
+func (s *Service) saveCalendarSync(ctx context.Context, calendarID string, result *SyncResult, from, to time.Time) error {
+	var windowStart, windowEnd interface{}
+	if result.Complete {
+		windowStart, windowEnd = formatSyncTime(from), formatSyncTime(to)
+	}
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE calendars
+		SET sync_token = ?,
+			window_start = COALESCE(?, window_start),
+			window_end = COALESCE(?, window_end),
+			updated_at = datetime('now')
+		WHERE backend = ? AND id = ?
+	`, nullString(result.Token), windowStart, windowEnd, s.backend.Name(), calendarID)
+	return err
+}
@@ -0,0 +1,104 @@
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ConflictError is returned instead of creating or moving an event that
+// would overlap other events, with alternatives to offer the user
+type ConflictError struct {
+	// Event is the event as it would have been saved
+	Event     *Event   `json:"event"`
+	Conflicts []*Event `json:"conflicts"`
+	// NextFree is the first free time for the event from its start on
+	NextFree *Slot `json:"next_free,omitempty"`
+	// MoveTo is the first free time for the first conflicting event, should
+	// it make way
+	MoveTo *Slot `json:"move_to,omitempty"`
+}
+
+func (e *ConflictError) Error() string {
+	first := e.Conflicts[0]
+	msg := fmt.Sprintf("%s overlaps with %s at %s", e.Event.Title, first.Title, first.StartTime.Local().Format("Mon Jan 2 3:04 PM"))
+	switch n := len(e.Conflicts) - 1; {
+	case n == 1:
+		msg += " and 1 other event"
+	case n > 1:
+		msg += fmt.Sprintf(" and %d other events", n)
+	}
+	return msg
+}
+
+// Conflicts returns the cached events overlapping start to end, other than
+// the event excludeID. All-day events do not conflict.
+func (s *Service) Conflicts(ctx context.Context, start, end time.Time, excludeID string) ([]*Event, error) {
+	events, err := s.eventsBetween(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []*Event
+	for _, e := range events {
+		if e.AllDay || e.ID == excludeID {
+			continue
+		}
+		if e.StartTime.Before(end) && e.EndTime.After(start) {
+			conflicts = append(conflicts, e)
+		}
+	}
+	return conflicts, nil
+}
+
+// checkConflicts returns a *ConflictError when event overlaps other events
+func (s *Service) checkConflicts(ctx context.Context, event *Event) error {
+	if event.AllDay {
+		return nil
+	}
+	conflicts, err := s.Conflicts(ctx, event.StartTime, event.EndTime, event.ID)
+	if err != nil {
+		return err
+	}
+	// Occurrences the backend lists on their own are part of the series
+	if event.RemoteID != "" {
+		conflicts = slices.DeleteFunc(conflicts, func(c *Event) bool { return c.SeriesID == event.RemoteID })
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	s.nameCalendars(ctx, conflicts)
+
+	first := conflicts[0]
+	return &ConflictError{
+		Event:     event,
+		Conflicts: conflicts,
+		NextFree:  s.nextFreeSlot(ctx, event.StartTime, event.EndTime.Sub(event.StartTime), []string{event.ID}),
+		MoveTo: s.nextFreeSlot(ctx, first.StartTime, first.EndTime.Sub(first.StartTime), []string{first.ID, event.ID},
+			&busyPeriod{Start: event.StartTime, End: event.EndTime}),
+	}
+}
+
+// checkSeriesConflicts is checkConflicts for every occurrence of a recurring
+// event up to the end of the synced window, where other events are known.
+// The error is about the first occurrence that overlaps.
+func (s *Service) checkSeriesConflicts(ctx context.Context, event *Event) error {
+	if len(event.Recurrence) == 0 {
+		return s.checkConflicts(ctx, event)
+	}
+	if event.AllDay {
+		return nil
+	}
+	now := time.Now()
+	occurrences, err := expandRecurrence(event, now, now.Add(s.syncFuture))
+	if err != nil {
+		return err
+	}
+	for _, o := range occurrences {
+		if err := s.checkConflicts(ctx, o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/baswilson/pika/internal/database"
+)
+
+// newTestService returns a service with the local backend on a fresh database
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+
+	driver, err := database.NewSQLiteDriver(filepath.Join(t.TempDir(), "pika.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	if err := driver.Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return NewServiceWithBackend(LocalBackend{}, driver.DB())
+}
+
+func TestRecurringEventsCheckEveryOccurrence(t *testing.T) {
+	service := newTestService(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	at := func(days, hour int) string {
+		return time.Date(now.Year(), now.Month(), now.Day()+days, hour, 0, 0, 0, time.Local).Format(time.RFC3339)
+	}
+
+	// A meeting in three days is free of the first occurrences of a daily
+	// series starting tomorrow, but not of the third
+	if _, err := service.CreateEvent(ctx, "Dentist", "", at(3, 10), at(3, 11), ""); err != nil {
+		t.Fatal(err)
+	}
+	daily, err := ParseRecurrence("FREQ=DAILY")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = service.CreateEventWithOptions(ctx, "Standup", "", at(1, 10), at(1, 11), "", CreateOptions{Recurrence: daily})
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("creating the series: got %v, want a conflict", err)
+	}
+	if want := at(3, 10); conflict.Event.StartTime.Local().Format(time.RFC3339) != want {
+		t.Errorf("conflict reported at %s, want the occurrence at %s", conflict.Event.StartTime.Local(), want)
+	}
+
+	// Turning a free event into the same series is refused too
+	single, err := service.CreateEvent(ctx, "Standup", "", at(1, 10), at(1, 11), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = service.UpdateEventWithOptions(ctx, single.ID, nil, nil, nil, nil, nil, UpdateOptions{Recurrence: &daily})
+	if !errors.As(err, &conflict) {
+		t.Fatalf("adding the recurrence: got %v, want a conflict", err)
+	}
+
+	// As is moving a series that fits onto the meeting
+	twice, err := ParseRecurrence("FREQ=DAILY;COUNT=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	short, err := service.CreateEventWithOptions(ctx, "Review", "", at(1, 14), at(1, 15), "", CreateOptions{Recurrence: twice})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, end := at(2, 10), at(2, 11)
+	_, err = service.UpdateEventWithOptions(ctx, short.ID, nil, nil, &start, &end, nil, UpdateOptions{Scope: ScopeSeries})
+	if !errors.As(err, &conflict) {
+		t.Fatalf("moving the series: got %v, want a conflict", err)
+	}
+
+	// A single occurrence only checks itself
+	_, err = service.UpdateEventWithOptions(ctx, short.ID, nil, nil, &start, &end, nil, UpdateOptions{})
+	if err != nil {
+		t.Errorf("moving one occurrence: %v", err)
+	}
+}
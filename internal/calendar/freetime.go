@@ -0,0 +1,406 @@
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FreeTimeQuery describes the free time to look for
+type FreeTimeQuery struct {
+	From     time.Time
+	To       time.Time
+	Duration time.Duration
+	// WorkStart and WorkEnd bound the hours searched each day, in minutes
+	// after local midnight, e.g. 540 and 1020 for 9:00 to 17:00
+	WorkStart int
+	WorkEnd   int
+	// Weekends includes Saturdays and Sundays. Ranges without a weekday are
+	// searched in full regardless.
+	Weekends bool
+	// Buffer is kept free between a slot and other events
+	Buffer time.Duration
+	// Limit is the number of slots returned
+	Limit int
+
+	// For conflict suggestions: the events being moved are not busy, extra
+	// times are, and only cached events count
+	exclude   []string
+	extra     []*busyPeriod
+	cacheOnly bool
+}
+
+// Slot is a free period long enough for the requested duration
+type Slot struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Score float64   `json:"score"`
+}
+
+// busyPeriod is a time taken by an event
+type busyPeriod struct {
+	Start time.Time
+	End   time.Time
+}
+
+// freeBusyBackend is implemented by backends that can report busy times
+// beyond the cached events, such as calendars that are not synced
+type freeBusyBackend interface {
+	FreeBusy(ctx context.Context, calendarIDs []string, from, to time.Time) ([]busyPeriod, error)
+}
+
+// Defaults for free time searches
+const (
+	defaultWorkStart = 9 * 60
+	defaultWorkEnd   = 17 * 60
+	defaultSlotLimit = 5
+)
+
+// slotStep is the granularity of slot start times
+const slotStep = 15 * time.Minute
+
+// SetWorkingHours sets the default hours searched for free time, as
+// "09:00-17:00"
+func (s *Service) SetWorkingHours(hours string) error {
+	start, end, err := ParseWorkingHours(hours)
+	if err != nil {
+		return err
+	}
+	s.workStart, s.workEnd = start, end
+	return nil
+}
+
+// SetMeetingBuffer sets the default time kept free around other events
+func (s *Service) SetMeetingBuffer(buffer time.Duration) {
+	s.buffer = buffer
+}
+
+// NewFreeTimeQuery returns a query for free time between from and to with
+// the configured working hours and buffer
+func (s *Service) NewFreeTimeQuery(from, to time.Time, duration time.Duration) FreeTimeQuery {
+	return FreeTimeQuery{
+		From:      from,
+		To:        to,
+		Duration:  duration,
+		WorkStart: s.workStart,
+		WorkEnd:   s.workEnd,
+		Buffer:    s.buffer,
+		Limit:     defaultSlotLimit,
+	}
+}
+
+// ParseWorkingHours parses working hours such as "09:00-17:00" into
+// minutes after midnight
+func ParseWorkingHours(hours string) (start, end int, err error) {
+	from, to, ok := strings.Cut(hours, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid working hours %q, expected e.g. 09:00-17:00", hours)
+	}
+	if start, err = ParseClock(from); err != nil {
+		return 0, 0, err
+	}
+	if end, err = ParseClock(to); err != nil {
+		return 0, 0, err
+	}
+	if end <= start {
+		return 0, 0, fmt.Errorf("invalid working hours %q: end is not after start", hours)
+	}
+	return start, end, nil
+}
+
+// ParseClock parses a time of day such as "9:30" or "17:00" into minutes
+// after midnight. "24:00" is the end of the day.
+func ParseClock(clock string) (int, error) {
+	h, m, ok := strings.Cut(strings.TrimSpace(clock), ":")
+	if !ok {
+		m = "0"
+	}
+	hours, err1 := strconv.Atoi(h)
+	minutes, err2 := strconv.Atoi(m)
+	if err1 != nil || err2 != nil || hours < 0 || minutes < 0 || minutes > 59 || hours*60+minutes > 24*60 {
+		return 0, fmt.Errorf("invalid time of day %q, expected e.g. 09:00", clock)
+	}
+	return hours*60 + minutes, nil
+}
+
+// FindFreeTime returns the best slots for an event of the requested
+// duration, best first. Busy times come from the cached events and, when
+// the backend can report them, its free/busy information. All-day events
+// do not count as busy.
+//
+// Slots are ranked by how soon they are, how much room they leave around
+// other events and whether they start on the hour, with at most two slots
+// per day until every day had its turn.
+func (s *Service) FindFreeTime(ctx context.Context, q FreeTimeQuery) ([]*Slot, error) {
+	if q.Limit <= 0 {
+		q.Limit = defaultSlotLimit
+	}
+	candidates, err := s.freeSlots(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	return pickSlots(candidates, q.Limit), nil
+}
+
+// nextFreeSlot returns the earliest free slot of duration from start on,
+// within a week, or nil, leaving out the events in exclude. Events outside
+// the working hours look for the next free time at any hour.
+func (s *Service) nextFreeSlot(ctx context.Context, start time.Time, duration time.Duration, exclude []string, extra ...*busyPeriod) *Slot {
+	q := s.NewFreeTimeQuery(start, start.AddDate(0, 0, 7), duration)
+	local := start.Local()
+	if minutes := local.Hour()*60 + local.Minute(); minutes < q.WorkStart || minutes+int(duration.Minutes()) > q.WorkEnd {
+		q.WorkStart, q.WorkEnd = 0, 24*60
+	}
+	q.Weekends = local.Weekday() == time.Saturday || local.Weekday() == time.Sunday
+	q.exclude, q.extra, q.cacheOnly = exclude, extra, true
+
+	candidates, err := s.freeSlots(ctx, q)
+	if err != nil || len(candidates) == 0 {
+		return nil
+	}
+	return candidates[0]
+}
+
+// freeSlots returns the scored candidate slots for a query, soonest first
+func (s *Service) freeSlots(ctx context.Context, q FreeTimeQuery) ([]*Slot, error) {
+	if q.Duration <= 0 {
+		return nil, fmt.Errorf("duration must be positive")
+	}
+	if q.WorkEnd <= q.WorkStart {
+		return nil, fmt.Errorf("working hours end before they start")
+	}
+
+	// Only future slots can be booked
+	from := q.From
+	if now := time.Now(); from.Before(now) {
+		from = now
+	}
+	from = ceilTime(from, slotStep)
+	if !q.To.After(from) {
+		return nil, fmt.Errorf("the time range is in the past")
+	}
+
+	busy, err := s.busyPeriods(ctx, from.Add(-q.Buffer), q.To.Add(q.Buffer), q.exclude, !q.cacheOnly)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range q.extra {
+		busy = append(busy, &busyPeriod{Start: b.Start, End: b.End})
+	}
+	for _, b := range busy {
+		b.Start = b.Start.Add(-q.Buffer)
+		b.End = b.End.Add(q.Buffer)
+	}
+	busy = mergeBusy(busy)
+
+	weekends := q.Weekends || !hasWeekday(from, q.To)
+	var candidates []*Slot
+	for _, window := range workWindows(from, q.To, q.WorkStart, q.WorkEnd, weekends) {
+		for _, gap := range freeGaps(window, busy) {
+			candidates = append(candidates, slotsInGap(gap, window, q.Duration, from)...)
+		}
+	}
+
+	return candidates, nil
+}
+
+// busyPeriods collects the busy times between from and to, leaving out the
+// events in exclude. With remote set the backend's free/busy information is
+// added when it has any.
+func (s *Service) busyPeriods(ctx context.Context, from, to time.Time, exclude []string, remote bool) ([]*busyPeriod, error) {
+	events, err := s.eventsBetween(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var busy []*busyPeriod
+	for _, e := range events {
+		if !e.AllDay && !slices.Contains(exclude, e.ID) {
+			busy = append(busy, &busyPeriod{Start: e.StartTime, End: e.EndTime})
+		}
+	}
+
+	if fb, ok := s.backend.(freeBusyBackend); ok && remote && s.IsInitialized() {
+		var ids []string
+		if calendars, err := s.Calendars(ctx); err == nil {
+			for _, cal := range calendars {
+				if cal.Selected {
+					ids = append(ids, cal.ID)
+				}
+			}
+		}
+		if len(ids) == 0 {
+			ids = []string{PrimaryCalendar}
+		}
+
+		// The cache still gives a useful answer when this fails
+		periods, err := fb.FreeBusy(ctx, ids, from, to)
+		if err != nil {
+			fmt.Printf("Failed to fetch free/busy times: %v\n", err)
+		}
+		for i := range periods {
+			busy = append(busy, &periods[i])
+		}
+	}
+	return busy, nil
+}
+
+// mergeBusy sorts busy periods and merges the overlapping ones
+func mergeBusy(busy []*busyPeriod) []*busyPeriod {
+	sort.Slice(busy, func(i, j int) bool { return busy[i].Start.Before(busy[j].Start) })
+
+	var merged []*busyPeriod
+	for _, b := range busy {
+		if n := len(merged); n > 0 && !b.Start.After(merged[n-1].End) {
+			if b.End.After(merged[n-1].End) {
+				merged[n-1].End = b.End
+			}
+			continue
+		}
+		merged = append(merged, &busyPeriod{Start: b.Start, End: b.End})
+	}
+	return merged
+}
+
+// hasWeekday reports whether a weekday falls between from and to
+func hasWeekday(from, to time.Time) bool {
+	for day := startOfDay(from.Local()); day.Before(to); day = day.AddDate(0, 0, 1) {
+		if wd := day.Weekday(); wd != time.Saturday && wd != time.Sunday {
+			return true
+		}
+	}
+	return false
+}
+
+// workWindows returns the working hours of each day between from and to,
+// in local time
+func workWindows(from, to time.Time, workStart, workEnd int, weekends bool) []*busyPeriod {
+	var windows []*busyPeriod
+	for day := startOfDay(from.Local()); day.Before(to); day = day.AddDate(0, 0, 1) {
+		if wd := day.Weekday(); !weekends && (wd == time.Saturday || wd == time.Sunday) {
+			continue
+		}
+		start := day.Add(time.Duration(workStart) * time.Minute)
+		end := day.Add(time.Duration(workEnd) * time.Minute)
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if end.After(start) {
+			windows = append(windows, &busyPeriod{Start: start, End: end})
+		}
+	}
+	return windows
+}
+
+// freeGaps returns the parts of window not covered by the merged busy periods
+func freeGaps(window *busyPeriod, busy []*busyPeriod) []*busyPeriod {
+	var gaps []*busyPeriod
+	cursor := window.Start
+	for _, b := range busy {
+		if !b.End.After(cursor) {
+			continue
+		}
+		if !b.Start.Before(window.End) {
+			break
+		}
+		if b.Start.After(cursor) {
+			gaps = append(gaps, &busyPeriod{Start: cursor, End: b.Start})
+		}
+		cursor = b.End
+	}
+	if window.End.After(cursor) {
+		gaps = append(gaps, &busyPeriod{Start: cursor, End: window.End})
+	}
+	return gaps
+}
+
+// maxSlotsPerGap limits the candidates taken from one free gap
+const maxSlotsPerGap = 4
+
+// slotsInGap returns scored candidate slots in a free gap of window,
+// starting every half hour from the first quarter hour
+func slotsInGap(gap, window *busyPeriod, duration time.Duration, from time.Time) []*Slot {
+	var slots []*Slot
+	for start := ceilTime(gap.Start, slotStep); !start.Add(duration).After(gap.End) && len(slots) < maxSlotsPerGap; start = start.Add(2 * slotStep) {
+		end := start.Add(duration)
+
+		// Sooner is better, by a point a day
+		score := 10 - start.Sub(from).Hours()/24
+		// Room before and after other events, up to an hour, is worth up
+		// to two points; the edges of the working hours need none
+		before, after := start.Sub(gap.Start), gap.End.Sub(end)
+		if gap.Start.Equal(window.Start) {
+			before = time.Hour
+		}
+		if gap.End.Equal(window.End) {
+			after = time.Hour
+		}
+		room := min(before, after, time.Hour)
+		score += 2 * room.Hours()
+		// Round start times are easier to remember
+		switch start.Minute() {
+		case 0:
+			score += 0.5
+		case 30:
+			score += 0.25
+		}
+
+		slots = append(slots, &Slot{Start: start, End: end, Score: float64(int(score*100)) / 100})
+	}
+	return slots
+}
+
+// pickSlots returns the best limit slots, at most two per day while other
+// days still have candidates
+func pickSlots(candidates []*Slot, limit int) []*Slot {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].Start.Before(candidates[j].Start)
+	})
+
+	picked := make([]*Slot, 0, limit)
+	taken := make(map[*Slot]bool)
+	perDay := make(map[string]int)
+	for _, slot := range candidates {
+		if len(picked) == limit {
+			return picked
+		}
+		day := slot.Start.Local().Format("2006-01-02")
+		if perDay[day] < 2 {
+			perDay[day]++
+			taken[slot] = true
+			picked = append(picked, slot)
+		}
+	}
+	for _, slot := range candidates {
+		if len(picked) == limit {
+			break
+		}
+		if !taken[slot] {
+			picked = append(picked, slot)
+		}
+	}
+	return picked
+}
+
+// startOfDay returns local midnight of t's day
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// ceilTime rounds t up to a multiple of d
+func ceilTime(t time.Time, d time.Duration) time.Time {
+	if r := t.Truncate(d); r.Before(t) {
+		return r.Add(d)
+	}
+	return t
+}
@@ -0,0 +1,313 @@
+package calendar
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+	gcalendar "google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+)
+
+// ErrReauthRequired is returned when Google no longer accepts the saved
+// authorization, such as after access was revoked in the Google account
+var ErrReauthRequired = errors.New("google calendar access was revoked or has expired, connect it again")
+
+// ErrInvalidState is returned for an authorization callback that does not
+// belong to an authorization started by PIKA, or that came too late
+var ErrInvalidState = errors.New("unknown or expired authorization request")
+
+// authRequestTTL is how long an authorization started with AuthURL can be
+// completed
+const authRequestTTL = 10 * time.Minute
+
+// googleRevokeURL is Google's OAuth token revocation endpoint
+const googleRevokeURL = "https://oauth2.googleapis.com/revoke"
+
+// authRequest is an authorization in progress
+type authRequest struct {
+	verifier string // PKCE code verifier
+	expires  time.Time
+}
+
+// AuthStatus reports whether the calendar backend is authorized
+type AuthStatus struct {
+	Backend   string `json:"backend"`
+	Connected bool   `json:"connected"`
+	// ReauthRequired is true when Google rejected the saved authorization,
+	// so Google Calendar has to be connected again
+	ReauthRequired bool       `json:"reauth_required"`
+	Error          string     `json:"error,omitempty"`
+	ErrorAt        *time.Time `json:"error_at,omitempty"`
+}
+
+// persistingTokenSource hands out the access tokens of a GoogleBackend,
+// saving refreshed tokens and noting when Google rejects the refresh token
+type persistingTokenSource struct {
+	g    *GoogleBackend
+	base oauth2.TokenSource
+}
+
+// Token returns a valid access token, refreshing it when it expired
+func (p *persistingTokenSource) Token() (*oauth2.Token, error) {
+	token, err := p.base.Token()
+	if err != nil {
+		var retrieveErr *oauth2.RetrieveError
+		if errors.As(err, &retrieveErr) && retrieveErr.ErrorCode == "invalid_grant" {
+			p.g.requireReauth(p, retrieveErr)
+			return nil, fmt.Errorf("%w (%s)", ErrReauthRequired, retrieveErr.ErrorCode)
+		}
+		return nil, err
+	}
+
+	p.g.mu.Lock()
+	if p.g.source != p {
+		// Disconnected or connected again since
+		p.g.mu.Unlock()
+		return nil, fmt.Errorf("google calendar is not connected")
+	}
+	refreshed := token.AccessToken != p.g.token.AccessToken
+	if refreshed {
+		p.g.token = token
+	}
+	p.g.mu.Unlock()
+
+	if refreshed {
+		if err := p.g.saveToken(context.Background(), token); err != nil {
+			fmt.Printf("Warning: failed to save refreshed token: %v\n", err)
+		}
+	}
+	return token, nil
+}
+
+// AuthURL starts an authorization and returns the URL to send the user to.
+// The authorization is bound to a random state and a PKCE verifier that
+// Exchange checks.
+func (g *GoogleBackend) AuthURL() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	state := hex.EncodeToString(b)
+	verifier := oauth2.GenerateVerifier()
+
+	now := time.Now()
+	g.mu.Lock()
+	for s, req := range g.requests {
+		if now.After(req.expires) {
+			delete(g.requests, s)
+		}
+	}
+	g.requests[state] = &authRequest{verifier: verifier, expires: now.Add(authRequestTTL)}
+	g.mu.Unlock()
+
+	// Force consent to always get a refresh token
+	return g.config.AuthCodeURL(state,
+		oauth2.AccessTypeOffline, oauth2.ApprovalForce, oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Exchange completes the authorization with the given state, exchanging its
+// code for tokens and saving them
+func (g *GoogleBackend) Exchange(ctx context.Context, state, code string) error {
+	g.mu.Lock()
+	req := g.requests[state]
+	delete(g.requests, state)
+	g.mu.Unlock()
+	if req == nil || time.Now().After(req.expires) {
+		return ErrInvalidState
+	}
+
+	token, err := g.config.Exchange(ctx, code, oauth2.VerifierOption(req.verifier))
+	if err != nil {
+		return fmt.Errorf("failed to exchange code: %w", err)
+	}
+
+	g.setToken(token)
+
+	// Save token to database
+	return g.saveToken(ctx, token)
+}
+
+// Disconnect revokes PIKA's access at Google and forgets the tokens. The
+// tokens are forgotten even when Google could not be reached.
+func (g *GoogleBackend) Disconnect(ctx context.Context) error {
+	g.mu.Lock()
+	token := g.token
+	g.token = nil
+	g.source = nil
+	g.reauthErr = nil
+	g.reauthAt = time.Time{}
+	g.mu.Unlock()
+
+	if _, err := g.db.ExecContext(ctx, "DELETE FROM oauth_tokens WHERE provider = 'google'"); err != nil {
+		return fmt.Errorf("failed to delete token: %w", err)
+	}
+	if token == nil {
+		return nil
+	}
+	return g.revoke(ctx, token)
+}
+
+// revoke revokes a token at Google. Revoking the refresh token revokes its
+// access tokens too.
+func (g *GoogleBackend) revoke(ctx context.Context, token *oauth2.Token) error {
+	value := token.RefreshToken
+	if value == "" {
+		value = token.AccessToken
+	}
+
+	form := url.Values{"token": {value}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, googleRevokeURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Google answers 400 for a token that is already invalid
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest {
+		return fmt.Errorf("failed to revoke token: %s", resp.Status)
+	}
+	return nil
+}
+
+// SetReauthCallback sets the function to call when Google rejects the
+// saved authorization
+func (g *GoogleBackend) SetReauthCallback(cb func(err error)) {
+	g.mu.Lock()
+	g.onReauth = cb
+	g.mu.Unlock()
+}
+
+// requireReauth notes that Google rejected the refresh token of a token
+// source, calling the reauth callback the first time
+func (g *GoogleBackend) requireReauth(source *persistingTokenSource, err error) {
+	g.mu.Lock()
+	if g.source != source || g.reauthErr != nil {
+		g.mu.Unlock()
+		return
+	}
+	g.reauthErr = err
+	g.reauthAt = time.Now()
+	cb := g.onReauth
+	g.mu.Unlock()
+
+	fmt.Printf("Google Calendar needs to be connected again: %v\n", err)
+	if cb != nil {
+		cb(err)
+	}
+}
+
+// AuthStatus reports whether Google Calendar is authorized
+func (g *GoogleBackend) AuthStatus() *AuthStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	status := &AuthStatus{
+		Backend:        g.Name(),
+		Connected:      g.token != nil && g.reauthErr == nil,
+		ReauthRequired: g.reauthErr != nil,
+	}
+	if g.reauthErr != nil {
+		status.Error = g.reauthErr.Error()
+		status.ErrorAt = timePtr(g.reauthAt)
+	}
+	return status
+}
+
+// setToken makes a token the current one, with a token source that keeps
+// it refreshed
+func (g *GoogleBackend) setToken(token *oauth2.Token) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.token = token
+	// Not a request's context: the source outlives requests
+	g.source = &persistingTokenSource{g: g, base: g.config.TokenSource(context.Background(), token)}
+	g.reauthErr = nil
+	g.reauthAt = time.Time{}
+}
+
+// client returns a Calendar API client with auto-refreshing tokens
+func (g *GoogleBackend) client(ctx context.Context) (*gcalendar.Service, error) {
+	g.mu.Lock()
+	source := g.source
+	g.mu.Unlock()
+	if source == nil {
+		return nil, fmt.Errorf("google calendar is not connected")
+	}
+
+	// Fail early when the token cannot be refreshed
+	if _, err := source.Token(); err != nil {
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+
+	client := oauth2.NewClient(ctx, source)
+	return gcalendar.NewService(ctx, option.WithHTTPClient(client))
+}
+
+// loadToken loads the OAuth token from the database
+func (g *GoogleBackend) loadToken() {
+	ctx := context.Background()
+	query := `
+		SELECT access_token, refresh_token, token_type, expiry
+		FROM oauth_tokens
+		WHERE provider = 'google'
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	var accessToken, refreshToken, tokenType, expiryStr string
+
+	err := g.db.QueryRowContext(ctx, query).Scan(&accessToken, &refreshToken, &tokenType, &expiryStr)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			fmt.Printf("Failed to load OAuth token: %v\n", err)
+		}
+		return
+	}
+
+	// Parse expiry time - try multiple formats
+	var expiry time.Time
+	for _, format := range []string{time.RFC3339Nano, time.RFC3339, "2006-01-02 15:04:05.999999999-07:00", "2006-01-02T15:04:05Z"} {
+		if parsed, err := time.Parse(format, expiryStr); err == nil {
+			expiry = parsed
+			break
+		}
+	}
+
+	g.setToken(&oauth2.Token{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    tokenType,
+		Expiry:       expiry,
+	})
+	fmt.Println("Google Calendar token loaded from database")
+}
+
+// saveToken saves the OAuth token to the database
+func (g *GoogleBackend) saveToken(ctx context.Context, token *oauth2.Token) error {
+	// Delete existing tokens
+	_, _ = g.db.ExecContext(ctx, "DELETE FROM oauth_tokens WHERE provider = 'google'")
+
+	query := `
+		INSERT INTO oauth_tokens (id, provider, access_token, refresh_token, token_type, expiry)
+		VALUES (?, 'google', ?, ?, ?, ?)
+	`
+	_, err := g.db.ExecContext(ctx, query,
+		uuid.New().String(), token.AccessToken, token.RefreshToken, token.TokenType, token.Expiry)
+	return err
+}
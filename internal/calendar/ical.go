@@ -0,0 +1,509 @@
+package calendar
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// iCalendar (RFC 5545) reading and writing, covering what PIKA stores for
+// an event. Properties it does not know are kept so objects round-trip.
+
+// icalProp is one content line, e.g. DTSTART;TZID=Europe/Amsterdam:20260101T090000
+type icalProp struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// icalComponent is a BEGIN/END block such as VCALENDAR or VEVENT
+type icalComponent struct {
+	Name       string
+	Props      []*icalProp
+	Components []*icalComponent
+}
+
+// iCalendar time layouts
+const (
+	icalUTC   = "20060102T150405Z"
+	icalLocal = "20060102T150405"
+	icalDate  = "20060102"
+)
+
+// parseICal parses an iCalendar object and returns its outermost component
+func parseICal(data string) (*icalComponent, error) {
+	var stack []*icalComponent
+	var root *icalComponent
+
+	for _, line := range unfoldICal(data) {
+		if line == "" {
+			continue
+		}
+		prop, err := parseICalLine(line)
+		if err != nil {
+			return nil, err
+		}
+
+		switch prop.Name {
+		case "BEGIN":
+			c := &icalComponent{Name: strings.ToUpper(prop.Value)}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Components = append(parent.Components, c)
+			} else if root == nil {
+				root = c
+			}
+			stack = append(stack, c)
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].Name != strings.ToUpper(prop.Value) {
+				return nil, fmt.Errorf("unexpected END:%s", prop.Value)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("property %s outside a component", prop.Name)
+			}
+			c := stack[len(stack)-1]
+			c.Props = append(c.Props, prop)
+		}
+	}
+
+	if root == nil {
+		return nil, fmt.Errorf("no iCalendar component found")
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("unterminated %s component", stack[len(stack)-1].Name)
+	}
+	return root, nil
+}
+
+// unfoldICal splits data into content lines, joining folded continuations
+func unfoldICal(data string) []string {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	var lines []string
+	for _, line := range strings.Split(data, "\n") {
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, strings.TrimRight(line, "\r"))
+	}
+	return lines
+}
+
+// parseICalLine parses a single unfolded content line
+func parseICalLine(line string) (*icalProp, error) {
+	// The value starts at the first colon outside a quoted parameter value
+	inQuotes := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			inQuotes = !inQuotes
+		} else if r == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return nil, fmt.Errorf("invalid iCalendar line %q", line)
+	}
+
+	prop := &icalProp{Value: line[colon+1:]}
+	parts := splitICalParams(line[:colon])
+	prop.Name = strings.ToUpper(parts[0])
+	for _, p := range parts[1:] {
+		key, value, _ := strings.Cut(p, "=")
+		if prop.Params == nil {
+			prop.Params = make(map[string]string)
+		}
+		prop.Params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+	}
+	return prop, nil
+}
+
+// splitICalParams splits "NAME;A=1;B=\"x;y\"" on semicolons outside quotes
+func splitICalParams(s string) []string {
+	var parts []string
+	inQuotes := false
+	start := 0
+	for i, r := range s {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case r == ';' && !inQuotes:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// get returns the first property with the given name, or nil
+func (c *icalComponent) get(name string) *icalProp {
+	for _, p := range c.Props {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// text returns the unescaped value of a text property, or ""
+func (c *icalComponent) text(name string) string {
+	if p := c.get(name); p != nil {
+		return unescapeICalText(p.Value)
+	}
+	return ""
+}
+
+// set replaces all properties with the given name by a single one
+func (c *icalComponent) set(name, value string, params map[string]string) {
+	c.remove(name)
+	c.Props = append(c.Props, &icalProp{Name: name, Params: params, Value: value})
+}
+
+// setText sets a text property, removing it when value is empty
+func (c *icalComponent) setText(name, value string) {
+	if value == "" {
+		c.remove(name)
+		return
+	}
+	c.set(name, escapeICalText(value), nil)
+}
+
+// remove drops all properties with the given name
+func (c *icalComponent) remove(name string) {
+	props := c.Props[:0]
+	for _, p := range c.Props {
+		if p.Name != name {
+			props = append(props, p)
+		}
+	}
+	c.Props = props
+}
+
+// children returns the sub-components with the given name
+func (c *icalComponent) children(name string) []*icalComponent {
+	var result []*icalComponent
+	for _, child := range c.Components {
+		if child.Name == name {
+			result = append(result, child)
+		}
+	}
+	return result
+}
+
+// encode writes the component in iCalendar format with CRLF line endings
+func (c *icalComponent) encode() string {
+	var b strings.Builder
+	c.encodeTo(&b)
+	return b.String()
+}
+
+func (c *icalComponent) encodeTo(b *strings.Builder) {
+	writeICalLine(b, "BEGIN:"+c.Name)
+	for _, p := range c.Props {
+		writeICalLine(b, p.line())
+	}
+	for _, child := range c.Components {
+		child.encodeTo(b)
+	}
+	writeICalLine(b, "END:"+c.Name)
+}
+
+// line returns the unfolded content line of a property
+func (p *icalProp) line() string {
+	line := p.Name
+	keys := make([]string, 0, len(p.Params))
+	for key := range p.Params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := p.Params[key]
+		if strings.ContainsAny(value, ":;,") {
+			value = `"` + value + `"`
+		}
+		line += ";" + key + "=" + value
+	}
+	return line + ":" + p.Value
+}
+
+// writeICalLine writes a content line folded at 75 octets
+func writeICalLine(b *strings.Builder, line string) {
+	for len(line) > 75 {
+		cut := 75
+		// Don't split a UTF-8 sequence
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+// escapeICalText escapes a TEXT value
+func escapeICalText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// unescapeICalText reverses escapeICalText
+func unescapeICalText(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(s)
+}
+
+// icalTime parses a DATE or DATE-TIME property. allDay is true for dates.
+func icalTime(p *icalProp) (t time.Time, allDay bool, err error) {
+	value := p.Value
+	if p.Params["VALUE"] == "DATE" || len(value) == len(icalDate) {
+		t, err = time.ParseInLocation(icalDate, value, time.UTC)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err = time.Parse(icalUTC, value)
+		return t, false, err
+	}
+
+	loc := time.Local
+	if tzid := p.Params["TZID"]; tzid != "" {
+		if l, lerr := time.LoadLocation(tzid); lerr == nil {
+			loc = l
+		}
+	}
+	t, err = time.ParseInLocation(icalLocal, value, loc)
+	return t, false, err
+}
+
+// recurrenceProps are the VEVENT properties kept in Event.Recurrence
+var recurrenceProps = []string{"RRULE", "EXDATE", "RDATE"}
+
+// veventToEvent reads the fields PIKA uses from a VEVENT
+func veventToEvent(c *icalComponent) (*Event, error) {
+	startProp := c.get("DTSTART")
+	if startProp == nil {
+		return nil, fmt.Errorf("event has no DTSTART")
+	}
+	start, allDay, err := icalTime(startProp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DTSTART: %w", err)
+	}
+
+	end := start
+	if endProp := c.get("DTEND"); endProp != nil {
+		if end, _, err = icalTime(endProp); err != nil {
+			return nil, fmt.Errorf("invalid DTEND: %w", err)
+		}
+	} else if durProp := c.get("DURATION"); durProp != nil {
+		d, err := parseICalDuration(durProp.Value)
+		if err != nil {
+			return nil, err
+		}
+		end = start.Add(d)
+	} else if allDay {
+		end = start.AddDate(0, 0, 1)
+	}
+
+	event := &Event{
+		UID:         c.text("UID"),
+		Title:       c.text("SUMMARY"),
+		Description: c.text("DESCRIPTION"),
+		Location:    c.text("LOCATION"),
+		StartTime:   start.UTC(),
+		EndTime:     end.UTC(),
+		AllDay:      allDay,
+	}
+	for _, p := range c.Props {
+		for _, name := range recurrenceProps {
+			if p.Name == name {
+				event.Recurrence = append(event.Recurrence, p.line())
+			}
+		}
+	}
+	for _, p := range c.Props {
+		if p.Name == "ATTENDEE" {
+			if a := attendeeFromICal(p); a != nil {
+				event.Attendees = append(event.Attendees, a)
+			}
+		}
+	}
+	for _, alarm := range c.children("VALARM") {
+		if minutes, ok := alarmMinutes(alarm, start, end); ok {
+			event.Alarms = append(event.Alarms, minutes)
+		}
+	}
+	return event, nil
+}
+
+// alarmMinutes returns how many minutes before the event start a VALARM
+// triggers. Alarms after the start are not supported.
+func alarmMinutes(alarm *icalComponent, start, end time.Time) (int, bool) {
+	trigger := alarm.get("TRIGGER")
+	if trigger == nil {
+		return 0, false
+	}
+
+	var at time.Time
+	if trigger.Params["VALUE"] == "DATE-TIME" {
+		t, _, err := icalTime(trigger)
+		if err != nil {
+			return 0, false
+		}
+		at = t
+	} else {
+		d, err := parseICalDuration(trigger.Value)
+		if err != nil {
+			return 0, false
+		}
+		if trigger.Params["RELATED"] == "END" {
+			at = end.Add(d)
+		} else {
+			at = start.Add(d)
+		}
+	}
+
+	before := start.Sub(at)
+	if before < 0 {
+		return 0, false
+	}
+	return int(before / time.Minute), true
+}
+
+// applyEventToVEvent writes an event's fields, recurrence and attendees to
+// a VEVENT, keeping its other properties
+func applyEventToVEvent(c *icalComponent, event *Event) {
+	c.setText("SUMMARY", event.Title)
+	c.setText("DESCRIPTION", event.Description)
+	c.setText("LOCATION", event.Location)
+	if event.AllDay {
+		date := map[string]string{"VALUE": "DATE"}
+		c.set("DTSTART", event.StartTime.UTC().Format(icalDate), date)
+		c.set("DTEND", event.EndTime.UTC().Format(icalDate), date)
+	} else {
+		c.set("DTSTART", event.StartTime.UTC().Format(icalUTC), nil)
+		c.set("DTEND", event.EndTime.UTC().Format(icalUTC), nil)
+	}
+	c.remove("DURATION")
+	c.set("DTSTAMP", time.Now().UTC().Format(icalUTC), nil)
+
+	for _, name := range recurrenceProps {
+		c.remove(name)
+	}
+	for _, line := range event.Recurrence {
+		if p, err := parseICalLine(line); err == nil {
+			c.Props = append(c.Props, p)
+		}
+	}
+	setICalAttendees(c, event.Attendees)
+}
+
+// eventToVEvent builds a complete VEVENT for an event, including its
+// alarms
+func eventToVEvent(uid string, event *Event) *icalComponent {
+	vevent := &icalComponent{Name: "VEVENT"}
+	vevent.set("UID", uid, nil)
+	applyEventToVEvent(vevent, event)
+
+	for _, minutes := range event.Alarms {
+		alarm := &icalComponent{Name: "VALARM"}
+		alarm.set("ACTION", "DISPLAY", nil)
+		alarm.setText("DESCRIPTION", event.Title)
+		alarm.set("TRIGGER", formatICalDuration(-time.Duration(minutes)*time.Minute), nil)
+		vevent.Components = append(vevent.Components, alarm)
+	}
+	return vevent
+}
+
+// newVCalendar returns an empty calendar object
+func newVCalendar() *icalComponent {
+	return &icalComponent{
+		Name: "VCALENDAR",
+		Props: []*icalProp{
+			{Name: "VERSION", Value: "2.0"},
+			{Name: "PRODID", Value: "-//PIKA//Calendar//EN"},
+			{Name: "CALSCALE", Value: "GREGORIAN"},
+		},
+	}
+}
+
+// formatICalDuration formats a duration as a DURATION value, e.g. "-PT15M"
+func formatICalDuration(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	if d == 0 {
+		return "PT0S"
+	}
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%sP%dD", sign, d/(24*time.Hour))
+	}
+
+	value := sign + "PT"
+	if h := d / time.Hour; h > 0 {
+		value += fmt.Sprintf("%dH", h)
+	}
+	if m := d % time.Hour / time.Minute; m > 0 {
+		value += fmt.Sprintf("%dM", m)
+	}
+	if sec := d % time.Minute / time.Second; sec > 0 {
+		value += fmt.Sprintf("%dS", sec)
+	}
+	return value
+}
+
+// parseICalDuration parses a DURATION value such as "PT1H30M" or "-P1D"
+func parseICalDuration(s string) (time.Duration, error) {
+	value := s
+	sign := time.Duration(1)
+	if strings.HasPrefix(value, "-") {
+		sign = -1
+		value = value[1:]
+	}
+	value = strings.TrimPrefix(value, "+")
+	if !strings.HasPrefix(value, "P") {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	value = value[1:]
+
+	var d time.Duration
+	inTime := false
+	num := ""
+	for _, r := range value {
+		switch {
+		case r == 'T':
+			inTime = true
+		case r >= '0' && r <= '9':
+			num += string(r)
+		default:
+			n, err := strconv.Atoi(num)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			num = ""
+			switch {
+			case r == 'W':
+				d += time.Duration(n) * 7 * 24 * time.Hour
+			case r == 'D':
+				d += time.Duration(n) * 24 * time.Hour
+			case r == 'H' && inTime:
+				d += time.Duration(n) * time.Hour
+			case r == 'M' && inTime:
+				d += time.Duration(n) * time.Minute
+			case r == 'S' && inTime:
+				d += time.Duration(n) * time.Second
+			default:
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+		}
+	}
+	if num != "" {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return sign * d, nil
+}
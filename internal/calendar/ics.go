@@ -0,0 +1,195 @@
+package calendar
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ImportResult summarizes an iCalendar import
+type ImportResult struct {
+	Created int      `json:"created"`
+	Updated int      `json:"updated"`
+	Skipped int      `json:"skipped"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// Import adds the events of an iCalendar file to the local calendar.
+// Events are matched on their UID, so importing the same file again updates
+// rather than duplicates them. A modified occurrence of a recurring event
+// (a VEVENT with RECURRENCE-ID) is excluded from its series and imported as
+// a separate event.
+func (s *Service) Import(ctx context.Context, data string) (*ImportResult, error) {
+	cal, err := parseICal(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid iCalendar file: %w", err)
+	}
+	if cal.Name != "VCALENDAR" {
+		return nil, fmt.Errorf("invalid iCalendar file: expected VCALENDAR, got %s", cal.Name)
+	}
+
+	result := &ImportResult{}
+	var series, overrides []*Event
+	exdates := make(map[string][]string) // by series UID
+	for _, vevent := range cal.children("VEVENT") {
+		event, err := veventToEvent(vevent)
+		if err != nil {
+			result.Skipped++
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", vevent.text("SUMMARY"), err))
+			continue
+		}
+		if event.UID == "" {
+			event.UID = uuid.New().String()
+		}
+		if rid := vevent.get("RECURRENCE-ID"); rid != nil {
+			// Exclude the original occurrence from the series
+			exdate := &icalProp{Name: "EXDATE", Params: rid.Params, Value: rid.Value}
+			exdates[event.UID] = append(exdates[event.UID], exdate.line())
+			event.UID += "/" + rid.Value
+			event.Recurrence = nil
+			overrides = append(overrides, event)
+			continue
+		}
+		series = append(series, event)
+	}
+
+	for _, event := range series {
+		if len(event.Recurrence) > 0 {
+			event.Recurrence = append(event.Recurrence, exdates[event.UID]...)
+		}
+	}
+
+	for _, event := range append(series, overrides...) {
+		created, err := s.importEvent(ctx, event)
+		if err != nil {
+			result.Skipped++
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", event.Title, err))
+			continue
+		}
+		if created {
+			result.Created++
+		} else {
+			result.Updated++
+		}
+	}
+	return result, nil
+}
+
+// importEvent saves an imported event, updating the event with the same UID
+// if there is one. created is false for updates.
+func (s *Service) importEvent(ctx context.Context, event *Event) (created bool, err error) {
+	var existingID string
+	err = s.db.QueryRowContext(ctx,
+		"SELECT id FROM calendar_events WHERE uid = ? AND remote_id IS NULL", event.UID,
+	).Scan(&existingID)
+	if err == sql.ErrNoRows {
+		event.ID = uuid.New().String()
+		return true, s.saveLocalEvent(ctx, event)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	previous, err := s.GetEventByID(ctx, existingID)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE calendar_events
+		SET title = ?, description = ?, start_time = ?, end_time = ?, location = ?,
+			all_day = ?, recurrence = ?, attendees = ?, alarms = ?, updated_at = datetime('now')
+		WHERE id = ?
+	`, event.Title, event.Description,
+		event.StartTime.UTC().Format("2006-01-02 15:04:05"), event.EndTime.UTC().Format("2006-01-02 15:04:05"),
+		event.Location, event.AllDay, formatRecurrence(event.Recurrence),
+		formatAttendees(event.Attendees), formatAlarms(event.Alarms), existingID)
+	if err != nil {
+		return false, err
+	}
+
+	event.ID = existingID
+	if !event.StartTime.Equal(previous.StartTime) {
+		s.notifyChange(event, false)
+	}
+	return false, nil
+}
+
+// feedRefresh is the refresh interval suggested to subscribed apps
+const feedRefresh = 15 * time.Minute
+
+// Export returns every event in the calendar as an iCalendar file
+func (s *Service) Export(ctx context.Context) (string, error) {
+	query := `
+		SELECT ` + eventColumns + `
+		FROM calendar_events
+		ORDER BY start_time ASC
+	`
+	events, err := s.queryEvents(ctx, query)
+	if err != nil {
+		return "", err
+	}
+
+	cal := newVCalendar()
+	cal.set("X-WR-CALNAME", "PIKA", nil)
+	// Tell subscribed apps how often to refresh the feed
+	cal.set("REFRESH-INTERVAL", formatICalDuration(feedRefresh), map[string]string{"VALUE": "DURATION"})
+	cal.set("X-PUBLISHED-TTL", formatICalDuration(feedRefresh), nil)
+	for _, e := range events {
+		uid := e.UID
+		if uid == "" {
+			uid = e.ID + "@pika"
+		}
+		cal.Components = append(cal.Components, eventToVEvent(uid, e))
+	}
+	return cal.encode(), nil
+}
+
+// feedTokenKey is the app_config key of the ICS feed token
+const feedTokenKey = "calendar_feed_token"
+
+// FeedToken returns the secret token in the calendar's ICS feed URL,
+// creating one the first time
+func (s *Service) FeedToken(ctx context.Context) (string, error) {
+	var token string
+	err := s.db.QueryRowContext(ctx, "SELECT value FROM app_config WHERE key = ?", feedTokenKey).Scan(&token)
+	if err == nil && token != "" {
+		return token, nil
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+	return s.RotateFeedToken(ctx)
+}
+
+// RotateFeedToken replaces the feed token, cutting off every app subscribed
+// to the old feed URL
+func (s *Service) RotateFeedToken(ctx context.Context) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO app_config (key, value, updated_at)
+		VALUES (?, ?, datetime('now'))
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = datetime('now')
+	`, feedTokenKey, token)
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ValidFeedToken reports whether token is the current feed token
+func (s *Service) ValidFeedToken(ctx context.Context, token string) bool {
+	current, err := s.FeedToken(ctx)
+	return err == nil && subtle.ConstantTimeCompare([]byte(current), []byte(token)) == 1
+}
@@ -0,0 +1,70 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/baswilson/pika/internal/rrule"
+)
+
+// expandRecurrence returns the occurrences of an event overlapping
+// [from, to). An event without an RRULE is returned as is when it overlaps.
+// Local recurring events are expanded in the local time zone so they keep
+// their wall-clock time across DST changes; all-day events in UTC.
+func expandRecurrence(e *Event, from, to time.Time) ([]*Event, error) {
+	loc := time.Local
+	if e.AllDay {
+		loc = time.UTC
+	}
+
+	var rule *rrule.Rule
+	exdates := make(map[int64]bool)
+	for _, line := range e.Recurrence {
+		p, err := parseICalLine(line)
+		if err != nil {
+			return nil, err
+		}
+		switch p.Name {
+		case "RRULE":
+			if rule, err = rrule.Parse(p.Value, loc); err != nil {
+				return nil, err
+			}
+		case "EXDATE":
+			for _, v := range strings.Split(p.Value, ",") {
+				t, _, err := icalTime(&icalProp{Name: p.Name, Params: p.Params, Value: v})
+				if err != nil {
+					return nil, fmt.Errorf("invalid EXDATE: %w", err)
+				}
+				exdates[t.Unix()] = true
+			}
+		}
+	}
+
+	duration := e.EndTime.Sub(e.StartTime)
+	overlaps := func(t time.Time) bool {
+		return t.Before(to) && (t.Add(duration).After(from) || !t.Before(from))
+	}
+
+	if rule == nil {
+		if overlaps(e.StartTime) {
+			return []*Event{e}, nil
+		}
+		return nil, nil
+	}
+
+	var result []*Event
+	rule.Each(e.StartTime.In(loc), func(t time.Time) bool {
+		if !t.Before(to) {
+			return false
+		}
+		if !exdates[t.Unix()] && overlaps(t) {
+			occurrence := *e
+			occurrence.StartTime = t.UTC()
+			occurrence.EndTime = t.Add(duration).UTC()
+			result = append(result, &occurrence)
+		}
+		return true
+	})
+	return result, nil
+}
@@ -0,0 +1,195 @@
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/baswilson/pika/internal/rrule"
+	"github.com/google/uuid"
+)
+
+// Scopes of an edit to a recurring event
+const (
+	// ScopeOccurrence changes a single occurrence
+	ScopeOccurrence = "this"
+	// ScopeSeries changes every occurrence
+	ScopeSeries = "series"
+)
+
+// seriesBackend is implemented by backends that list the occurrences of a
+// recurring event as events of their own, so that the recurring event
+// itself can be edited
+type seriesBackend interface {
+	Series(ctx context.Context, calendarID, seriesID string) (*Event, error)
+}
+
+// ParseRecurrence turns a rule such as "FREQ=WEEKLY;BYDAY=MO" or
+// "RRULE:FREQ=WEEKLY;BYDAY=MO" into Event.Recurrence lines
+func ParseRecurrence(rule string) ([]string, error) {
+	rule = strings.ToUpper(strings.TrimSpace(rule))
+	rule = strings.TrimPrefix(rule, "RRULE:")
+	if _, err := rrule.Parse(rule, time.Local); err != nil {
+		return nil, fmt.Errorf("invalid recurrence %q: %w", rule, err)
+	}
+	return []string{"RRULE:" + rule}, nil
+}
+
+// findOccurrence returns the occurrence of a recurring event starting at
+// at, or the next one from now when at is zero. A series without upcoming
+// occurrences is edited from its first one.
+func findOccurrence(event *Event, at time.Time) (*Event, error) {
+	if at.IsZero() {
+		now := time.Now()
+		occurrences, err := expandRecurrence(event, now, now.AddDate(1, 0, 0))
+		if err != nil {
+			return nil, err
+		}
+		if len(occurrences) == 0 {
+			return event, nil
+		}
+		return occurrences[0], nil
+	}
+
+	occurrences, err := expandRecurrence(event, at, at.Add(time.Second))
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range occurrences {
+		if o.StartTime.Equal(at) {
+			return o, nil
+		}
+	}
+	return nil, fmt.Errorf("%s has no occurrence at %s", event.Title, at.Local().Format("Mon Jan 2 3:04 PM"))
+}
+
+// shiftExceptions moves the EXDATEs of a recurrence along with a series
+// that moved by shift, so skipped occurrences stay skipped
+func shiftExceptions(recurrence []string, shift time.Duration, allDay bool) []string {
+	if shift == 0 {
+		return recurrence
+	}
+	shifted := make([]string, len(recurrence))
+	for i, line := range recurrence {
+		shifted[i] = line
+		p, err := parseICalLine(line)
+		if err != nil || p.Name != "EXDATE" {
+			continue
+		}
+		var values []string
+		for _, v := range strings.Split(p.Value, ",") {
+			t, _, err := icalTime(&icalProp{Name: p.Name, Params: p.Params, Value: v})
+			if err != nil {
+				values = nil
+				break
+			}
+			if allDay {
+				values = append(values, t.Add(shift).Format(icalDate))
+			} else {
+				values = append(values, t.Add(shift).UTC().Format(icalUTC))
+			}
+		}
+		if values == nil {
+			continue
+		}
+		params := map[string]string{}
+		if allDay {
+			params["VALUE"] = "DATE"
+		}
+		shifted[i] = (&icalProp{Name: "EXDATE", Params: params, Value: strings.Join(values, ",")}).line()
+	}
+	return shifted
+}
+
+// detachOccurrence applies an edit to one occurrence of a recurring event:
+// the series skips the occurrence, which becomes an event of its own
+func (s *Service) detachOccurrence(ctx context.Context, series, occurrence, edited *Event) (*Event, error) {
+	exdate := "EXDATE:" + occurrence.StartTime.UTC().Format(icalUTC)
+	if series.AllDay {
+		exdate = "EXDATE;VALUE=DATE:" + occurrence.StartTime.UTC().Format(icalDate)
+	}
+	updated := *series
+	updated.Recurrence = append(slices.Clone(series.Recurrence), exdate)
+	if err := s.saveEventUpdate(ctx, series, &updated); err != nil {
+		return nil, err
+	}
+
+	edited.ID = uuid.New().String()
+	edited.RemoteID = ""
+	edited.Backend = ""
+	edited.UID = ""
+	edited.Recurrence = nil
+	edited.CreatedAt = time.Now()
+	if err := s.saveLocalEvent(ctx, edited); err != nil {
+		return nil, err
+	}
+	if !edited.StartTime.Equal(occurrence.StartTime) {
+		s.notifyChange(edited, false)
+	}
+
+	// The occurrence goes where its series is
+	if s.onBackend(series) {
+		remoteID, err := s.backend.Create(ctx, edited)
+		if err != nil {
+			fmt.Printf("Failed to create %s event: %v\n", s.backend.Name(), err)
+		} else if remoteID != "" {
+			edited.RemoteID = remoteID
+			edited.Backend = s.backend.Name()
+			s.setRemoteID(ctx, edited.ID, remoteID)
+		}
+	}
+	return edited, nil
+}
+
+// updateRemoteSeries applies an edit of an occurrence listed on its own to
+// its whole series in the backend. The cached occurrences are refreshed by
+// the sync that follows. With checkConflicts, no occurrence of the edited
+// series may overlap other events.
+func (s *Service) updateRemoteSeries(ctx context.Context, occurrence, edited *Event, opts UpdateOptions, checkConflicts bool) (*Event, error) {
+	backend, ok := s.backend.(seriesBackend)
+	if !ok || !s.onBackend(occurrence) {
+		return nil, fmt.Errorf("the series of %s can only be edited while %s is connected", occurrence.Title, s.backend.Name())
+	}
+
+	series, err := backend.Series(ctx, occurrence.CalendarID, occurrence.SeriesID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the series of %s: %w", occurrence.Title, err)
+	}
+	series.Title = edited.Title
+	series.Description = edited.Description
+	series.Location = edited.Location
+	series.Attendees = edited.Attendees
+	if opts.Recurrence != nil {
+		series.Recurrence = edited.Recurrence
+	}
+	series.StartTime = series.StartTime.Add(edited.StartTime.Sub(occurrence.StartTime))
+	series.EndTime = series.StartTime.Add(edited.EndTime.Sub(edited.StartTime))
+	if checkConflicts {
+		if err := s.checkSeriesConflicts(ctx, series); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.backend.Update(ctx, series); err != nil {
+		return nil, fmt.Errorf("failed to update the series of %s: %w", occurrence.Title, err)
+	}
+	go s.sync()
+	return edited, nil
+}
+
+// removeSeriesEvents drops cached recurring events of a calendar that the
+// backend now lists occurrence by occurrence, such as one just created.
+// The event still exists, so this is not reported as a change.
+func (s *Service) removeSeriesEvents(ctx context.Context, calendarID string, seriesIDs []string) {
+	for _, seriesID := range seriesIDs {
+		_, err := s.db.ExecContext(ctx, `
+			DELETE FROM calendar_events
+			WHERE backend = ? AND calendar_id = ? AND remote_id = ? AND COALESCE(recurrence, '') != ''
+		`, s.backend.Name(), calendarID, seriesID)
+		if err != nil {
+			fmt.Printf("Failed to remove recurring event %s: %v\n", seriesID, err)
+		}
+	}
+}
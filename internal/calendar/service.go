@@ -0,0 +1,1114 @@
+package calendar
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/baswilson/pika/internal/config"
+	"github.com/google/uuid"
+)
+
+// Event represents a calendar event
+type Event struct {
+	ID          string    `json:"id"`
+	RemoteID    string    `json:"remote_id,omitempty"`
+	Backend     string    `json:"backend,omitempty"` // backend RemoteID belongs to
+	UID         string    `json:"uid,omitempty"`     // iCalendar UID of imported events
+	CalendarID  string    `json:"calendar_id,omitempty"`
+	Calendar    string    `json:"calendar,omitempty"` // name of the calendar
+	Color       string    `json:"color,omitempty"`    // e.g. "#a4bdfc"
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+	Location    string    `json:"location,omitempty"`
+	// AllDay events start and end at midnight UTC of their dates; the end is exclusive
+	AllDay bool `json:"all_day,omitempty"`
+	// Recurrence holds RRULE, EXDATE and RDATE lines, e.g. "RRULE:FREQ=WEEKLY;BYDAY=MO"
+	Recurrence []string `json:"recurrence,omitempty"`
+	// SeriesID is the remote ID of the recurring event an occurrence belongs
+	// to, for backends that list occurrences as events of their own
+	SeriesID  string      `json:"series_id,omitempty"`
+	Attendees []*Attendee `json:"attendees,omitempty"`
+	// Alarms are alerts in minutes before the start. Nil means the default
+	// alerts of the calendar or config; empty means no alerts.
+	Alarms    []int     `json:"alarms,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// EventChange reports an event that moved to a new start time or was deleted
+type EventChange struct {
+	Event   *Event // for deletions, the event as it was
+	Deleted bool
+}
+
+// Service keeps a local cache of calendar events in sync with a Backend
+type Service struct {
+	backend    Backend
+	db         *sql.DB
+	syncTicker *time.Ticker
+	stopSync   chan struct{}
+	onReminder func(event *Event, minutesBefore int)
+	onChange   func(change EventChange)
+
+	// syncPast and syncFuture bound the window of cached events around now
+	syncPast   time.Duration
+	syncFuture time.Duration
+
+	// workStart, workEnd and buffer are the defaults for free time searches
+	workStart int
+	workEnd   int
+	buffer    time.Duration
+
+	// alertMinutes are the default alerts of timed events, and allDayAlert
+	// the minutes after midnight all-day events are announced, or -1
+	alertMinutes []int
+	allDayAlert  int
+
+	syncMu  sync.Mutex // serializes syncs
+	stateMu sync.Mutex
+	running bool
+	last    syncCounts // changes applied by the latest sync
+}
+
+// NewService creates a new calendar service using the backend selected in
+// the config
+func NewService(cfg *config.Config, db *sql.DB) *Service {
+	s := NewServiceWithBackend(newBackend(cfg, db), db)
+	s.SetSyncWindow(cfg.CalendarSyncPastDays, cfg.CalendarSyncFutureDays)
+	if err := s.SetWorkingHours(cfg.CalendarWorkHours); err != nil {
+		fmt.Printf("Warning: %v, using 09:00-17:00\n", err)
+	}
+	s.SetMeetingBuffer(time.Duration(cfg.CalendarBufferMinutes) * time.Minute)
+	if alerts, err := ParseAlertMinutes(cfg.CalendarAlertMinutes); err != nil {
+		fmt.Printf("Warning: %v, using 15 and 5 minutes\n", err)
+	} else {
+		s.SetDefaultAlerts(alerts)
+	}
+	if err := s.SetAllDayAlert(cfg.CalendarAllDayAlert); err != nil {
+		fmt.Printf("Warning: %v, announcing all-day events at 08:00\n", err)
+	}
+	return s
+}
+
+// NewServiceWithBackend creates a calendar service syncing with the given backend
+func NewServiceWithBackend(backend Backend, db *sql.DB) *Service {
+	return &Service{
+		backend:      backend,
+		db:           db,
+		stopSync:     make(chan struct{}),
+		syncPast:     defaultSyncPastDays * 24 * time.Hour,
+		syncFuture:   defaultSyncFutureDays * 24 * time.Hour,
+		workStart:    defaultWorkStart,
+		workEnd:      defaultWorkEnd,
+		alertMinutes: defaultAlerts,
+		allDayAlert:  defaultAllDayAlert,
+	}
+}
+
+// SetSyncWindow sets how many days before and after now events are synced.
+// Non-positive values keep the defaults.
+func (s *Service) SetSyncWindow(pastDays, futureDays int) {
+	if pastDays > 0 {
+		s.syncPast = time.Duration(pastDays) * 24 * time.Hour
+	}
+	if futureDays > 0 {
+		s.syncFuture = time.Duration(futureDays) * 24 * time.Hour
+	}
+}
+
+// BackendName returns the name of the calendar backend in use
+func (s *Service) BackendName() string {
+	return s.backend.Name()
+}
+
+// SetReminderCallback sets the function to call when a reminder is triggered
+func (s *Service) SetReminderCallback(cb func(event *Event, minutesBefore int)) {
+	s.onReminder = cb
+}
+
+// SetChangeCallback sets the function to call when an event moves or is
+// deleted, whether through PIKA or in the remote calendar
+func (s *Service) SetChangeCallback(cb func(change EventChange)) {
+	s.onChange = cb
+}
+
+// notifyChange runs the change callback, if any
+func (s *Service) notifyChange(event *Event, deleted bool) {
+	if s.onChange != nil {
+		s.onChange(EventChange{Event: event, Deleted: deleted})
+	}
+}
+
+// StartBackgroundSync starts the background sync and reminder service
+func (s *Service) StartBackgroundSync() {
+	s.syncTicker = time.NewTicker(5 * time.Minute)
+
+	go func() {
+		// Initial sync
+		s.sync()
+
+		for {
+			select {
+			case <-s.syncTicker.C:
+				s.sync()
+				s.checkReminders()
+			case <-s.stopSync:
+				s.syncTicker.Stop()
+				return
+			}
+		}
+	}()
+
+	// Also run reminder check more frequently (every minute)
+	go func() {
+		reminderTicker := time.NewTicker(1 * time.Minute)
+		defer reminderTicker.Stop()
+
+		for {
+			select {
+			case <-reminderTicker.C:
+				s.checkReminders()
+			case <-s.stopSync:
+				return
+			}
+		}
+	}()
+
+	fmt.Println("Calendar background sync started (every 5 minutes)")
+}
+
+// StopBackgroundSync stops the background sync service
+func (s *Service) StopBackgroundSync() {
+	close(s.stopSync)
+}
+
+// Sync fetches changes to the selected calendars from the backend and
+// applies them to the local cache. With a sync token from an earlier sync
+// only the changes since then are fetched; a calendar is listed in full
+// again when there is none or when the window has moved on since its last
+// full sync.
+func (s *Service) Sync(ctx context.Context) error {
+	if !s.IsInitialized() {
+		return fmt.Errorf("calendar backend %s is not connected", s.backend.Name())
+	}
+
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+	s.setRunning(true)
+	defer s.setRunning(false)
+
+	now := time.Now()
+	from, to := now.Add(-s.syncPast), now.Add(s.syncFuture)
+
+	if err := s.refreshCalendars(ctx); err != nil {
+		s.recordSyncError(err)
+		return err
+	}
+	calendars, err := s.storedCalendars(ctx)
+	if err != nil {
+		return err
+	}
+
+	var counts syncCounts
+	var syncErr error
+	full := false
+	for _, cal := range calendars {
+		if !cal.Selected {
+			continue
+		}
+		c, complete, err := s.syncCalendar(ctx, cal, from, to)
+		if err != nil {
+			if syncErr == nil {
+				syncErr = fmt.Errorf("%s: %w", cal.Name, err)
+			}
+			continue
+		}
+		counts.Updated += c.Updated
+		counts.Deleted += c.Deleted
+		full = full || complete
+	}
+	if full {
+		s.pruneEventsBefore(ctx, from)
+	}
+
+	s.stateMu.Lock()
+	s.last = counts
+	s.stateMu.Unlock()
+	if syncErr != nil {
+		s.recordSyncError(syncErr)
+		return syncErr
+	}
+	if err := s.saveSyncState(ctx, now, full); err != nil {
+		return err
+	}
+
+	fmt.Printf("Calendar synced: %d events updated, %d deleted\n", counts.Updated, counts.Deleted)
+	return nil
+}
+
+// syncCalendar applies the changes to one calendar. complete is true when
+// the calendar was listed in full.
+func (s *Service) syncCalendar(ctx context.Context, cal *Calendar, from, to time.Time) (counts syncCounts, complete bool, err error) {
+	state, err := s.loadCalendarSync(ctx, cal.ID)
+	if err != nil {
+		return counts, false, err
+	}
+	token := state.token
+	if token != "" && !state.covers(from, to) {
+		token = ""
+	}
+
+	result, err := s.backend.Sync(ctx, cal.ID, from, to, token)
+	if err != nil {
+		return counts, false, err
+	}
+
+	seen := make(map[string]bool, len(result.Events))
+	series := make(map[string]bool)
+	for _, remote := range result.Events {
+		seen[remote.RemoteID] = true
+		if remote.SeriesID != "" {
+			series[remote.SeriesID] = true
+		}
+		remote.CalendarID = cal.ID
+		if remote.Color == "" {
+			remote.Color = cal.Color
+		}
+		event, moved := s.upsertRemoteEvent(ctx, remote)
+		if event == nil {
+			continue
+		}
+		counts.Updated++
+		if moved {
+			s.notifyChange(event, false)
+		}
+	}
+	for _, remoteID := range result.Deleted {
+		counts.Deleted += s.removeRemoteEvent(ctx, cal.ID, remoteID)
+	}
+	if len(series) > 0 {
+		s.removeSeriesEvents(ctx, cal.ID, slices.Collect(maps.Keys(series)))
+	}
+
+	// Events in the window that the backend no longer returns were deleted
+	// there. Only safe to tell when the listing was complete.
+	if result.Complete {
+		counts.Deleted += s.removeDeletedEvents(ctx, cal.ID, seen, from, to)
+	}
+
+	return counts, result.Complete, s.saveCalendarSync(ctx, cal.ID, result, from, to)
+}
+
+// sync runs a background sync, logging failures
+func (s *Service) sync() {
+	if s.backend.Name() == BackendLocal {
+		return
+	}
+	if !s.IsInitialized() {
+		fmt.Println("Calendar sync skipped: not initialized")
+		return
+	}
+	fmt.Printf("Starting calendar sync from %s...\n", s.backend.Name())
+
+	ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+	defer cancel()
+
+	if err := s.Sync(ctx); err != nil {
+		fmt.Printf("Calendar sync error: %v\n", err)
+	}
+}
+
+// upsertRemoteEvent inserts or updates an event from the backend.
+// moved is true when an event already cached has a new start time.
+func (s *Service) upsertRemoteEvent(ctx context.Context, remote *Event) (event *Event, moved bool) {
+	backend := s.backend.Name()
+
+	// Convert to UTC for consistent storage and comparison
+	startTimeUTC := remote.StartTime.UTC().Format("2006-01-02 15:04:05")
+	endTimeUTC := remote.EndTime.UTC().Format("2006-01-02 15:04:05")
+
+	// Remember the cached start time to detect moves
+	var localID, previousStart string
+	err := s.db.QueryRowContext(ctx,
+		"SELECT id, start_time FROM calendar_events WHERE backend = ? AND calendar_id = ? AND remote_id = ?",
+		backend, remote.CalendarID, remote.RemoteID,
+	).Scan(&localID, &previousStart)
+	if err != nil {
+		localID = uuid.New().String()
+	}
+
+	query := `
+		INSERT INTO calendar_events (id, remote_id, backend, calendar_id, color, uid, title, description, start_time, end_time, location, all_day, recurrence, series_id, attendees, alarms, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, datetime('now'), datetime('now'))
+		ON CONFLICT (backend, calendar_id, remote_id) DO UPDATE SET
+			color = excluded.color,
+			uid = excluded.uid,
+			title = excluded.title,
+			description = excluded.description,
+			start_time = excluded.start_time,
+			end_time = excluded.end_time,
+			location = excluded.location,
+			all_day = excluded.all_day,
+			recurrence = excluded.recurrence,
+			series_id = excluded.series_id,
+			attendees = excluded.attendees,
+			alarms = excluded.alarms,
+			updated_at = datetime('now')
+	`
+
+	_, err = s.db.ExecContext(ctx, query,
+		localID,
+		remote.RemoteID,
+		backend,
+		remote.CalendarID,
+		nullString(remote.Color),
+		nullString(remote.UID),
+		remote.Title,
+		remote.Description,
+		startTimeUTC,
+		endTimeUTC,
+		remote.Location,
+		remote.AllDay,
+		formatRecurrence(remote.Recurrence),
+		nullString(remote.SeriesID),
+		formatAttendees(remote.Attendees),
+		formatAlarms(remote.Alarms),
+	)
+
+	if err != nil {
+		fmt.Printf("Failed to cache event %s: %v\n", remote.Title, err)
+		return nil, false
+	}
+
+	event = &Event{
+		ID:          localID,
+		RemoteID:    remote.RemoteID,
+		Backend:     backend,
+		CalendarID:  remote.CalendarID,
+		Color:       remote.Color,
+		UID:         remote.UID,
+		Title:       remote.Title,
+		Description: remote.Description,
+		StartTime:   remote.StartTime.UTC(),
+		EndTime:     remote.EndTime.UTC(),
+		Location:    remote.Location,
+		AllDay:      remote.AllDay,
+		Recurrence:  remote.Recurrence,
+		SeriesID:    remote.SeriesID,
+		Attendees:   remote.Attendees,
+		Alarms:      remote.Alarms,
+	}
+	return event, previousStart != "" && previousStart != startTimeUTC
+}
+
+// removeDeletedEvents deletes cached events of a calendar starting between
+// from and to that were not in the latest listing, returning how many were
+// removed
+func (s *Service) removeDeletedEvents(ctx context.Context, calendarID string, seen map[string]bool, from, to time.Time) int {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, remote_id
+		FROM calendar_events
+		WHERE backend = ? AND calendar_id = ? AND remote_id IS NOT NULL AND start_time >= ? AND start_time < ?
+	`, s.backend.Name(), calendarID, from.UTC().Format("2006-01-02 15:04:05"), to.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		fmt.Printf("Failed to check for deleted events: %v\n", err)
+		return 0
+	}
+
+	var gone []string
+	for rows.Next() {
+		var id, remoteID string
+		if err := rows.Scan(&id, &remoteID); err != nil {
+			continue
+		}
+		if !seen[remoteID] {
+			gone = append(gone, id)
+		}
+	}
+	rows.Close()
+
+	return s.removeCachedEvents(ctx, gone)
+}
+
+// removeRemoteEvent deletes the cached event of a calendar with the given
+// remote ID and, for a recurring event, its occurrences, whose remote IDs
+// extend the series ID after a "_" (Google) or "#" (CalDAV). It returns how
+// many were removed.
+func (s *Service) removeRemoteEvent(ctx context.Context, calendarID, remoteID string) int {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id
+		FROM calendar_events
+		WHERE backend = ? AND calendar_id = ? AND (remote_id = ? OR substr(remote_id, 1, ?) IN (? || '_', ? || '#'))
+	`, s.backend.Name(), calendarID, remoteID, len(remoteID)+1, remoteID, remoteID)
+	if err != nil {
+		fmt.Printf("Failed to look up deleted event %s: %v\n", remoteID, err)
+		return 0
+	}
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	rows.Close()
+
+	return s.removeCachedEvents(ctx, ids)
+}
+
+// removeCachedEvents deletes events deleted in the remote calendar and
+// reports each as a change, returning how many were removed
+func (s *Service) removeCachedEvents(ctx context.Context, ids []string) int {
+	removed := 0
+	for _, id := range ids {
+		event, err := s.GetEventByID(ctx, id)
+		if err != nil {
+			continue
+		}
+		if _, err := s.db.ExecContext(ctx, "DELETE FROM calendar_events WHERE id = ?", id); err != nil {
+			fmt.Printf("Failed to remove deleted event %s: %v\n", event.Title, err)
+			continue
+		}
+		fmt.Printf("Event deleted in %s calendar: %s\n", s.backend.Name(), event.Title)
+		s.notifyChange(event, true)
+		removed++
+	}
+	return removed
+}
+
+// pruneEventsBefore drops cached events of the current backend that ended
+// before the sync window. They still exist remotely, so this is not
+// reported as a change.
+func (s *Service) pruneEventsBefore(ctx context.Context, from time.Time) {
+	_, err := s.db.ExecContext(ctx, `
+		DELETE FROM calendar_events
+		WHERE backend = ? AND remote_id IS NOT NULL AND end_time < ?
+	`, s.backend.Name(), from.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		fmt.Printf("Failed to prune old calendar events: %v\n", err)
+	}
+}
+
+// GetAuthURL starts a Google authorization and returns the URL to send the
+// user to, or "" when the backend does not use Google sign-in
+func (s *Service) GetAuthURL() (string, error) {
+	g, ok := s.backend.(*GoogleBackend)
+	if !ok {
+		return "", nil
+	}
+	return g.AuthURL()
+}
+
+// ExchangeCode completes the Google authorization with the given state,
+// exchanging its code for tokens
+func (s *Service) ExchangeCode(ctx context.Context, state, code string) error {
+	g, ok := s.backend.(*GoogleBackend)
+	if !ok {
+		return fmt.Errorf("calendar backend %s does not use Google sign-in", s.backend.Name())
+	}
+	if err := g.Exchange(ctx, state, code); err != nil {
+		return err
+	}
+
+	// Trigger immediate sync now that we have valid credentials
+	go s.sync()
+
+	return nil
+}
+
+// Disconnect revokes PIKA's access to Google Calendar and forgets the
+// tokens. Cached events are kept until Google Calendar is connected again.
+func (s *Service) Disconnect(ctx context.Context) error {
+	g, ok := s.backend.(*GoogleBackend)
+	if !ok {
+		return fmt.Errorf("calendar backend %s does not use Google sign-in", s.backend.Name())
+	}
+	return g.Disconnect(ctx)
+}
+
+// AuthStatus reports whether the calendar backend is authorized and whether
+// Google Calendar has to be connected again
+func (s *Service) AuthStatus() *AuthStatus {
+	if g, ok := s.backend.(*GoogleBackend); ok {
+		return g.AuthStatus()
+	}
+	return &AuthStatus{Backend: s.backend.Name(), Connected: s.IsInitialized()}
+}
+
+// SetReauthCallback sets the function to call when Google rejects the saved
+// authorization, so Google Calendar has to be connected again
+func (s *Service) SetReauthCallback(cb func(err error)) {
+	if g, ok := s.backend.(*GoogleBackend); ok {
+		g.SetReauthCallback(cb)
+	}
+}
+
+// IsInitialized returns whether the calendar backend is configured and authorized
+func (s *Service) IsInitialized() bool {
+	return s.backend.Ready()
+}
+
+// ListEvents returns upcoming calendar events from local cache
+func (s *Service) ListEvents(ctx context.Context) ([]*Event, error) {
+	// Always use local cached events for speed
+	// Background sync keeps them up to date
+	return s.listLocalEvents(ctx)
+}
+
+// CreateOptions holds optional attributes for a new event
+type CreateOptions struct {
+	// Calendar is the ID or name of the calendar; empty for the default
+	Calendar string
+	// AllowConflicts books the event even when it overlaps other events
+	AllowConflicts bool
+	// Recurrence makes the event repeat, e.g. from ParseRecurrence
+	Recurrence []string
+	// Attendees are invited to the event
+	Attendees []*Attendee
+}
+
+// CreateEvent creates a new event in the default calendar
+func (s *Service) CreateEvent(ctx context.Context, title, description, startTime, endTime, location string) (*Event, error) {
+	return s.CreateEventWithOptions(ctx, title, description, startTime, endTime, location, CreateOptions{})
+}
+
+// CreateEventWithOptions creates a new calendar event with optional
+// attributes such as the calendar
+func (s *Service) CreateEventWithOptions(ctx context.Context, title, description, startTime, endTime, location string, opts CreateOptions) (*Event, error) {
+	cal, err := s.ResolveCalendar(ctx, opts.Calendar)
+	if err != nil {
+		return nil, err
+	}
+	if cal.ReadOnly {
+		return nil, fmt.Errorf("calendar %s is read-only", cal.Name)
+	}
+
+	start, err := time.Parse(time.RFC3339, startTime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start time: %w", err)
+	}
+
+	end, err := time.Parse(time.RFC3339, endTime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end time: %w", err)
+	}
+
+	event := &Event{
+		ID:          uuid.New().String(),
+		CalendarID:  cal.ID,
+		Calendar:    cal.Name,
+		Color:       cal.Color,
+		Title:       title,
+		Description: description,
+		StartTime:   start,
+		EndTime:     end,
+		Location:    location,
+		Recurrence:  opts.Recurrence,
+		Attendees:   opts.Attendees,
+		CreatedAt:   time.Now(),
+	}
+
+	if !opts.AllowConflicts {
+		if err := s.checkSeriesConflicts(ctx, event); err != nil {
+			return nil, err
+		}
+	}
+
+	// Save locally first
+	if err := s.saveLocalEvent(ctx, event); err != nil {
+		return nil, err
+	}
+
+	// If the backend is connected, also create there
+	if s.IsInitialized() {
+		remoteID, err := s.backend.Create(ctx, event)
+		if err != nil {
+			// Log but don't fail - local event is saved
+			fmt.Printf("Failed to create %s event: %v\n", s.backend.Name(), err)
+		} else if remoteID != "" {
+			event.RemoteID = remoteID
+			event.Backend = s.backend.Name()
+			s.setRemoteID(ctx, event.ID, remoteID)
+
+			// Trigger a sync to get the latest events
+			go s.sync()
+		}
+	}
+
+	return event, nil
+}
+
+// saveLocalEvent saves an event to the local database
+func (s *Service) saveLocalEvent(ctx context.Context, event *Event) error {
+	// Convert to UTC for consistent storage
+	startTimeUTC := event.StartTime.UTC().Format("2006-01-02 15:04:05")
+	endTimeUTC := event.EndTime.UTC().Format("2006-01-02 15:04:05")
+
+	query := `
+		INSERT INTO calendar_events (id, calendar_id, color, uid, title, description, start_time, end_time, location, all_day, recurrence, attendees, alarms, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, datetime('now'))
+	`
+	calendarID := event.CalendarID
+	if calendarID == "" {
+		calendarID = PrimaryCalendar
+	}
+	_, err := s.db.ExecContext(ctx, query,
+		event.ID, calendarID, nullString(event.Color), nullString(event.UID), event.Title, event.Description,
+		startTimeUTC, endTimeUTC, event.Location,
+		event.AllDay, formatRecurrence(event.Recurrence), formatAttendees(event.Attendees), formatAlarms(event.Alarms))
+	return err
+}
+
+// listLocalEvents returns the next 20 events from local database, including
+// ones in progress
+func (s *Service) listLocalEvents(ctx context.Context) ([]*Event, error) {
+	now := time.Now()
+
+	fmt.Printf("Querying local events...\n")
+	events, err := s.eventsBetween(ctx, now, now.AddDate(1, 0, 0))
+	if err != nil {
+		fmt.Printf("Error querying events: %v\n", err)
+		return nil, err
+	}
+	if len(events) > 20 {
+		events = events[:20]
+	}
+
+	fmt.Printf("listLocalEvents returning %d events\n", len(events))
+	return events, nil
+}
+
+// EventQuery selects events by time range, text and calendar
+type EventQuery struct {
+	From time.Time
+	To   time.Time
+	// Text is matched against the title, description and location
+	Text string
+	// Calendar is the ID or name of a calendar; empty for all calendars
+	Calendar string
+	// Limit caps the number of events; 0 for no limit
+	Limit int
+}
+
+// SearchEvents returns the events overlapping the query's range that match
+// its text and calendar, soonest first, with recurring events expanded
+// into their occurrences
+func (s *Service) SearchEvents(ctx context.Context, q EventQuery) ([]*Event, error) {
+	if !q.To.After(q.From) {
+		return nil, fmt.Errorf("the end of the range must be after its start")
+	}
+
+	var filter string
+	var args []interface{}
+	if text := strings.TrimSpace(q.Text); text != "" {
+		filter += " AND (title LIKE ? OR description LIKE ? OR location LIKE ?)"
+		pattern := "%" + text + "%"
+		args = append(args, pattern, pattern, pattern)
+	}
+	if q.Calendar != "" {
+		cal, err := s.ResolveCalendar(ctx, q.Calendar)
+		if err != nil {
+			return nil, err
+		}
+		filter += " AND calendar_id = ?"
+		args = append(args, cal.ID)
+	}
+
+	events, err := s.eventsMatching(ctx, q.From, q.To, filter, args...)
+	if err != nil {
+		return nil, err
+	}
+	if q.Limit > 0 && len(events) > q.Limit {
+		events = events[:q.Limit]
+	}
+	return events, nil
+}
+
+// eventsBetween returns the events overlapping from and to, soonest first,
+// with recurring events expanded into their occurrences
+func (s *Service) eventsBetween(ctx context.Context, from, to time.Time) ([]*Event, error) {
+	return s.eventsMatching(ctx, from, to, "")
+}
+
+// eventsMatching is eventsBetween for the events also matching filter, an
+// SQL condition starting with AND
+func (s *Service) eventsMatching(ctx context.Context, from, to time.Time, filter string, args ...interface{}) ([]*Event, error) {
+	fromUTC := from.UTC().Format("2006-01-02 15:04:05")
+	toUTC := to.UTC().Format("2006-01-02 15:04:05")
+
+	query := `
+		SELECT ` + eventColumns + `
+		FROM calendar_events
+		WHERE start_time < ? AND (
+			(COALESCE(recurrence, '') = '' AND (start_time >= ? OR end_time > ?))
+			OR COALESCE(recurrence, '') != ''
+		)` + filter + `
+		ORDER BY start_time ASC
+	`
+	rows, err := s.queryEvents(ctx, query, append([]interface{}{toUTC, fromUTC, fromUTC}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*Event
+	for _, e := range rows {
+		if len(e.Recurrence) == 0 {
+			events = append(events, e)
+			continue
+		}
+		occurrences, err := expandRecurrence(e, from, to)
+		if err != nil {
+			fmt.Printf("Skipping recurring event %s: %v\n", e.Title, err)
+			continue
+		}
+		events = append(events, occurrences...)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].StartTime.Before(events[j].StartTime) })
+	return events, nil
+}
+
+// setRemoteID records the backend ID of a local event
+func (s *Service) setRemoteID(ctx context.Context, id, remoteID string) error {
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE calendar_events SET remote_id = ?, backend = ?, updated_at = datetime('now') WHERE id = ?",
+		remoteID, s.backend.Name(), id)
+	return err
+}
+
+// onBackend reports whether an event exists in the current backend
+func (s *Service) onBackend(event *Event) bool {
+	return s.IsInitialized() && event.RemoteID != "" && event.Backend == s.backend.Name()
+}
+
+// UpdateOptions holds optional behaviour for an event update
+type UpdateOptions struct {
+	// AllowConflicts moves the event even when it then overlaps other events
+	AllowConflicts bool
+	// Scope is what an edit of a recurring event changes, ScopeOccurrence
+	// unless set. A new recurrence always applies to the series.
+	Scope string
+	// Occurrence is the start of the occurrence edited; zero for the next one
+	Occurrence time.Time
+	// Recurrence replaces the recurrence when not nil; empty ends it
+	Recurrence *[]string
+	// Attendees replaces the attendees when not nil
+	Attendees *[]*Attendee
+}
+
+// UpdateEvent updates an existing calendar event. Nil leaves a field
+// unchanged.
+func (s *Service) UpdateEvent(ctx context.Context, eventID string, title, description, startTime, endTime, location *string) (*Event, error) {
+	return s.UpdateEventWithOptions(ctx, eventID, title, description, startTime, endTime, location, UpdateOptions{})
+}
+
+// UpdateEventWithOptions updates an existing calendar event, checking that
+// a new time does not overlap other events unless allowed. For a recurring
+// event, the times given are those of the occurrence edited; when the whole
+// series changes, every occurrence moves by as much.
+func (s *Service) UpdateEventWithOptions(ctx context.Context, eventID string, title, description, startTime, endTime, location *string, opts UpdateOptions) (*Event, error) {
+	// First, get the existing event
+	event, err := s.GetEventByID(ctx, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("event not found: %w", err)
+	}
+
+	// A plain event is its own single occurrence
+	occurrence := event
+	if len(event.Recurrence) > 0 {
+		if occurrence, err = findOccurrence(event, opts.Occurrence); err != nil {
+			return nil, err
+		}
+	}
+	edited := *occurrence
+
+	// Update fields if provided
+	if title != nil {
+		edited.Title = *title
+	}
+	if description != nil {
+		edited.Description = *description
+	}
+	if startTime != nil {
+		start, err := time.Parse(time.RFC3339, *startTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start time: %w", err)
+		}
+		edited.StartTime = start
+	}
+	if endTime != nil {
+		end, err := time.Parse(time.RFC3339, *endTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end time: %w", err)
+		}
+		edited.EndTime = end
+	}
+	if location != nil {
+		edited.Location = *location
+	}
+	if opts.Recurrence != nil {
+		edited.Recurrence = *opts.Recurrence
+	}
+	if opts.Attendees != nil {
+		edited.Attendees = *opts.Attendees
+	}
+
+	// A series is checked occurrence by occurrence as it will be saved
+	series := opts.Scope == ScopeSeries || opts.Recurrence != nil
+	check := !opts.AllowConflicts && (startTime != nil || endTime != nil || opts.Recurrence != nil)
+	switch {
+	case event.SeriesID != "" && series:
+		return s.updateRemoteSeries(ctx, event, &edited, opts, check)
+	case len(event.Recurrence) > 0 && !series:
+		if check {
+			single := edited
+			single.Recurrence = nil
+			if err := s.checkConflicts(ctx, &single); err != nil {
+				return nil, err
+			}
+		}
+		return s.detachOccurrence(ctx, event, occurrence, &edited)
+	}
+
+	shift := edited.StartTime.Sub(occurrence.StartTime)
+	updated := edited
+	updated.Recurrence = shiftExceptions(edited.Recurrence, shift, edited.AllDay)
+	updated.StartTime = event.StartTime.Add(shift)
+	updated.EndTime = updated.StartTime.Add(edited.EndTime.Sub(edited.StartTime))
+	if check {
+		if err := s.checkSeriesConflicts(ctx, &updated); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.saveEventUpdate(ctx, event, &updated); err != nil {
+		return nil, err
+	}
+	return &edited, nil
+}
+
+// saveEventUpdate writes the new version of a cached event locally and, if
+// it lives there, to the connected backend
+func (s *Service) saveEventUpdate(ctx context.Context, previous, event *Event) error {
+	// Update locally - store times in UTC
+	startTimeUTC := event.StartTime.UTC().Format("2006-01-02 15:04:05")
+	endTimeUTC := event.EndTime.UTC().Format("2006-01-02 15:04:05")
+
+	query := `
+		UPDATE calendar_events
+		SET title = ?, description = ?, start_time = ?, end_time = ?, location = ?, recurrence = ?, attendees = ?, updated_at = datetime('now')
+		WHERE id = ?
+	`
+	_, err := s.db.ExecContext(ctx, query,
+		event.Title, event.Description, startTimeUTC, endTimeUTC, event.Location,
+		formatRecurrence(event.Recurrence), formatAttendees(event.Attendees), event.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update local event: %w", err)
+	}
+	if !event.StartTime.Equal(previous.StartTime) {
+		s.notifyChange(event, false)
+	}
+
+	// If the event lives in the connected backend, update there too
+	if s.onBackend(event) {
+		if err := s.backend.Update(ctx, event); err != nil {
+			fmt.Printf("Failed to update %s event: %v\n", s.backend.Name(), err)
+		} else {
+			go s.sync()
+		}
+	}
+	return nil
+}
+
+// DeleteEvent deletes a calendar event
+func (s *Service) DeleteEvent(ctx context.Context, eventID string) error {
+	// First, get the existing event to check for a remote ID
+	event, err := s.GetEventByID(ctx, eventID)
+	if err != nil {
+		return fmt.Errorf("event not found: %w", err)
+	}
+
+	// Delete locally
+	_, err = s.db.ExecContext(ctx, "DELETE FROM calendar_events WHERE id = ?", eventID)
+	if err != nil {
+		return fmt.Errorf("failed to delete local event: %w", err)
+	}
+	s.notifyChange(event, true)
+
+	// If the event lives in the connected backend, delete there too
+	if s.onBackend(event) {
+		if err := s.backend.Delete(ctx, event.CalendarID, event.RemoteID); err != nil {
+			fmt.Printf("Failed to delete %s event: %v\n", s.backend.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// GetEventByID retrieves an event by its ID
+func (s *Service) GetEventByID(ctx context.Context, eventID string) (*Event, error) {
+	query := `
+		SELECT ` + eventColumns + `
+		FROM calendar_events
+		WHERE id = ?
+	`
+
+	event, err := scanEvent(s.db.QueryRowContext(ctx, query, eventID))
+	if err != nil {
+		return nil, err
+	}
+	s.nameCalendars(ctx, []*Event{event})
+	return event, nil
+}
+
+// FindEventByTitle searches for events by title (partial match)
+func (s *Service) FindEventByTitle(ctx context.Context, titleSearch string) ([]*Event, error) {
+	query := `
+		SELECT ` + eventColumns + `
+		FROM calendar_events
+		WHERE title LIKE ?
+		ORDER BY end_time < ? AND COALESCE(recurrence, '') = '', start_time ASC
+		LIMIT 10
+	`
+
+	// Upcoming events come first, so an occurrence of a series listed
+	// occurrence by occurrence is the next one rather than the first
+	now := time.Now()
+	events, err := s.queryEvents(ctx, query, "%"+titleSearch+"%", now.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Recurring events are reported at their next occurrence
+	for i, e := range events {
+		if len(e.Recurrence) == 0 {
+			continue
+		}
+		if next, err := expandRecurrence(e, now, now.AddDate(1, 0, 0)); err == nil && len(next) > 0 {
+			events[i] = next[0]
+		}
+	}
+	return events, nil
+}
+
+// eventColumns is the column list scanned by scanEvent
+const eventColumns = "id, remote_id, backend, calendar_id, color, uid, title, description, start_time, end_time, location, all_day, recurrence, series_id, attendees, alarms, created_at"
+
+// scanner is satisfied by both *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanEvent reads an event selected with eventColumns
+func scanEvent(row scanner) (*Event, error) {
+	e := &Event{}
+	var remoteID, backend, calendarID, color, uid, description, location, recurrence, seriesID, attendees, alarms sql.NullString
+	var startTimeStr, endTimeStr string
+	var allDay sql.NullBool
+	var createdAtStr sql.NullString
+
+	if err := row.Scan(&e.ID, &remoteID, &backend, &calendarID, &color, &uid, &e.Title, &description, &startTimeStr, &endTimeStr,
+		&location, &allDay, &recurrence, &seriesID, &attendees, &alarms, &createdAtStr); err != nil {
+		return nil, err
+	}
+
+	// Parse times - supports both old and new formats
+	e.StartTime = parseTimeString(startTimeStr)
+	e.EndTime = parseTimeString(endTimeStr)
+	e.CreatedAt = parseTimeString(createdAtStr.String)
+
+	e.RemoteID = remoteID.String
+	e.Backend = backend.String
+	e.CalendarID = calendarID.String
+	e.Color = color.String
+	e.UID = uid.String
+	e.Description = description.String
+	e.Location = location.String
+	e.AllDay = allDay.Bool
+	if recurrence.String != "" {
+		e.Recurrence = strings.Split(recurrence.String, "\n")
+	}
+	e.SeriesID = seriesID.String
+	e.Attendees = parseAttendees(attendees.String)
+	if alarms.String != "" {
+		json.Unmarshal([]byte(alarms.String), &e.Alarms)
+	}
+
+	return e, nil
+}
+
+// formatRecurrence joins recurrence lines for storage
+func formatRecurrence(lines []string) interface{} {
+	if len(lines) == 0 {
+		return nil
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatAlarms encodes alarm offsets for storage
+func formatAlarms(alarms []int) interface{} {
+	if alarms == nil {
+		return nil
+	}
+	data, _ := json.Marshal(alarms)
+	return string(data)
+}
+
+// nullString stores empty strings as NULL
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// queryEvents runs a query selecting eventColumns
+func (s *Service) queryEvents(ctx context.Context, query string, args ...interface{}) ([]*Event, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*Event
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	s.nameCalendars(ctx, events)
+	return events, nil
+}
+
+// parseTimeString parses a time string in various formats
+func parseTimeString(s string) time.Time {
+	// Try UTC format first (new format)
+	if t, err := time.Parse("2006-01-02 15:04:05", s); err == nil {
+		return t.UTC()
+	}
+	// Try RFC3339 with timezone (old format from Go's time.Time)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC()
+	}
+	// Try other common formats
+	for _, format := range []string{"2006-01-02T15:04:05Z07:00", "2006-01-02 15:04:05-07:00"} {
+		if t, err := time.Parse(format, s); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
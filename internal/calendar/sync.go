@@ -0,0 +1,191 @@
+package calendar
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Default sync window, in days before and after now
+const (
+	defaultSyncPastDays   = 30
+	defaultSyncFutureDays = 180
+)
+
+// syncTimeout bounds a sync, which may page through many events
+const syncTimeout = 2 * time.Minute
+
+// syncWindowSlack is how far the window may move on before the next sync
+// lists it in full again. Incremental syncs only report changes, so events
+// that were never changed are picked up as they come into the window by
+// these full syncs.
+const syncWindowSlack = 24 * time.Hour
+
+// SyncStatus reports the state of calendar sync
+type SyncStatus struct {
+	Backend      string                `json:"backend"`
+	Connected    bool                  `json:"connected"`
+	Running      bool                  `json:"running"`
+	Calendars    []*CalendarSyncStatus `json:"calendars"`
+	LastSync     *time.Time            `json:"last_sync,omitempty"`
+	LastFullSync *time.Time            `json:"last_full_sync,omitempty"`
+	// Updated and Deleted count the events changed by the latest sync in
+	// this session
+	Updated     int        `json:"updated"`
+	Deleted     int        `json:"deleted"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+// CalendarSyncStatus reports the sync state of a selected calendar
+type CalendarSyncStatus struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Incremental is true when the next sync fetches only changes
+	Incremental bool       `json:"incremental"`
+	WindowStart *time.Time `json:"window_start,omitempty"`
+	WindowEnd   *time.Time `json:"window_end,omitempty"`
+}
+
+// syncCounts are the changes applied by a sync
+type syncCounts struct {
+	Updated int
+	Deleted int
+}
+
+// syncState is the stored sync status of a backend
+type syncState struct {
+	lastSync     time.Time
+	lastFullSync time.Time
+	lastError    string
+	lastErrorAt  time.Time
+}
+
+// loadSyncState reads the sync status of the current backend
+func (s *Service) loadSyncState(ctx context.Context) (*syncState, error) {
+	var lastSync, lastFullSync, lastError, lastErrorAt sql.NullString
+	err := s.db.QueryRowContext(ctx, `
+		SELECT last_sync_at, last_full_sync_at, last_error, last_error_at
+		FROM calendar_sync_state
+		WHERE backend = ?
+	`, s.backend.Name()).Scan(&lastSync, &lastFullSync, &lastError, &lastErrorAt)
+	if err == sql.ErrNoRows {
+		return &syncState{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &syncState{
+		lastSync:     parseTimeString(lastSync.String),
+		lastFullSync: parseTimeString(lastFullSync.String),
+		lastError:    lastError.String,
+		lastErrorAt:  parseTimeString(lastErrorAt.String),
+	}, nil
+}
+
+// saveSyncState records a successful sync, clearing any earlier error.
+// full is true when any calendar was listed in full.
+func (s *Service) saveSyncState(ctx context.Context, now time.Time, full bool) error {
+	var lastFullSync interface{}
+	if full {
+		lastFullSync = formatSyncTime(now)
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO calendar_sync_state (backend, last_sync_at, last_full_sync_at, updated_at)
+		VALUES (?, ?, ?, datetime('now'))
+		ON CONFLICT(backend) DO UPDATE SET
+			last_sync_at = excluded.last_sync_at,
+			last_full_sync_at = COALESCE(excluded.last_full_sync_at, last_full_sync_at),
+			last_error = NULL,
+			last_error_at = NULL,
+			updated_at = datetime('now')
+	`, s.backend.Name(), formatSyncTime(now), lastFullSync)
+	return err
+}
+
+// recordSyncError records a failed sync, keeping the sync token for the
+// next attempt
+func (s *Service) recordSyncError(syncErr error) {
+	// Not the sync's context, which is done when the sync timed out
+	_, err := s.db.Exec(`
+		INSERT INTO calendar_sync_state (backend, last_error, last_error_at, updated_at)
+		VALUES (?, ?, ?, datetime('now'))
+		ON CONFLICT(backend) DO UPDATE SET
+			last_error = excluded.last_error,
+			last_error_at = excluded.last_error_at,
+			updated_at = datetime('now')
+	`, s.backend.Name(), syncErr.Error(), formatSyncTime(time.Now()))
+	if err != nil {
+		fmt.Printf("Failed to record calendar sync error: %v\n", err)
+	}
+}
+
+// setRunning marks whether a sync is in progress
+func (s *Service) setRunning(running bool) {
+	s.stateMu.Lock()
+	s.running = running
+	s.stateMu.Unlock()
+}
+
+// SyncStatus reports when the calendar was last synced and the last error
+func (s *Service) SyncStatus(ctx context.Context) (*SyncStatus, error) {
+	state, err := s.loadSyncState(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &SyncStatus{
+		Backend:      s.backend.Name(),
+		Connected:    s.IsInitialized(),
+		Calendars:    []*CalendarSyncStatus{},
+		LastSync:     timePtr(state.lastSync),
+		LastFullSync: timePtr(state.lastFullSync),
+		LastError:    state.lastError,
+		LastErrorAt:  timePtr(state.lastErrorAt),
+	}
+
+	calendars, err := s.storedCalendars(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, cal := range calendars {
+		if !cal.Selected {
+			continue
+		}
+		cs, err := s.loadCalendarSync(ctx, cal.ID)
+		if err != nil {
+			return nil, err
+		}
+		status.Calendars = append(status.Calendars, &CalendarSyncStatus{
+			ID:          cal.ID,
+			Name:        cal.Name,
+			Incremental: cs.token != "" && cs.covers(now.Add(-s.syncPast), now.Add(s.syncFuture)),
+			WindowStart: timePtr(cs.windowStart),
+			WindowEnd:   timePtr(cs.windowEnd),
+		})
+	}
+
+	s.stateMu.Lock()
+	status.Running = s.running
+	status.Updated = s.last.Updated
+	status.Deleted = s.last.Deleted
+	s.stateMu.Unlock()
+	return status, nil
+}
+
+// formatSyncTime formats a time for calendar_sync_state
+func formatSyncTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+// timePtr returns nil for the zero time
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
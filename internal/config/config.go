@@ -24,18 +24,62 @@ type Config struct {
 	RequestyBaseURL string
 	RequestyModel   string
 
+	// Calendar
+	CalendarBackend string // auto, local, google or caldav
+
+	// CalendarSyncPastDays and CalendarSyncFutureDays are the window of
+	// events cached from the calendar backend, in days before and after now
+	CalendarSyncPastDays   int
+	CalendarSyncFutureDays int
+
+	// CalendarWorkHours are the hours searched for free time, e.g.
+	// "09:00-17:00", and CalendarBufferMinutes the time kept free around
+	// other events
+	CalendarWorkHours     string
+	CalendarBufferMinutes int
+
+	// CalendarAlertMinutes are the minutes before a timed event that it is
+	// announced when neither it nor its calendar has reminders, e.g. "15,5",
+	// and CalendarAllDayAlert the time all-day events are announced on
+	// their day, e.g. "08:00", or "off"
+	CalendarAlertMinutes string
+	CalendarAllDayAlert  string
+
 	// Google Calendar
 	GoogleClientID     string
 	GoogleClientSecret string
 	GoogleRedirectURL  string
 
+	// CalDAV (Fastmail, Nextcloud, Radicale, ...)
+	CalDAVURL      string // URL of the calendar collection
+	CalDAVUsername string
+	CalDAVPassword string
+
 	// Memory
 	MemoryContextLimit int
 	MemoryTopK         int
 
+	// MemoryExtractionIdle is how long a conversation must be idle before it
+	// is reviewed for memories, in minutes. 0 disables background extraction.
+	MemoryExtractionIdle int
+
 	// Ollama (local embeddings)
 	OllamaURL        string
 	OllamaEmbedModel string
+
+	// Embeddings
+	EmbeddingProvider   string // auto, ollama, openai or hash
+	EmbeddingBaseURL    string // OpenAI-compatible endpoint for the openai provider
+	EmbeddingAPIKey     string
+	EmbeddingModel      string // model for the openai provider
+	EmbeddingDimensions int    // vector size for the hash provider
+
+	// EmbeddingCacheSize is the number of recent embeddings kept in memory
+	EmbeddingCacheSize int
+
+	// ReminderOffsets is the default notification schedule for reminders,
+	// e.g. "24h,12h,3h,1h,10m,0"
+	ReminderOffsets string
 }
 
 func Load() *Config {
@@ -47,20 +91,38 @@ func Load() *Config {
 	dbConfig := loadFromDatabase(dbPath)
 
 	return &Config{
-		Port:               port,
-		Env:                getEnvOrDB("ENV", "development", dbConfig),
-		DataDir:            dataDir,
-		DatabasePath:       dbPath,
-		RequestyAPIKey:     getEnvOrDB("REQUESTY_API_KEY", "", dbConfig),
-		RequestyBaseURL:    getEnvOrDB("REQUESTY_BASE_URL", "https://router.requesty.ai/v1", dbConfig),
-		RequestyModel:      getEnvOrDB("REQUESTY_MODEL", "google/gemini-2.0-flash-001", dbConfig),
-		GoogleClientID:     getEnvOrDB("GOOGLE_CLIENT_ID", "", dbConfig),
-		GoogleClientSecret: getEnvOrDB("GOOGLE_CLIENT_SECRET", "", dbConfig),
-		GoogleRedirectURL:  getEnvOrDB("GOOGLE_REDIRECT_URL", "http://localhost:"+port+"/auth/google/callback", dbConfig),
-		MemoryContextLimit: getEnvIntOrDB("MEMORY_CONTEXT_LIMIT", 2000, dbConfig),
-		MemoryTopK:         getEnvIntOrDB("MEMORY_TOP_K", 10, dbConfig),
-		OllamaURL:          getEnvOrDB("OLLAMA_URL", "http://localhost:11434", dbConfig),
-		OllamaEmbedModel:   getEnvOrDB("OLLAMA_EMBED_MODEL", "nomic-embed-text", dbConfig),
+		Port:                   port,
+		Env:                    getEnvOrDB("ENV", "development", dbConfig),
+		DataDir:                dataDir,
+		DatabasePath:           dbPath,
+		RequestyAPIKey:         getEnvOrDB("REQUESTY_API_KEY", "", dbConfig),
+		RequestyBaseURL:        getEnvOrDB("REQUESTY_BASE_URL", "https://router.requesty.ai/v1", dbConfig),
+		RequestyModel:          getEnvOrDB("REQUESTY_MODEL", "google/gemini-2.0-flash-001", dbConfig),
+		CalendarBackend:        getEnvOrDB("CALENDAR_BACKEND", "auto", dbConfig),
+		CalendarSyncPastDays:   getEnvIntOrDB("CALENDAR_SYNC_PAST_DAYS", 30, dbConfig),
+		CalendarSyncFutureDays: getEnvIntOrDB("CALENDAR_SYNC_FUTURE_DAYS", 180, dbConfig),
+		CalendarWorkHours:      getEnvOrDB("CALENDAR_WORK_HOURS", "09:00-17:00", dbConfig),
+		CalendarBufferMinutes:  getEnvIntOrDB("CALENDAR_BUFFER_MINUTES", 0, dbConfig),
+		CalendarAlertMinutes:   getEnvOrDB("CALENDAR_ALERT_MINUTES", "15,5", dbConfig),
+		CalendarAllDayAlert:    getEnvOrDB("CALENDAR_ALL_DAY_ALERT", "08:00", dbConfig),
+		GoogleClientID:         getEnvOrDB("GOOGLE_CLIENT_ID", "", dbConfig),
+		GoogleClientSecret:     getEnvOrDB("GOOGLE_CLIENT_SECRET", "", dbConfig),
+		GoogleRedirectURL:      getEnvOrDB("GOOGLE_REDIRECT_URL", "http://localhost:"+port+"/auth/google/callback", dbConfig),
+		CalDAVURL:              getEnvOrDB("CALDAV_URL", "", dbConfig),
+		CalDAVUsername:         getEnvOrDB("CALDAV_USERNAME", "", dbConfig),
+		CalDAVPassword:         getEnvOrDB("CALDAV_PASSWORD", "", dbConfig),
+		MemoryContextLimit:     getEnvIntOrDB("MEMORY_CONTEXT_LIMIT", 2000, dbConfig),
+		MemoryTopK:             getEnvIntOrDB("MEMORY_TOP_K", 10, dbConfig),
+		MemoryExtractionIdle:   getEnvIntOrDB("MEMORY_EXTRACTION_IDLE", 5, dbConfig),
+		OllamaURL:              getEnvOrDB("OLLAMA_URL", "http://localhost:11434", dbConfig),
+		OllamaEmbedModel:       getEnvOrDB("OLLAMA_EMBED_MODEL", "nomic-embed-text", dbConfig),
+		EmbeddingProvider:      getEnvOrDB("EMBEDDING_PROVIDER", "auto", dbConfig),
+		EmbeddingBaseURL:       getEnvOrDB("EMBEDDING_BASE_URL", "https://api.openai.com/v1", dbConfig),
+		EmbeddingAPIKey:        getEnvOrDB("EMBEDDING_API_KEY", "", dbConfig),
+		EmbeddingModel:         getEnvOrDB("EMBEDDING_MODEL", "text-embedding-3-small", dbConfig),
+		EmbeddingDimensions:    getEnvIntOrDB("EMBEDDING_DIMENSIONS", 512, dbConfig),
+		EmbeddingCacheSize:     getEnvIntOrDB("EMBEDDING_CACHE_SIZE", 512, dbConfig),
+		ReminderOffsets:        getEnvOrDB("REMINDER_OFFSETS", "24h,12h,3h,1h,10m,0", dbConfig),
 	}
 }
 
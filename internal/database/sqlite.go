@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -95,7 +96,8 @@ func (d *SQLiteDriver) Initialize(ctx context.Context) error {
 	-- Calendar events table
 	CREATE TABLE IF NOT EXISTS calendar_events (
 		id TEXT PRIMARY KEY,
-		google_event_id TEXT UNIQUE,
+		remote_id TEXT,
+		backend TEXT,
 		title TEXT NOT NULL,
 		description TEXT,
 		start_time TEXT NOT NULL,
@@ -106,7 +108,46 @@ func (d *SQLiteDriver) Initialize(ctx context.Context) error {
 	);
 
 	CREATE INDEX IF NOT EXISTS idx_calendar_events_start ON calendar_events(start_time);
-	CREATE INDEX IF NOT EXISTS idx_calendar_events_google_id ON calendar_events(google_event_id);
+
+	-- Calendars of the backend account and their sync progress. sync_token
+	-- fetches only later changes; window_start and window_end bound the last
+	-- full sync
+	CREATE TABLE IF NOT EXISTS calendars (
+		backend TEXT NOT NULL,
+		id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		color TEXT,
+		is_primary INTEGER DEFAULT 0,
+		selected INTEGER DEFAULT 1,
+		is_default INTEGER DEFAULT 0,
+		read_only INTEGER DEFAULT 0,
+		sync_token TEXT,
+		window_start TEXT,
+		window_end TEXT,
+		updated_at TEXT DEFAULT (datetime('now')),
+		PRIMARY KEY (backend, id)
+	);
+
+	-- Sync status per calendar backend
+	CREATE TABLE IF NOT EXISTS calendar_sync_state (
+		backend TEXT PRIMARY KEY,
+		last_sync_at TEXT,
+		last_full_sync_at TEXT,
+		last_error TEXT,
+		last_error_at TEXT,
+		updated_at TEXT DEFAULT (datetime('now'))
+	);
+
+	-- Event alerts already announced, per occurrence, so that they are not
+	-- repeated after a restart. minutes_before is negative for the
+	-- morning-of announcement of an all-day event.
+	CREATE TABLE IF NOT EXISTS calendar_event_alerts (
+		event_id TEXT NOT NULL,
+		occurrence_start TEXT NOT NULL,
+		minutes_before INTEGER NOT NULL,
+		delivered_at TEXT DEFAULT (datetime('now')),
+		PRIMARY KEY (event_id, occurrence_start, minutes_before)
+	);
 
 	-- Conversations table
 	CREATE TABLE IF NOT EXISTS conversations (
@@ -146,12 +187,6 @@ func (d *SQLiteDriver) Initialize(ctx context.Context) error {
 		title TEXT NOT NULL,
 		description TEXT,
 		remind_at TEXT NOT NULL,
-		notified_24h INTEGER DEFAULT 0,
-		notified_12h INTEGER DEFAULT 0,
-		notified_3h INTEGER DEFAULT 0,
-		notified_1h INTEGER DEFAULT 0,
-		notified_10m INTEGER DEFAULT 0,
-		notified_at_time INTEGER DEFAULT 0,
 		completed INTEGER DEFAULT 0,
 		created_at TEXT DEFAULT (datetime('now')),
 		updated_at TEXT DEFAULT (datetime('now'))
@@ -159,6 +194,63 @@ func (d *SQLiteDriver) Initialize(ctx context.Context) error {
 
 	CREATE INDEX IF NOT EXISTS idx_reminders_remind_at ON reminders(remind_at);
 	CREATE INDEX IF NOT EXISTS idx_reminders_completed ON reminders(completed);
+
+	-- Notifications sent before each reminder, offset_seconds before remind_at.
+	-- status is pending, sent, or skipped (already past when scheduled)
+	CREATE TABLE IF NOT EXISTS reminder_notifications (
+		reminder_id TEXT NOT NULL REFERENCES reminders(id) ON DELETE CASCADE,
+		offset_seconds INTEGER NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		sent_at TEXT,
+		PRIMARY KEY (reminder_id, offset_seconds)
+	);
+
+	-- Snooze history of reminders
+	CREATE TABLE IF NOT EXISTS reminder_snoozes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		reminder_id TEXT NOT NULL REFERENCES reminders(id) ON DELETE CASCADE,
+		snoozed_at TEXT NOT NULL,
+		snoozed_until TEXT NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_reminder_snoozes_reminder ON reminder_snoozes(reminder_id);
+
+	-- Knowledge graph entities extracted from memories
+	CREATE TABLE IF NOT EXISTS entities (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		normalized_name TEXT NOT NULL,
+		entity_type TEXT NOT NULL DEFAULT 'other',
+		created_at TEXT DEFAULT (datetime('now')),
+		updated_at TEXT DEFAULT (datetime('now')),
+		UNIQUE(normalized_name, entity_type)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_entities_normalized_name ON entities(normalized_name);
+
+	-- Relations between entities (or an entity and a literal value)
+	CREATE TABLE IF NOT EXISTS entity_relations (
+		id TEXT PRIMARY KEY,
+		subject_id TEXT NOT NULL REFERENCES entities(id) ON DELETE CASCADE,
+		predicate TEXT NOT NULL,
+		object_id TEXT REFERENCES entities(id) ON DELETE CASCADE,
+		object_value TEXT,
+		memory_id TEXT NOT NULL REFERENCES memories(id) ON DELETE CASCADE,
+		created_at TEXT DEFAULT (datetime('now'))
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_entity_relations_subject ON entity_relations(subject_id);
+	CREATE INDEX IF NOT EXISTS idx_entity_relations_object ON entity_relations(object_id);
+	CREATE INDEX IF NOT EXISTS idx_entity_relations_memory ON entity_relations(memory_id);
+
+	-- Which memories mention which entities
+	CREATE TABLE IF NOT EXISTS entity_mentions (
+		entity_id TEXT NOT NULL REFERENCES entities(id) ON DELETE CASCADE,
+		memory_id TEXT NOT NULL REFERENCES memories(id) ON DELETE CASCADE,
+		PRIMARY KEY (entity_id, memory_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_entity_mentions_memory ON entity_mentions(memory_id);
 	`
 
 	_, err := d.db.ExecContext(ctx, schema)
@@ -166,9 +258,195 @@ func (d *SQLiteDriver) Initialize(ctx context.Context) error {
 		return fmt.Errorf("failed to initialize schema: %w", err)
 	}
 
+	if err := d.migrate(ctx); err != nil {
+		return fmt.Errorf("failed to migrate schema: %w", err)
+	}
+
 	return nil
 }
 
+// columnMigration describes a column added to an existing table after its
+// initial release. CREATE TABLE IF NOT EXISTS leaves older databases untouched,
+// so these are applied with ALTER TABLE when the column is missing.
+type columnMigration struct {
+	table      string
+	column     string
+	definition string
+}
+
+// columnMigrations lists columns added since the original schema
+var columnMigrations = []columnMigration{
+	{"memories", "embedding_model", "TEXT"},
+	{"memories", "embedding_dim", "INTEGER"},
+	{"memories", "expires_at", "TEXT"},
+	{"memories", "source_origin", "TEXT"},
+	{"memories", "source_conversation_id", "TEXT"},
+	{"memories", "source_request_id", "TEXT"},
+	{"memories", "source_utterance", "TEXT"},
+	{"memories", "source_model", "TEXT"},
+	{"reminders", "recurrence", "TEXT"},
+	{"reminders", "occurrence", "INTEGER DEFAULT 1"},
+	{"reminders", "nag_minutes", "INTEGER DEFAULT 0"},
+	{"reminders", "snoozed_until", "TEXT"},
+	{"reminders", "acknowledged_at", "TEXT"},
+	{"reminders", "last_announced_at", "TEXT"},
+	{"reminders", "priority", "TEXT DEFAULT 'normal'"},
+	{"reminders", "list", "TEXT"},
+	{"reminders", "tags", "TEXT"},
+	{"reminders", "event_id", "TEXT"},
+	{"reminders", "event_offset_seconds", "INTEGER"},
+	{"reminders", "event_deleted", "INTEGER DEFAULT 0"},
+	{"calendar_events", "remote_id", "TEXT"},
+	{"calendar_events", "backend", "TEXT"},
+	{"calendar_events", "uid", "TEXT"},
+	{"calendar_events", "all_day", "INTEGER DEFAULT 0"},
+	{"calendar_events", "recurrence", "TEXT"},
+	{"calendar_events", "alarms", "TEXT"},
+	// Events cached before calendars were tracked are all in the primary one
+	{"calendar_events", "calendar_id", "TEXT DEFAULT 'primary'"},
+	{"calendar_events", "color", "TEXT"},
+	{"calendar_events", "attendees", "TEXT"},
+	// Remote ID of the recurring series an occurrence listed on its own belongs to
+	{"calendar_events", "series_id", "TEXT"},
+	{"calendars", "default_alerts", "TEXT"},
+}
+
+// migrate brings an existing database up to date with the current schema
+func (d *SQLiteDriver) migrate(ctx context.Context) error {
+	for _, m := range columnMigrations {
+		exists, err := d.hasColumn(ctx, m.table, m.column)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", m.table, m.column, m.definition)
+		if _, err := d.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to add %s.%s: %w", m.table, m.column, err)
+		}
+	}
+
+	if err := d.migrateReminderTiers(ctx); err != nil {
+		return err
+	}
+	if err := d.migrateGoogleEventIDs(ctx); err != nil {
+		return err
+	}
+
+	// Indexes on migrated columns can only be created once the columns exist
+	_, err := d.db.ExecContext(ctx, `
+		CREATE INDEX IF NOT EXISTS idx_memories_embedding_model ON memories(embedding_model);
+		CREATE INDEX IF NOT EXISTS idx_memories_expires_at ON memories(expires_at);
+		CREATE INDEX IF NOT EXISTS idx_memories_source_conversation ON memories(source_conversation_id);
+		CREATE INDEX IF NOT EXISTS idx_reminders_list ON reminders(list);
+		CREATE INDEX IF NOT EXISTS idx_reminders_event ON reminders(event_id);
+		DROP INDEX IF EXISTS idx_calendar_events_remote;
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_calendar_events_remote_calendar ON calendar_events(backend, calendar_id, remote_id);
+		CREATE INDEX IF NOT EXISTS idx_calendar_events_uid ON calendar_events(uid);
+		CREATE INDEX IF NOT EXISTS idx_calendar_events_calendar_start ON calendar_events(calendar_id, start_time);
+		CREATE INDEX IF NOT EXISTS idx_calendar_events_series ON calendar_events(series_id);
+	`)
+	return err
+}
+
+// legacyReminderTiers maps the fixed notified_* reminder columns to their offsets
+var legacyReminderTiers = []struct {
+	column  string
+	seconds int
+}{
+	{"notified_24h", 24 * 60 * 60},
+	{"notified_12h", 12 * 60 * 60},
+	{"notified_3h", 3 * 60 * 60},
+	{"notified_1h", 60 * 60},
+	{"notified_10m", 10 * 60},
+	{"notified_at_time", 0},
+}
+
+// migrateReminderTiers moves the fixed notified_* flags of existing reminders
+// into reminder_notifications and drops the old columns
+func (d *SQLiteDriver) migrateReminderTiers(ctx context.Context) error {
+	exists, err := d.hasColumn(ctx, "reminders", "notified_at_time")
+	if err != nil || !exists {
+		return err
+	}
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Unsent tiers whose time has already passed are skipped, as they would
+	// be for a new reminder; the at-time notification is always kept
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, tier := range legacyReminderTiers {
+		stmt := fmt.Sprintf(`
+			INSERT OR IGNORE INTO reminder_notifications (reminder_id, offset_seconds, status)
+			SELECT id, %[1]d, CASE
+				WHEN %[2]s = 1 THEN 'sent'
+				WHEN %[1]d > 0 AND julianday(remind_at, '-%[1]d seconds') <= julianday(?) THEN 'skipped'
+				ELSE 'pending'
+			END FROM reminders
+		`, tier.seconds, tier.column)
+		if _, err := tx.ExecContext(ctx, stmt, now); err != nil {
+			return fmt.Errorf("failed to migrate %s: %w", tier.column, err)
+		}
+	}
+	for _, tier := range legacyReminderTiers {
+		if _, err := tx.ExecContext(ctx, "ALTER TABLE reminders DROP COLUMN "+tier.column); err != nil {
+			return fmt.Errorf("failed to drop reminders.%s: %w", tier.column, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// migrateGoogleEventIDs copies the google_event_id of events cached before
+// calendar backends existed into remote_id. SQLite cannot drop a UNIQUE
+// column, so the old column stays behind unused.
+func (d *SQLiteDriver) migrateGoogleEventIDs(ctx context.Context) error {
+	exists, err := d.hasColumn(ctx, "calendar_events", "google_event_id")
+	if err != nil || !exists {
+		return err
+	}
+
+	_, err = d.db.ExecContext(ctx, `
+		UPDATE calendar_events SET remote_id = google_event_id, backend = 'google'
+		WHERE google_event_id IS NOT NULL AND remote_id IS NULL
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to migrate google_event_id: %w", err)
+	}
+	return nil
+}
+
+// hasColumn reports whether a table already has the given column
+func (d *SQLiteDriver) hasColumn(ctx context.Context, table, column string) (bool, error) {
+	rows, err := d.db.QueryContext(ctx, fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
 // GetAppDataDir returns the application data directory for macOS
 func GetAppDataDir() (string, error) {
 	home, err := os.UserHomeDir()
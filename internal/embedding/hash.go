@@ -0,0 +1,102 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// HashProvider is a pure-Go embedder that needs no model server.
+// Each text is split into words and character trigrams which are hashed into a
+// fixed number of buckets (the "hashing trick"), then L2-normalised. The
+// vectors capture lexical rather than semantic similarity, which is enough to
+// keep memory search working offline and in tests. Output is deterministic.
+type HashProvider struct {
+	dims int
+}
+
+// defaultHashDimensions is used when no dimension is configured
+const defaultHashDimensions = 512
+
+// NewHashProvider creates a hash embedder producing vectors of length dims
+func NewHashProvider(dims int) *HashProvider {
+	if dims <= 0 {
+		dims = defaultHashDimensions
+	}
+	return &HashProvider{dims: dims}
+}
+
+// EmbeddingModel identifies the hashing scheme and dimension, so a change to
+// either is treated like a model change by the memory store
+func (p *HashProvider) EmbeddingModel() string {
+	return fmt.Sprintf("pika-hash-ngram-v1-%d", p.dims)
+}
+
+// GenerateEmbedding embeds a single text
+func (p *HashProvider) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return p.embed(text), nil
+}
+
+// GenerateEmbeddings embeds several texts
+func (p *HashProvider) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	results := make([][]float32, len(texts))
+	for i, text := range texts {
+		results[i] = p.embed(text)
+	}
+	return results, nil
+}
+
+// embed computes the hashed n-gram vector for text
+func (p *HashProvider) embed(text string) []float32 {
+	vec := make([]float32, p.dims)
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	for _, word := range words {
+		// Whole words carry the most signal
+		p.add(vec, "w:"+word, 1.0)
+
+		// Character trigrams make the vector tolerant to plurals and typos
+		runes := []rune("^" + word + "$")
+		for i := 0; i+3 <= len(runes); i++ {
+			p.add(vec, "c:"+string(runes[i:i+3]), 0.5)
+		}
+	}
+
+	// Word bigrams keep a little word order ("new york" vs "york new")
+	for i := 0; i+1 < len(words); i++ {
+		p.add(vec, "b:"+words[i]+" "+words[i+1], 0.75)
+	}
+
+	var norm float64
+	for _, v := range vec {
+		norm += float64(v) * float64(v)
+	}
+	if norm == 0 {
+		return vec
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	for i := range vec {
+		vec[i] *= scale
+	}
+	return vec
+}
+
+// add hashes a feature into a bucket. A second hash bit chooses the sign so
+// collisions tend to cancel out instead of accumulating.
+func (p *HashProvider) add(vec []float32, feature string, weight float32) {
+	h := fnv.New64a()
+	h.Write([]byte(feature))
+	sum := h.Sum64()
+
+	bucket := int(sum % uint64(p.dims))
+	if (sum>>63)&1 == 1 {
+		weight = -weight
+	}
+	vec[bucket] += weight
+}
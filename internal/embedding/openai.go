@@ -0,0 +1,65 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// OpenAIProvider generates embeddings with any OpenAI-compatible
+// /embeddings endpoint (OpenAI, Requesty, LM Studio, vLLM, ...).
+type OpenAIProvider struct {
+	client *openai.Client
+	model  string
+}
+
+// NewOpenAIProvider creates a provider for the endpoint at baseURL
+func NewOpenAIProvider(baseURL, apiKey, model string) *OpenAIProvider {
+	clientConfig := openai.DefaultConfig(apiKey)
+	if baseURL != "" {
+		clientConfig.BaseURL = baseURL
+	}
+
+	return &OpenAIProvider{
+		client: openai.NewClientWithConfig(clientConfig),
+		model:  model,
+	}
+}
+
+// EmbeddingModel returns the remote model name
+func (p *OpenAIProvider) EmbeddingModel() string {
+	return p.model
+}
+
+// GenerateEmbedding embeds a single text
+func (p *OpenAIProvider) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return single(ctx, p, text)
+}
+
+// GenerateEmbeddings embeds several texts with one request
+func (p *OpenAIProvider) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	req := openai.EmbeddingRequest{
+		Model: openai.EmbeddingModel(p.model),
+		Input: texts,
+	}
+
+	resp, err := p.client.CreateEmbeddings(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("embedding request failed: %w", err)
+	}
+
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("embedding model returned %d embeddings for %d inputs", len(resp.Data), len(texts))
+	}
+
+	results := make([][]float32, len(texts))
+	for _, d := range resp.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding model returned out of range index %d", d.Index)
+		}
+		results[d.Index] = d.Embedding
+	}
+
+	return results, nil
+}
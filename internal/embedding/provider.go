@@ -0,0 +1,71 @@
+package embedding
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/baswilson/pika/internal/config"
+)
+
+// Provider generates vector embeddings for text.
+// It has the same method set as memory.EmbeddingGenerator so any provider can
+// be handed to the memory store directly.
+type Provider interface {
+	GenerateEmbedding(ctx context.Context, text string) ([]float32, error)
+	GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error)
+	EmbeddingModel() string
+}
+
+// Provider names accepted in EMBEDDING_PROVIDER
+const (
+	ProviderAuto   = "auto"
+	ProviderOllama = "ollama"
+	ProviderOpenAI = "openai"
+	ProviderHash   = "hash"
+)
+
+// New creates the embedding provider selected in the config.
+// With "auto" (the default) Ollama is used when it is reachable and the
+// built-in hash embedder otherwise, so memory search always works offline.
+// Memories are tagged with the model that embedded them and only compared
+// within one model, so the two never mix; once Ollama is back, cmd/backfill
+// re-embeds what was saved offline.
+func New(cfg *config.Config) Provider {
+	switch strings.ToLower(cfg.EmbeddingProvider) {
+	case ProviderOllama:
+		return NewOllamaProvider(cfg.OllamaURL, cfg.OllamaEmbedModel)
+	case ProviderOpenAI:
+		return NewOpenAIProvider(cfg.EmbeddingBaseURL, cfg.EmbeddingAPIKey, cfg.EmbeddingModel)
+	case ProviderHash:
+		return NewHashProvider(cfg.EmbeddingDimensions)
+	}
+
+	if ollamaReachable(cfg.OllamaURL) {
+		return NewOllamaProvider(cfg.OllamaURL, cfg.OllamaEmbedModel)
+	}
+	log.Printf("Ollama not reachable at %s, using built-in hash embeddings", cfg.OllamaURL)
+	return NewHashProvider(cfg.EmbeddingDimensions)
+}
+
+// ollamaReachable checks whether an Ollama server answers at baseURL
+func ollamaReachable(baseURL string) bool {
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Get(strings.TrimSuffix(baseURL, "/") + "/api/tags")
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+// single embeds one text through a provider's batch method
+func single(ctx context.Context, p Provider, text string) ([]float32, error) {
+	embeddings, err := p.GenerateEmbeddings(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
@@ -0,0 +1,453 @@
+package knowledge
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/baswilson/pika/internal/memory"
+	"github.com/google/uuid"
+)
+
+// Entity types recognised by the extractor
+const (
+	TypePerson       = "person"
+	TypePlace        = "place"
+	TypeOrganization = "organization"
+	TypeDate         = "date"
+	TypeOther        = "other"
+)
+
+// UserEntity is the name used for the user themself in relations
+// such as "User -[dentist]-> Dr. Smith".
+const UserEntity = "User"
+
+// Entity is a person, place, organization or date mentioned in memories
+type Entity struct {
+	ID        string      `json:"id"`
+	Name      string      `json:"name"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	Relations []*Relation `json:"relations,omitempty"`
+	MemoryIDs []string    `json:"memory_ids,omitempty"`
+}
+
+// Relation links a subject entity to an object entity or literal value,
+// e.g. "User -[dentist]-> Dr. Smith" or "Dr. Smith -[phone]-> 555-0100".
+type Relation struct {
+	ID        string    `json:"id"`
+	SubjectID string    `json:"subject_id"`
+	Subject   string    `json:"subject"`
+	Predicate string    `json:"predicate"`
+	ObjectID  string    `json:"object_id,omitempty"`
+	Object    string    `json:"object"`
+	MemoryID  string    `json:"memory_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Fact renders the relation as a short sentence for the AI prompt
+func (r *Relation) Fact() string {
+	return fmt.Sprintf("%s %s: %s", r.Subject, strings.ReplaceAll(r.Predicate, "_", " "), r.Object)
+}
+
+// Extraction is the structured output of entity extraction for one memory
+type Extraction struct {
+	Entities  []ExtractedEntity   `json:"entities"`
+	Relations []ExtractedRelation `json:"relations"`
+}
+
+// ExtractedEntity is an entity found in a memory
+type ExtractedEntity struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// ExtractedRelation is a relation found in a memory. Object may name an
+// extracted entity or be a literal value.
+type ExtractedRelation struct {
+	Subject   string `json:"subject"`
+	Predicate string `json:"predicate"`
+	Object    string `json:"object"`
+}
+
+// Extractor turns memory text into entities and relations.
+// Implemented by ai.Service.
+type Extractor interface {
+	ExtractEntities(ctx context.Context, text string) (*Extraction, error)
+}
+
+// Store persists the knowledge graph in SQLite
+type Store struct {
+	db        *sql.DB
+	extractor Extractor
+}
+
+// NewStore creates a new knowledge graph store
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// SetExtractor sets the entity extractor used for new memories
+func (s *Store) SetExtractor(e Extractor) {
+	s.extractor = e
+}
+
+// Attach extracts entities from every memory created in the memory store
+func (s *Store) Attach(memoryStore *memory.Store) {
+	memoryStore.OnCreate(func(m *memory.Memory) {
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+		if err := s.ProcessMemory(ctx, m.ID, m.Content); err != nil {
+			log.Printf("Knowledge extraction failed for memory %s: %v", m.ID, err)
+		}
+	})
+}
+
+// ProcessMemory extracts entities and relations from a memory and stores them
+func (s *Store) ProcessMemory(ctx context.Context, memoryID, content string) error {
+	if s.extractor == nil {
+		return nil
+	}
+
+	extraction, err := s.extractor.ExtractEntities(ctx, content)
+	if err != nil {
+		return err
+	}
+
+	return s.SaveExtraction(ctx, memoryID, extraction)
+}
+
+// SaveExtraction stores extracted entities and relations linked to a memory
+func (s *Store) SaveExtraction(ctx context.Context, memoryID string, ex *Extraction) error {
+	if ex == nil || (len(ex.Entities) == 0 && len(ex.Relations) == 0) {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ids := make(map[string]string) // normalized name -> entity ID
+	for _, e := range ex.Entities {
+		if strings.TrimSpace(e.Name) == "" {
+			continue
+		}
+		id, err := upsertEntity(ctx, tx, e.Name, e.Type)
+		if err != nil {
+			return err
+		}
+		ids[Normalize(e.Name)] = id
+		if err := addMention(ctx, tx, id, memoryID); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range ex.Relations {
+		predicate := normalizePredicate(r.Predicate)
+		if r.Subject == "" || predicate == "" || r.Object == "" {
+			continue
+		}
+
+		subjectID, ok := ids[Normalize(r.Subject)]
+		if !ok {
+			// Subjects are usually extracted entities; the user is implicit
+			subjectType := TypeOther
+			if Normalize(r.Subject) == Normalize(UserEntity) {
+				subjectType = TypePerson
+			}
+			subjectID, err = upsertEntity(ctx, tx, r.Subject, subjectType)
+			if err != nil {
+				return err
+			}
+			ids[Normalize(r.Subject)] = subjectID
+		}
+
+		var objectID sql.NullString
+		objectValue := r.Object
+		if id, ok := ids[Normalize(r.Object)]; ok {
+			objectID = sql.NullString{String: id, Valid: true}
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO entity_relations (id, subject_id, predicate, object_id, object_value, memory_id, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, uuid.New().String(), subjectID, predicate, objectID, objectValue, memoryID, time.Now().Format(time.RFC3339))
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// upsertEntity returns the ID of the entity with the given name and type,
+// creating it if needed
+func upsertEntity(ctx context.Context, tx *sql.Tx, name, entityType string) (string, error) {
+	entityType = normalizeType(entityType)
+	normalized := Normalize(name)
+	now := time.Now().Format(time.RFC3339)
+
+	var id string
+	err := tx.QueryRowContext(ctx,
+		"SELECT id FROM entities WHERE normalized_name = ? AND entity_type = ?",
+		normalized, entityType).Scan(&id)
+	if err == nil {
+		_, err = tx.ExecContext(ctx, "UPDATE entities SET updated_at = ? WHERE id = ?", now, id)
+		return id, err
+	}
+	if err != sql.ErrNoRows {
+		return "", err
+	}
+
+	id = uuid.New().String()
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO entities (id, name, normalized_name, entity_type, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, id, strings.TrimSpace(name), normalized, entityType, now, now)
+	return id, err
+}
+
+// addMention links an entity to the memory it was found in
+func addMention(ctx context.Context, tx *sql.Tx, entityID, memoryID string) error {
+	_, err := tx.ExecContext(ctx,
+		"INSERT OR IGNORE INTO entity_mentions (entity_id, memory_id) VALUES (?, ?)",
+		entityID, memoryID)
+	return err
+}
+
+// List returns entities whose name contains query, optionally of one type
+func (s *Store) List(ctx context.Context, query, entityType string, limit int) ([]*Entity, error) {
+	sqlQuery := `
+		SELECT id, name, entity_type, created_at, updated_at
+		FROM entities
+		WHERE normalized_name LIKE '%' || ? || '%'
+	`
+	args := []interface{}{Normalize(query)}
+	if entityType != "" {
+		sqlQuery += " AND entity_type = ?"
+		args = append(args, normalizeType(entityType))
+	}
+	sqlQuery += " ORDER BY updated_at DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entities []*Entity
+	for rows.Next() {
+		e := &Entity{}
+		var createdAtStr, updatedAtStr string
+		if err := rows.Scan(&e.ID, &e.Name, &e.Type, &createdAtStr, &updatedAtStr); err != nil {
+			return nil, err
+		}
+		e.CreatedAt = parseTime(createdAtStr)
+		e.UpdatedAt = parseTime(updatedAtStr)
+		entities = append(entities, e)
+	}
+	return entities, rows.Err()
+}
+
+// Get returns an entity with its relations and the memories that mention it
+func (s *Store) Get(ctx context.Context, id string) (*Entity, error) {
+	e := &Entity{}
+	var createdAtStr, updatedAtStr string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, name, entity_type, created_at, updated_at
+		FROM entities
+		WHERE id = ?
+	`, id).Scan(&e.ID, &e.Name, &e.Type, &createdAtStr, &updatedAtStr)
+	if err != nil {
+		return nil, err
+	}
+	e.CreatedAt = parseTime(createdAtStr)
+	e.UpdatedAt = parseTime(updatedAtStr)
+
+	e.Relations, err = s.relations(ctx, "r.subject_id = ? OR r.object_id = ?", id, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT memory_id FROM entity_mentions WHERE entity_id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var memoryID string
+		if err := rows.Scan(&memoryID); err != nil {
+			return nil, err
+		}
+		e.MemoryIDs = append(e.MemoryIDs, memoryID)
+	}
+
+	return e, rows.Err()
+}
+
+// relations returns relations matching a WHERE clause over entity_relations r
+func (s *Store) relations(ctx context.Context, where string, args ...interface{}) ([]*Relation, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT r.id, r.subject_id, subj.name, r.predicate, COALESCE(r.object_id, ''),
+		       COALESCE(obj.name, r.object_value, ''), r.memory_id, r.created_at
+		FROM entity_relations r
+		JOIN memories m ON m.id = r.memory_id
+		JOIN entities subj ON subj.id = r.subject_id
+		LEFT JOIN entities obj ON obj.id = r.object_id
+		WHERE `+where+`
+		ORDER BY r.created_at DESC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var relations []*Relation
+	for rows.Next() {
+		r := &Relation{}
+		var createdAtStr string
+		if err := rows.Scan(&r.ID, &r.SubjectID, &r.Subject, &r.Predicate, &r.ObjectID, &r.Object, &r.MemoryID, &createdAtStr); err != nil {
+			return nil, err
+		}
+		r.CreatedAt = parseTime(createdAtStr)
+		relations = append(relations, r)
+	}
+	return relations, rows.Err()
+}
+
+// FactsForText returns facts about the entities mentioned in text, plus
+// facts whose relation is named in it ("who is my dentist?" matches the
+// "dentist" relation). Used to ground the AI prompt in the knowledge graph.
+func (s *Store) FactsForText(ctx context.Context, text string, limit int) ([]string, error) {
+	normalized := " " + Normalize(text) + " "
+
+	// Find entities named in the text (the user entity is implicit everywhere)
+	rows, err := s.db.QueryContext(ctx, "SELECT id, normalized_name FROM entities WHERE normalized_name != ?", Normalize(UserEntity))
+	if err != nil {
+		return nil, err
+	}
+	var entityIDs []string
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if name != "" && strings.Contains(normalized, " "+name+" ") {
+			entityIDs = append(entityIDs, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Find relations named in the text
+	predRows, err := s.db.QueryContext(ctx, "SELECT DISTINCT predicate FROM entity_relations")
+	if err != nil {
+		return nil, err
+	}
+	var predicates []string
+	for predRows.Next() {
+		var p string
+		if err := predRows.Scan(&p); err != nil {
+			predRows.Close()
+			return nil, err
+		}
+		if strings.Contains(normalized, " "+strings.ReplaceAll(p, "_", " ")+" ") {
+			predicates = append(predicates, p)
+		}
+	}
+	predRows.Close()
+	if err := predRows.Err(); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var facts []string
+	add := func(relations []*Relation) {
+		for _, r := range relations {
+			fact := r.Fact()
+			if !seen[fact] {
+				seen[fact] = true
+				facts = append(facts, fact)
+			}
+		}
+	}
+
+	// Facts from expired memories are left out even before they are purged
+	unexpired, now := memory.UnexpiredCondition("m")
+	for _, id := range entityIDs {
+		relations, err := s.relations(ctx, "(r.subject_id = ? OR r.object_id = ?) AND "+unexpired, id, id, now)
+		if err != nil {
+			return nil, err
+		}
+		add(relations)
+	}
+	for _, p := range predicates {
+		relations, err := s.relations(ctx, "r.predicate = ? AND "+unexpired, p, now)
+		if err != nil {
+			return nil, err
+		}
+		add(relations)
+	}
+
+	sort.Strings(facts)
+	if len(facts) > limit {
+		facts = facts[:limit]
+	}
+	return facts, nil
+}
+
+// Normalize lowercases a name and strips punctuation for matching
+func Normalize(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r > 127:
+			b.WriteRune(r)
+		default:
+			// Apostrophes split too, so "Sarah's" still contains "sarah"
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// normalizePredicate turns "Works At" into "works_at"
+func normalizePredicate(p string) string {
+	return strings.ReplaceAll(Normalize(p), " ", "_")
+}
+
+// normalizeType maps an extracted type onto the known entity types
+func normalizeType(t string) string {
+	switch strings.ToLower(strings.TrimSpace(t)) {
+	case TypePerson, "people":
+		return TypePerson
+	case TypePlace, "location":
+		return TypePlace
+	case TypeOrganization, "organisation", "org", "company":
+		return TypeOrganization
+	case TypeDate, "time", "datetime":
+		return TypeDate
+	default:
+		return TypeOther
+	}
+}
+
+// parseTime parses a time string from SQLite
+func parseTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t, _ = time.Parse("2006-01-02 15:04:05", s)
+	}
+	return t
+}
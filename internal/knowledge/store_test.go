@@ -0,0 +1,61 @@
+package knowledge
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/baswilson/pika/internal/database"
+	"github.com/baswilson/pika/internal/memory"
+)
+
+func TestFactsForTextSkipsExpiredMemories(t *testing.T) {
+	driver, err := database.NewSQLiteDriver(filepath.Join(t.TempDir(), "pika.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer driver.Close()
+	ctx := context.Background()
+	if err := driver.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	memories := memory.NewStore(driver.DB())
+	store := NewStore(driver.DB())
+
+	expires := time.Now().Add(time.Hour)
+	m, err := memories.CreateWithOptions(ctx, "Staying at the Hilton this week", 0.5, nil, memory.CreateOptions{ExpiresAt: &expires})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.SaveExtraction(ctx, m.ID, &Extraction{
+		Entities:  []ExtractedEntity{{Name: "Hilton", Type: TypePlace}},
+		Relations: []ExtractedRelation{{Subject: UserEntity, Predicate: "hotel", Object: "Hilton"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	facts, err := store.FactsForText(ctx, "what is my hotel?", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(facts) != 1 {
+		t.Fatalf("got facts %q before expiry, want one", facts)
+	}
+
+	// Expired but not yet purged
+	if _, err := driver.DB().Exec("UPDATE memories SET expires_at = ? WHERE id = ?",
+		time.Now().Add(-time.Minute).UTC().Format(time.RFC3339), m.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	facts, err = store.FactsForText(ctx, "what is my hotel at the Hilton?", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(facts) != 0 {
+		t.Errorf("got facts %q from an expired memory", facts)
+	}
+}
@@ -0,0 +1,244 @@
+package learning
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/baswilson/pika/internal/memory"
+)
+
+// Turn is one message in a conversation transcript
+type Turn struct {
+	Role    string `json:"role"` // "user" or "assistant"
+	Content string `json:"content"`
+}
+
+// Candidate is a memory proposed by the extractor
+type Candidate struct {
+	Content    string   `json:"content"`
+	Importance float64  `json:"importance"`
+	Tags       []string `json:"tags"`
+}
+
+// Extractor reviews a transcript and proposes memories.
+// Implemented by ai.Service.
+type Extractor interface {
+	ExtractMemories(ctx context.Context, transcript []Turn) ([]Candidate, error)
+	Model() string
+}
+
+// session buffers the turns of a conversation not yet reviewed
+type session struct {
+	turns        []Turn
+	lastActivity time.Time
+}
+
+// Learner extracts memories from conversations in the background once they go idle
+type Learner struct {
+	store     *memory.Store
+	extractor Extractor
+	ticker    *time.Ticker
+	stop      chan struct{}
+	done      chan struct{}
+	mu        sync.Mutex
+	running   bool
+	sessions  map[string]*session
+
+	// Configuration
+	idleThreshold  time.Duration
+	checkInterval  time.Duration // 1 minute
+	reviewTimeout  time.Duration // per idle session
+	flushTimeout   time.Duration // for all sessions left on Stop
+	dedupThreshold float32       // embedding similarity treated as the same memory
+	maxTurns       int           // turns kept per session
+}
+
+// NewLearner creates a learner that reviews a session after idleThreshold without activity
+func NewLearner(store *memory.Store, extractor Extractor, idleThreshold time.Duration) *Learner {
+	return &Learner{
+		store:          store,
+		extractor:      extractor,
+		stop:           make(chan struct{}),
+		done:           make(chan struct{}),
+		sessions:       make(map[string]*session),
+		idleThreshold:  idleThreshold,
+		checkInterval:  1 * time.Minute,
+		reviewTimeout:  60 * time.Second,
+		flushTimeout:   20 * time.Second,
+		dedupThreshold: 0.9,
+		maxTurns:       40,
+	}
+}
+
+// Record adds a turn to a session's transcript
+func (l *Learner) Record(sessionID, role, content string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sess, ok := l.sessions[sessionID]
+	if !ok {
+		sess = &session{}
+		l.sessions[sessionID] = sess
+	}
+	sess.turns = append(sess.turns, Turn{Role: role, Content: content})
+	if len(sess.turns) > l.maxTurns {
+		sess.turns = sess.turns[len(sess.turns)-l.maxTurns:]
+	}
+	sess.lastActivity = time.Now()
+}
+
+// Start begins the idle checking loop
+func (l *Learner) Start() {
+	l.mu.Lock()
+	if l.running {
+		l.mu.Unlock()
+		return
+	}
+	l.running = true
+	l.ticker = time.NewTicker(l.checkInterval)
+	l.mu.Unlock()
+
+	log.Printf("Memory learner started (idle threshold %v)", l.idleThreshold)
+
+	go func() {
+		defer close(l.done)
+		for {
+			select {
+			case <-l.ticker.C:
+				l.reviewIdle()
+			case <-l.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the learner after reviewing the sessions not yet reviewed, so
+// the end of a conversation is not lost on shutdown. Reviews still running
+// after flushTimeout are abandoned.
+func (l *Learner) Stop() {
+	l.mu.Lock()
+	if !l.running {
+		l.mu.Unlock()
+		return
+	}
+	l.running = false
+	if l.ticker != nil {
+		l.ticker.Stop()
+	}
+	close(l.stop)
+	l.mu.Unlock()
+
+	// Let a review in progress finish before taking the rest
+	<-l.done
+
+	l.mu.Lock()
+	pending := make(map[string][]Turn, len(l.sessions))
+	for id, sess := range l.sessions {
+		pending[id] = sess.turns
+		delete(l.sessions, id)
+	}
+	l.mu.Unlock()
+
+	if len(pending) > 0 {
+		log.Printf("Reviewing %d conversations before stopping the memory learner", len(pending))
+		ctx, cancel := context.WithTimeout(context.Background(), l.flushTimeout)
+		defer cancel()
+
+		var wg sync.WaitGroup
+		for id, turns := range pending {
+			wg.Add(1)
+			go func(id string, turns []Turn) {
+				defer wg.Done()
+				if err := l.review(ctx, id, turns); err != nil {
+					log.Printf("Memory extraction failed for session %s: %v", id, err)
+				}
+			}(id, turns)
+		}
+		wg.Wait()
+	}
+	log.Println("Memory learner stopped")
+}
+
+// reviewIdle extracts memories from every session idle for longer than the threshold
+func (l *Learner) reviewIdle() {
+	now := time.Now()
+
+	l.mu.Lock()
+	idle := make(map[string][]Turn)
+	for id, sess := range l.sessions {
+		if now.Sub(sess.lastActivity) >= l.idleThreshold {
+			idle[id] = sess.turns
+			delete(l.sessions, id)
+		}
+	}
+	l.mu.Unlock()
+
+	for id, turns := range idle {
+		ctx, cancel := context.WithTimeout(context.Background(), l.reviewTimeout)
+		err := l.review(ctx, id, turns)
+		cancel()
+		if err != nil {
+			log.Printf("Memory extraction failed for session %s: %v", id, err)
+		}
+	}
+}
+
+// review extracts memories from a transcript and saves those not already known
+func (l *Learner) review(ctx context.Context, sessionID string, turns []Turn) error {
+	if !hasUserTurn(turns) {
+		return nil
+	}
+
+	candidates, err := l.extractor.ExtractMemories(ctx, turns)
+	if err != nil {
+		return err
+	}
+
+	source := &memory.Source{
+		Origin:         memory.OriginExtracted,
+		ConversationID: sessionID,
+		Model:          l.extractor.Model(),
+	}
+
+	saved, skipped := 0, 0
+	for _, c := range candidates {
+		if c.Content == "" {
+			continue
+		}
+
+		dup, err := l.store.FindDuplicate(ctx, c.Content, l.dedupThreshold)
+		if err != nil {
+			log.Printf("Warning: duplicate check failed for %q: %v", c.Content, err)
+		}
+		if dup != nil {
+			skipped++
+			continue
+		}
+
+		importance := c.Importance
+		if importance <= 0 || importance > 1 {
+			importance = 0.5
+		}
+		if _, err := l.store.CreateWithOptions(ctx, c.Content, importance, c.Tags, memory.CreateOptions{Source: source}); err != nil {
+			log.Printf("Warning: failed to save extracted memory %q: %v", c.Content, err)
+			continue
+		}
+		saved++
+	}
+
+	log.Printf("Learned %d memories from session %s (%d already known)", saved, sessionID, skipped)
+	return nil
+}
+
+// hasUserTurn reports whether the transcript contains anything the user said
+func hasUserTurn(turns []Turn) bool {
+	for _, t := range turns {
+		if t.Role == "user" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package learning
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/baswilson/pika/internal/database"
+	"github.com/baswilson/pika/internal/memory"
+)
+
+// fakeExtractor proposes one memory per user turn
+type fakeExtractor struct{}
+
+func (fakeExtractor) ExtractMemories(ctx context.Context, transcript []Turn) ([]Candidate, error) {
+	var candidates []Candidate
+	for _, t := range transcript {
+		if t.Role == "user" {
+			candidates = append(candidates, Candidate{Content: t.Content, Importance: 0.5})
+		}
+	}
+	return candidates, nil
+}
+
+func (fakeExtractor) Model() string { return "fake" }
+
+func TestStopReviewsPendingSessions(t *testing.T) {
+	driver, err := database.NewSQLiteDriver(filepath.Join(t.TempDir(), "pika.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer driver.Close()
+	ctx := context.Background()
+	if err := driver.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	store := memory.NewStore(driver.DB())
+
+	// The session never goes idle before the learner stops
+	learner := NewLearner(store, fakeExtractor{}, time.Hour)
+	learner.Start()
+	learner.Record("conversation-1", "user", "My sister is called Anna")
+	learner.Record("conversation-1", "assistant", "Nice to know!")
+	learner.Stop()
+
+	learned, err := store.ListByConversation(ctx, "conversation-1", memory.OriginExtracted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(learned) != 1 || learned[0].Content != "My sister is called Anna" {
+		t.Errorf("learned %d memories on stop, want the one about Anna", len(learned))
+	}
+
+	// Stopping again does nothing
+	learner.Stop()
+}
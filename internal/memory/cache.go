@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"sync"
+)
+
+// EmbeddingCache is a fixed-size LRU cache of embeddings keyed by model and
+// a hash of the embedded text. It avoids re-embedding text that was just
+// seen, such as a repeated question or a memory saved from the same utterance.
+type EmbeddingCache struct {
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List // front = most recently used
+	mu       sync.Mutex
+}
+
+type cacheEntry struct {
+	key       string
+	embedding []float32
+}
+
+// NewEmbeddingCache creates a cache holding up to capacity embeddings.
+// A capacity of zero or less disables caching.
+func NewEmbeddingCache(capacity int) *EmbeddingCache {
+	return &EmbeddingCache{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// cacheKey builds the lookup key for a model and text
+func cacheKey(model, text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return model + ":" + hex.EncodeToString(sum[:])
+}
+
+// Get returns a copy of the cached embedding for text under model, if
+// present, so callers may modify it
+func (c *EmbeddingCache) Get(model, text string) ([]float32, bool) {
+	if c == nil || c.capacity <= 0 {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[cacheKey(model, text)]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return slices.Clone(el.Value.(*cacheEntry).embedding), true
+}
+
+// Put stores a copy of an embedding, evicting the least recently used entry
+// when full
+func (c *EmbeddingCache) Put(model, text string, embedding []float32) {
+	if c == nil || c.capacity <= 0 || len(embedding) == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	embedding = slices.Clone(embedding)
+	key := cacheKey(model, text)
+	if el, ok := c.items[key]; ok {
+		el.Value.(*cacheEntry).embedding = embedding
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&cacheEntry{key: key, embedding: embedding})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// Len returns the number of cached embeddings
+func (c *EmbeddingCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,43 @@
+package memory
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// emailPattern matches an email address in a memory such as
+// "Sarah's email is sarah@example.com"
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+// FindEmail returns the email address remembered for a contact, or "" when
+// no memory mentioning name has one. An address containing the name is
+// preferred; otherwise a memory must be about an email address and hold
+// just one to be trusted.
+func (s *Store) FindEmail(ctx context.Context, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil
+	}
+
+	contents, err := s.SearchRelevant(ctx, name, 20)
+	if err != nil {
+		return "", err
+	}
+
+	first := strings.ToLower(strings.Fields(name)[0])
+	var fallback string
+	for _, content := range contents {
+		emails := emailPattern.FindAllString(content, -1)
+		for i, email := range emails {
+			emails[i] = strings.TrimRight(email, ".")
+			if strings.Contains(strings.ToLower(emails[i]), first) {
+				return emails[i], nil
+			}
+		}
+		if fallback == "" && len(emails) == 1 && strings.Contains(strings.ToLower(content), "mail") {
+			fallback = emails[0]
+		}
+	}
+	return fallback, nil
+}
@@ -7,4 +7,13 @@ import "context"
 // depending directly on the AI service.
 type EmbeddingGenerator interface {
 	GenerateEmbedding(ctx context.Context, text string) ([]float32, error)
+
+	// GenerateEmbeddings embeds several texts in a single request.
+	// The result has one vector per input, in the same order.
+	GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error)
+
+	// EmbeddingModel returns the name of the model producing embeddings.
+	// Vectors from different models live in different spaces, so the store
+	// records it alongside each embedding and only compares like with like.
+	EmbeddingModel() string
 }
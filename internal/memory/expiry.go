@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// ExpiryPurger periodically deletes temporary memories whose expiry has passed.
+// Expired memories are already hidden from retrieval; purging keeps the
+// table, vector search and exports free of them.
+type ExpiryPurger struct {
+	store    *Store
+	interval time.Duration
+	stop     chan struct{}
+	mu       sync.Mutex
+	running  bool
+}
+
+// NewExpiryPurger creates a purger that runs every interval
+func NewExpiryPurger(store *Store, interval time.Duration) *ExpiryPurger {
+	return &ExpiryPurger{
+		store:    store,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start purges once immediately and then on every interval
+func (p *ExpiryPurger) Start() {
+	p.mu.Lock()
+	if p.running {
+		p.mu.Unlock()
+		return
+	}
+	p.running = true
+	p.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		p.purge()
+		for {
+			select {
+			case <-ticker.C:
+				p.purge()
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+
+	log.Printf("Memory expiry purger started (every %v)", p.interval)
+}
+
+// Stop stops the purger
+func (p *ExpiryPurger) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.running {
+		return
+	}
+	p.running = false
+	close(p.stop)
+}
+
+// purge deletes expired memories
+func (p *ExpiryPurger) purge() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	n, err := p.store.PurgeExpired(ctx)
+	if err != nil {
+		log.Printf("Failed to purge expired memories: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("Purged %d expired memories", n)
+	}
+}
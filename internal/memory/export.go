@@ -0,0 +1,378 @@
+package memory
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Export format identifiers. The first line of an export is an ExportHeader,
+// followed by one ExportRecord per line (JSON Lines).
+const (
+	ExportFormat  = "pika-memories"
+	ExportVersion = 1
+)
+
+// ExportHeader is the first line of a JSON Lines export
+type ExportHeader struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Count      int       `json:"count"`
+}
+
+// ExportRecord is a single memory in the portable export format
+type ExportRecord struct {
+	ID             string     `json:"id"`
+	Content        string     `json:"content"`
+	Importance     float64    `json:"importance"`
+	Tags           []string   `json:"tags"`
+	CreatedAt      time.Time  `json:"created_at"`
+	LastAccessed   time.Time  `json:"last_accessed"`
+	AccessCount    int        `json:"access_count"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	Source         *Source    `json:"source,omitempty"`
+	Embedding      []float32  `json:"embedding,omitempty"`
+	EmbeddingModel string     `json:"embedding_model,omitempty"`
+}
+
+// Import modes
+const (
+	// ImportMerge inserts every record whose ID is not already present
+	ImportMerge = "merge"
+	// ImportDedup additionally skips records whose content matches an existing memory
+	ImportDedup = "dedup"
+)
+
+// ImportResult summarises an import
+type ImportResult struct {
+	Imported   int      `json:"imported"`
+	Skipped    int      `json:"skipped"`
+	Reembedded int      `json:"reembedded"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// Export writes all memories as JSON Lines.
+// Embeddings and their model name are included when withEmbeddings is set.
+func (s *Store) Export(ctx context.Context, w io.Writer, withEmbeddings bool) error {
+	var count int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM memories").Scan(&count); err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(ExportHeader{
+		Format:     ExportFormat,
+		Version:    ExportVersion,
+		ExportedAt: time.Now().UTC(),
+		Count:      count,
+	}); err != nil {
+		return err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+memoryColumns+`, embedding
+		FROM memories
+		ORDER BY created_at ASC
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var embeddingBlob []byte
+		m, err := scanMemory(rows, &embeddingBlob)
+		if err != nil {
+			return err
+		}
+
+		rec := ExportRecord{
+			ID:           m.ID,
+			Content:      m.Content,
+			Importance:   m.Importance,
+			Tags:         m.Tags,
+			CreatedAt:    m.CreatedAt,
+			LastAccessed: m.LastAccessed,
+			AccessCount:  m.AccessCount,
+			ExpiresAt:    m.ExpiresAt,
+			Source:       m.Source,
+		}
+		if withEmbeddings && m.EmbeddingModel != "" {
+			rec.Embedding = BlobToVector(embeddingBlob)
+			rec.EmbeddingModel = m.EmbeddingModel
+		}
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ExportMarkdown writes all memories as a human-readable Markdown document,
+// most important first.
+func (s *Store) ExportMarkdown(ctx context.Context, w io.Writer) error {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+memoryColumns+`
+		FROM memories
+		ORDER BY importance DESC, created_at ASC
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	memories, err := scanMemories(rows)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# PIKA Memories\n\n")
+	fmt.Fprintf(bw, "Exported %s - %d memories\n\n", time.Now().Format("January 2, 2006 3:04 PM"), len(memories))
+	for _, m := range memories {
+		fmt.Fprintf(bw, "- %s\n", strings.ReplaceAll(m.Content, "\n", " "))
+		details := fmt.Sprintf("importance %.1f, saved %s", m.Importance, m.CreatedAt.Format("Jan 2, 2006"))
+		if len(m.Tags) > 0 {
+			details += ", tags: " + strings.Join(m.Tags, ", ")
+		}
+		if m.ExpiresAt != nil {
+			details += ", expires " + m.ExpiresAt.Local().Format("Jan 2, 2006 3:04 PM")
+		}
+		fmt.Fprintf(bw, "  - _%s_\n", details)
+	}
+	return bw.Flush()
+}
+
+// Import reads a JSON Lines export and inserts its memories.
+// Records with an ID that already exists or appeared earlier in the file are
+// skipped; in ImportDedup mode records whose normalised content matches an
+// existing memory are skipped too. Records without an embedding from the
+// active model are re-embedded in batches. The create hooks run for the
+// imported memories in the background. When a record cannot be read, the
+// records before it are still imported.
+func (s *Store) Import(ctx context.Context, r io.Reader, mode string) (*ImportResult, error) {
+	if mode == "" {
+		mode = ImportMerge
+	}
+	if mode != ImportMerge && mode != ImportDedup {
+		return nil, fmt.Errorf("unknown import mode: %s", mode)
+	}
+
+	dec := json.NewDecoder(r)
+
+	var header ExportHeader
+	if err := dec.Decode(&header); err != nil {
+		return nil, fmt.Errorf("failed to read export header: %w", err)
+	}
+	if header.Format != ExportFormat {
+		return nil, fmt.Errorf("not a PIKA memory export (format %q)", header.Format)
+	}
+	if header.Version > ExportVersion {
+		return nil, fmt.Errorf("unsupported export version %d (max %d)", header.Version, ExportVersion)
+	}
+
+	seenContent := make(map[string]bool)
+	if mode == ImportDedup {
+		var err error
+		seenContent, err = s.contentIndex(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := &ImportResult{}
+	var pending []ExportRecord
+	var imported []*Memory
+	defer func() { s.notifyImported(imported) }()
+	seenIDs := make(map[string]bool)
+
+	for line := 1; ; line++ {
+		var rec ExportRecord
+		if err := dec.Decode(&rec); err == io.EOF {
+			break
+		} else if err != nil {
+			imported = append(imported, s.importBatch(ctx, pending, result)...)
+			return result, fmt.Errorf("record %d: %w", line, err)
+		}
+
+		if strings.TrimSpace(rec.Content) == "" {
+			result.Skipped++
+			continue
+		}
+		if rec.ID == "" {
+			rec.ID = uuid.New().String()
+		}
+
+		exists := seenIDs[rec.ID]
+		if !exists {
+			var err error
+			if exists, err = s.exists(ctx, rec.ID); err != nil {
+				imported = append(imported, s.importBatch(ctx, pending, result)...)
+				return result, err
+			}
+		}
+		key := normalizeContent(rec.Content)
+		if exists || (mode == ImportDedup && seenContent[key]) {
+			result.Skipped++
+			continue
+		}
+		seenIDs[rec.ID] = true
+		seenContent[key] = true
+
+		pending = append(pending, rec)
+		if len(pending) >= importBatchSize {
+			imported = append(imported, s.importBatch(ctx, pending, result)...)
+			pending = pending[:0]
+		}
+	}
+	imported = append(imported, s.importBatch(ctx, pending, result)...)
+
+	return result, nil
+}
+
+// importBatchSize is the number of records embedded and inserted together
+const importBatchSize = 32
+
+// importBatch embeds records that need it and inserts them, returning the
+// memories inserted
+func (s *Store) importBatch(ctx context.Context, records []ExportRecord, result *ImportResult) []*Memory {
+	if len(records) == 0 {
+		return nil
+	}
+	model := s.activeModel()
+
+	if s.embedder != nil {
+		var texts []string
+		var idx []int
+		for i, rec := range records {
+			if rec.EmbeddingModel != model || len(rec.Embedding) == 0 {
+				texts = append(texts, rec.Content)
+				idx = append(idx, i)
+			}
+		}
+		if len(texts) > 0 {
+			embeddings, err := s.embedder.GenerateEmbeddings(ctx, texts)
+			if err != nil {
+				log.Printf("Warning: failed to embed imported memories: %v", err)
+			} else {
+				for i, emb := range embeddings {
+					records[idx[i]].Embedding = emb
+					records[idx[i]].EmbeddingModel = model
+				}
+				result.Reembedded += len(texts)
+			}
+		}
+	}
+
+	var memories []*Memory
+	for _, rec := range records {
+		m, err := s.insertRecord(ctx, rec)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", rec.ID, err))
+			continue
+		}
+		memories = append(memories, m)
+		result.Imported++
+	}
+	return memories
+}
+
+// insertRecord stores an imported memory, keeping its original timestamps
+func (s *Store) insertRecord(ctx context.Context, rec ExportRecord) (*Memory, error) {
+	tags := rec.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	tagsJSON, err := json.Marshal(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	createdAt, lastAccessed := rec.CreatedAt, rec.LastAccessed
+	if createdAt.IsZero() {
+		createdAt = now
+	}
+	if lastAccessed.IsZero() {
+		lastAccessed = createdAt
+	}
+
+	var embeddingModel sql.NullString
+	var embeddingDim sql.NullInt64
+	if len(rec.Embedding) > 0 && rec.EmbeddingModel != "" {
+		embeddingModel = sql.NullString{String: rec.EmbeddingModel, Valid: true}
+		embeddingDim = sql.NullInt64{Int64: int64(len(rec.Embedding)), Valid: true}
+	} else {
+		rec.Embedding = nil
+	}
+
+	// Records from exports without provenance are marked as imported
+	source := rec.Source
+	if source == nil {
+		source = &Source{Origin: OriginImport}
+	}
+
+	args := []interface{}{rec.ID, rec.Content, Vector(rec.Embedding), embeddingModel, embeddingDim, rec.Importance, string(tagsJSON), createdAt, lastAccessed, rec.AccessCount, expiryValue(rec.ExpiresAt)}
+	args = append(args, sourceValues(source)...)
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO memories (id, content, embedding, embedding_model, embedding_dim, importance, tags, created_at, last_accessed, access_count, expires_at, `+sourceColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Memory{
+		ID:             rec.ID,
+		Content:        rec.Content,
+		Importance:     rec.Importance,
+		Tags:           tags,
+		CreatedAt:      createdAt,
+		LastAccessed:   lastAccessed,
+		AccessCount:    rec.AccessCount,
+		EmbeddingModel: embeddingModel.String,
+		EmbeddingDim:   int(embeddingDim.Int64),
+		ExpiresAt:      rec.ExpiresAt,
+		Source:         source,
+	}, nil
+}
+
+// exists reports whether a memory with the given ID is stored
+func (s *Store) exists(ctx context.Context, id string) (bool, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM memories WHERE id = ?", id).Scan(&n)
+	return n > 0, err
+}
+
+// contentIndex returns the normalised content of every stored memory
+func (s *Store) contentIndex(ctx context.Context) (map[string]bool, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT content FROM memories")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	index := make(map[string]bool)
+	for rows.Next() {
+		var content string
+		if err := rows.Scan(&content); err != nil {
+			return nil, err
+		}
+		index[normalizeContent(content)] = true
+	}
+	return index, rows.Err()
+}
+
+// normalizeContent lowercases and collapses whitespace for duplicate detection
+func normalizeContent(content string) string {
+	return strings.Join(strings.Fields(strings.ToLower(content)), " ")
+}
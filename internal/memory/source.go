@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// Memory origins
+const (
+	// OriginUser is a memory saved explicitly through the API
+	OriginUser = "user"
+	// OriginInferred is a memory the model chose to save during a conversation
+	OriginInferred = "inferred"
+	// OriginExtracted is a memory found by reviewing a conversation after it went idle
+	OriginExtracted = "extracted"
+	// OriginImport is a memory restored from an export
+	OriginImport = "import"
+)
+
+// Source records where a memory came from
+type Source struct {
+	Origin         string `json:"origin"`
+	ConversationID string `json:"conversation_id,omitempty"`
+	RequestID      string `json:"request_id,omitempty"`
+	Utterance      string `json:"utterance,omitempty"`
+	Model          string `json:"model,omitempty"`
+}
+
+type sourceKey struct{}
+
+// WithSource returns a context carrying the source of memories created while handling it
+func WithSource(ctx context.Context, src Source) context.Context {
+	return context.WithValue(ctx, sourceKey{}, src)
+}
+
+// SourceFromContext returns the source stored by WithSource, if any
+func SourceFromContext(ctx context.Context) (Source, bool) {
+	src, ok := ctx.Value(sourceKey{}).(Source)
+	return src, ok
+}
+
+// sourceColumns is the provenance column list, in the order read by scanMemory
+const sourceColumns = "source_origin, source_conversation_id, source_request_id, source_utterance, source_model"
+
+// sourceValues converts an optional source into nullable column values
+func sourceValues(src *Source) []interface{} {
+	if src == nil {
+		return []interface{}{nil, nil, nil, nil, nil}
+	}
+	return []interface{}{
+		nullString(src.Origin),
+		nullString(src.ConversationID),
+		nullString(src.RequestID),
+		nullString(src.Utterance),
+		nullString(src.Model),
+	}
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// GetSource returns the provenance of a memory, or nil if none was recorded
+func (s *Store) GetSource(ctx context.Context, id string) (*Source, error) {
+	m, err := scanMemory(s.db.QueryRowContext(ctx, `
+		SELECT `+memoryColumns+`
+		FROM memories
+		WHERE id = ?
+	`, id))
+	if err != nil {
+		return nil, err
+	}
+	return m.Source, nil
+}
+
+// ListByConversation returns the memories created during a conversation, oldest first.
+// When origins are given only memories with one of those origins are returned.
+func (s *Store) ListByConversation(ctx context.Context, conversationID string, origins ...string) ([]*Memory, error) {
+	query := `
+		SELECT ` + memoryColumns + `
+		FROM memories
+		WHERE source_conversation_id = ?`
+	args := []interface{}{conversationID}
+	if len(origins) > 0 {
+		query += " AND source_origin IN (?" + strings.Repeat(", ?", len(origins)-1) + ")"
+		for _, o := range origins {
+			args = append(args, o)
+		}
+	}
+	query += " ORDER BY created_at ASC"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanMemories(rows)
+}
+
+// DeleteInferredByConversation removes every memory the model inferred or
+// extracted from a conversation and returns how many were removed. Memories
+// the user saved explicitly are kept.
+func (s *Store) DeleteInferredByConversation(ctx context.Context, conversationID string) (int64, error) {
+	result, err := s.db.ExecContext(ctx,
+		"DELETE FROM memories WHERE source_conversation_id = ? AND source_origin IN (?, ?)",
+		conversationID, OriginInferred, OriginExtracted)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -7,6 +7,7 @@ import (
 	"log"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -42,12 +43,129 @@ type Memory struct {
 	CreatedAt    time.Time `json:"created_at"`
 	LastAccessed time.Time `json:"last_accessed"`
 	AccessCount  int       `json:"access_count"`
+
+	// EmbeddingModel and EmbeddingDim identify the vector space of the stored
+	// embedding. Both are empty when the memory has no embedding yet.
+	EmbeddingModel string `json:"embedding_model,omitempty"`
+	EmbeddingDim   int    `json:"embedding_dim,omitempty"`
+
+	// ExpiresAt is set for temporary memories ("I'm working from home today").
+	// Expired memories are excluded from retrieval and purged in the background.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// Source records the conversation turn or API call that produced the memory.
+	// Nil for memories saved before provenance was tracked.
+	Source *Source `json:"source,omitempty"`
+}
+
+// CreateOptions holds optional attributes for a new memory
+type CreateOptions struct {
+	// ExpiresAt makes the memory temporary
+	ExpiresAt *time.Time
+	// Source records where the memory came from
+	Source *Source
+}
+
+// memoryColumns is the column list read by scanMemory
+const memoryColumns = "id, content, importance, tags, created_at, last_accessed, access_count, embedding_model, embedding_dim, expires_at, " + sourceColumns
+
+// notExpired is a WHERE condition excluding expired memories.
+// It takes the current time formatted with formatExpiry as its argument.
+const notExpired = "(expires_at IS NULL OR expires_at > ?)"
+
+// UnexpiredCondition returns notExpired for the memories table joined as
+// alias, and its argument, for packages that join memories in their queries
+func UnexpiredCondition(alias string) (string, interface{}) {
+	return "(" + alias + ".expires_at IS NULL OR " + alias + ".expires_at > ?)", nowExpiry()
+}
+
+// formatExpiry formats an expiry time so that string comparison in SQLite
+// matches chronological order
+func formatExpiry(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// nowExpiry returns the current time for comparison against expires_at
+func nowExpiry() string {
+	return formatExpiry(time.Now())
+}
+
+// expiryValue converts an optional expiry into a nullable column value
+func expiryValue(t *time.Time) sql.NullString {
+	if t == nil || t.IsZero() {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: formatExpiry(*t), Valid: true}
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanMemory reads a row selected with memoryColumns, followed by any extra
+// destinations the caller appended to the SELECT.
+func scanMemory(row rowScanner, extra ...interface{}) (*Memory, error) {
+	m := &Memory{}
+	var tagsJSON string
+	var createdAtStr, lastAccessedStr string
+	var embeddingModel, expiresAt sql.NullString
+	var embeddingDim sql.NullInt64
+	var origin, conversationID, requestID, utterance, model sql.NullString
+
+	dest := []interface{}{
+		&m.ID, &m.Content, &m.Importance, &tagsJSON, &createdAtStr, &lastAccessedStr, &m.AccessCount,
+		&embeddingModel, &embeddingDim, &expiresAt,
+		&origin, &conversationID, &requestID, &utterance, &model,
+	}
+	dest = append(dest, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	m.CreatedAt = parseTimeString(createdAtStr)
+	m.LastAccessed = parseTimeString(lastAccessedStr)
+	m.EmbeddingModel = embeddingModel.String
+	m.EmbeddingDim = int(embeddingDim.Int64)
+	if expiresAt.Valid {
+		if t := parseTimeString(expiresAt.String); !t.IsZero() {
+			m.ExpiresAt = &t
+		}
+	}
+	if origin.Valid {
+		m.Source = &Source{
+			Origin:         origin.String,
+			ConversationID: conversationID.String,
+			RequestID:      requestID.String,
+			Utterance:      utterance.String,
+			Model:          model.String,
+		}
+	}
+	if err := json.Unmarshal([]byte(tagsJSON), &m.Tags); err != nil {
+		m.Tags = []string{} // Default to empty if parse fails
+	}
+	return m, nil
+}
+
+// scanMemories collects all rows selected with memoryColumns
+func scanMemories(rows *sql.Rows) ([]*Memory, error) {
+	var memories []*Memory
+	for rows.Next() {
+		m, err := scanMemory(rows)
+		if err != nil {
+			return nil, err
+		}
+		memories = append(memories, m)
+	}
+	return memories, rows.Err()
 }
 
 // Store handles memory persistence
 type Store struct {
-	db       *sql.DB
-	embedder EmbeddingGenerator
+	db          *sql.DB
+	embedder    EmbeddingGenerator
+	createHooks []func(*Memory)
+	hooksDone   sync.WaitGroup // running create hooks
 }
 
 // NewStore creates a new memory store
@@ -61,20 +179,69 @@ func (s *Store) SetEmbedder(e EmbeddingGenerator) {
 	s.embedder = e
 }
 
+// OnCreate registers a function called in the background after each memory
+// is created. Used by subsystems that derive data from memories.
+func (s *Store) OnCreate(fn func(*Memory)) {
+	s.createHooks = append(s.createHooks, fn)
+}
+
+// notifyCreated runs the create hooks for a new memory
+func (s *Store) notifyCreated(m *Memory) {
+	for _, fn := range s.createHooks {
+		s.hooksDone.Add(1)
+		go func() {
+			defer s.hooksDone.Done()
+			fn(m)
+		}()
+	}
+}
+
+// notifyImported runs the create hooks for imported memories one at a time,
+// so a large import does not start a hook per memory at once
+func (s *Store) notifyImported(memories []*Memory) {
+	if len(s.createHooks) == 0 || len(memories) == 0 {
+		return
+	}
+	s.hooksDone.Add(1)
+	go func() {
+		defer s.hooksDone.Done()
+		for _, m := range memories {
+			for _, fn := range s.createHooks {
+				fn(m)
+			}
+		}
+	}()
+}
+
+// WaitHooks waits for the create hooks of the memories created so far,
+// for tools that exit after creating memories
+func (s *Store) WaitHooks() {
+	s.hooksDone.Wait()
+}
+
 // Create stores a new memory with optional embedding generation
 func (s *Store) Create(ctx context.Context, content string, importance float64, tags []string) (*Memory, error) {
+	return s.CreateWithOptions(ctx, content, importance, tags, CreateOptions{})
+}
+
+// CreateWithOptions stores a new memory with optional attributes such as an expiry
+func (s *Store) CreateWithOptions(ctx context.Context, content string, importance float64, tags []string, opts CreateOptions) (*Memory, error) {
 	id := uuid.New().String()
 	now := time.Now()
 
 	// Generate embedding if embedder is available
 	var embedding Vector
+	var embeddingModel sql.NullString
+	var embeddingDim sql.NullInt64
 	if s.embedder != nil {
 		emb, err := s.embedder.GenerateEmbedding(ctx, content)
 		if err != nil {
 			log.Printf("Warning: failed to generate embedding for memory: %v", err)
 			// Continue without embedding - memory is still valuable
-		} else {
+		} else if len(emb) > 0 {
 			embedding = Vector(emb)
+			embeddingModel = sql.NullString{String: s.embedder.EmbeddingModel(), Valid: true}
+			embeddingDim = sql.NullInt64{Int64: int64(len(emb)), Valid: true}
 		}
 	}
 
@@ -85,82 +252,66 @@ func (s *Store) Create(ctx context.Context, content string, importance float64,
 	}
 
 	query := `
-		INSERT INTO memories (id, content, embedding, importance, tags, created_at, last_accessed, access_count)
-		VALUES (?, ?, ?, ?, ?, ?, ?, 0)
+		INSERT INTO memories (id, content, embedding, embedding_model, embedding_dim, importance, tags, created_at, last_accessed, access_count, expires_at, ` + sourceColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 0, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err = s.db.ExecContext(ctx, query, id, content, embedding, importance, string(tagsJSON), now, now)
+	args := []interface{}{id, content, embedding, embeddingModel, embeddingDim, importance, string(tagsJSON), now, now, expiryValue(opts.ExpiresAt)}
+	args = append(args, sourceValues(opts.Source)...)
+	_, err = s.db.ExecContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Memory{
-		ID:           id,
-		Content:      content,
-		Importance:   importance,
-		Tags:         tags,
-		CreatedAt:    now,
-		LastAccessed: now,
-		AccessCount:  0,
-	}, nil
+	m := &Memory{
+		ID:             id,
+		Content:        content,
+		Importance:     importance,
+		Tags:           tags,
+		CreatedAt:      now,
+		LastAccessed:   now,
+		AccessCount:    0,
+		EmbeddingModel: embeddingModel.String,
+		EmbeddingDim:   int(embeddingDim.Int64),
+		ExpiresAt:      opts.ExpiresAt,
+		Source:         opts.Source,
+	}
+	s.notifyCreated(m)
+
+	return m, nil
 }
 
 // List returns the most recent memories
 func (s *Store) List(ctx context.Context, limit int) ([]*Memory, error) {
 	query := `
-		SELECT id, content, importance, tags, created_at, last_accessed, access_count
+		SELECT ` + memoryColumns + `
 		FROM memories
+		WHERE ` + notExpired + `
 		ORDER BY created_at DESC
 		LIMIT ?
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, limit)
+	rows, err := s.db.QueryContext(ctx, query, nowExpiry(), limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var memories []*Memory
-	for rows.Next() {
-		m := &Memory{}
-		var tagsJSON string
-		var createdAtStr, lastAccessedStr string
-		if err := rows.Scan(&m.ID, &m.Content, &m.Importance, &tagsJSON, &createdAtStr, &lastAccessedStr, &m.AccessCount); err != nil {
-			return nil, err
-		}
-		m.CreatedAt = parseTimeString(createdAtStr)
-		m.LastAccessed = parseTimeString(lastAccessedStr)
-		if err := json.Unmarshal([]byte(tagsJSON), &m.Tags); err != nil {
-			m.Tags = []string{} // Default to empty if parse fails
-		}
-		memories = append(memories, m)
-	}
-
-	return memories, nil
+	return scanMemories(rows)
 }
 
 // Get retrieves a memory by ID
 func (s *Store) Get(ctx context.Context, id string) (*Memory, error) {
 	query := `
-		SELECT id, content, importance, tags, created_at, last_accessed, access_count
+		SELECT ` + memoryColumns + `
 		FROM memories
 		WHERE id = ?
 	`
 
-	m := &Memory{}
-	var tagsJSON string
-	var createdAtStr, lastAccessedStr string
-	err := s.db.QueryRowContext(ctx, query, id).Scan(
-		&m.ID, &m.Content, &m.Importance, &tagsJSON, &createdAtStr, &lastAccessedStr, &m.AccessCount,
-	)
+	m, err := scanMemory(s.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		return nil, err
 	}
-	m.CreatedAt = parseTimeString(createdAtStr)
-	m.LastAccessed = parseTimeString(lastAccessedStr)
-	if err := json.Unmarshal([]byte(tagsJSON), &m.Tags); err != nil {
-		m.Tags = []string{}
-	}
 
 	// Update access count and last accessed
 	go s.updateAccess(id)
@@ -174,12 +325,12 @@ func (s *Store) SearchRelevant(ctx context.Context, query string, limit int) ([]
 	sqlQuery := `
 		SELECT content, tags
 		FROM memories
-		WHERE content LIKE '%' || ? || '%'
+		WHERE content LIKE '%' || ? || '%' AND ` + notExpired + `
 		ORDER BY importance DESC, last_accessed DESC
 		LIMIT ?
 	`
 
-	rows, err := s.db.QueryContext(ctx, sqlQuery, query, limit*2) // Get more to filter by tags
+	rows, err := s.db.QueryContext(ctx, sqlQuery, query, nowExpiry(), limit*2) // Get more to filter by tags
 	if err != nil {
 		return nil, err
 	}
@@ -217,47 +368,69 @@ func (s *Store) SearchRelevant(ctx context.Context, query string, limit int) ([]
 	return contents, nil
 }
 
-// SearchByVector searches using vector similarity (in-memory cosine similarity)
+// SearchByVector searches using vector similarity (in-memory cosine similarity).
+// Only embeddings produced by the active embedding model with the same
+// dimension as the query are compared; vectors from other models are skipped.
 func (s *Store) SearchByVector(ctx context.Context, embedding []float32, limit int) ([]*Memory, error) {
-	// Fetch all memories with embeddings
+	results, err := s.scoreByVector(ctx, embedding)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return top N
+	var memories []*Memory
+	for i := 0; i < len(results) && i < limit; i++ {
+		memories = append(memories, results[i].memory)
+	}
+
+	return memories, nil
+}
+
+// scoredMemory is a memory with its similarity to a query vector
+type scoredMemory struct {
+	memory     *Memory
+	similarity float32
+}
+
+// scoreByVector returns unexpired memories in the query's vector space,
+// most similar first
+func (s *Store) scoreByVector(ctx context.Context, embedding []float32) ([]scoredMemory, error) {
+	if len(embedding) == 0 {
+		return nil, nil
+	}
+
+	// Fetch all memories with embeddings in the query's vector space
 	query := `
-		SELECT id, content, importance, tags, created_at, last_accessed, access_count, embedding
+		SELECT ` + memoryColumns + `, embedding
 		FROM memories
-		WHERE embedding IS NOT NULL
+		WHERE embedding IS NOT NULL AND embedding_model = ? AND embedding_dim = ? AND ` + notExpired + `
 	`
 
-	rows, err := s.db.QueryContext(ctx, query)
+	rows, err := s.db.QueryContext(ctx, query, s.activeModel(), len(embedding), nowExpiry())
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	type memoryWithScore struct {
-		memory     *Memory
-		similarity float32
-	}
-	var results []memoryWithScore
-
+	var results []scoredMemory
 	for rows.Next() {
-		m := &Memory{}
-		var tagsJSON string
 		var embeddingBlob []byte
-		var createdAtStr, lastAccessedStr string
-		if err := rows.Scan(&m.ID, &m.Content, &m.Importance, &tagsJSON, &createdAtStr, &lastAccessedStr, &m.AccessCount, &embeddingBlob); err != nil {
+		m, err := scanMemory(rows, &embeddingBlob)
+		if err != nil {
 			return nil, err
 		}
-		m.CreatedAt = parseTimeString(createdAtStr)
-		m.LastAccessed = parseTimeString(lastAccessedStr)
-		if err := json.Unmarshal([]byte(tagsJSON), &m.Tags); err != nil {
-			m.Tags = []string{}
-		}
 
 		// Convert blob to vector and compute similarity
 		memEmbedding := BlobToVector(embeddingBlob)
-		if memEmbedding != nil {
-			similarity := CosineSimilarity(embedding, memEmbedding)
-			results = append(results, memoryWithScore{memory: m, similarity: similarity})
+		if len(memEmbedding) != len(embedding) {
+			// Recorded dimension disagrees with the blob; never compare across spaces
+			continue
 		}
+		similarity := CosineSimilarity(embedding, memEmbedding)
+		results = append(results, scoredMemory{memory: m, similarity: similarity})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
 	// Sort by similarity descending
@@ -265,13 +438,56 @@ func (s *Store) SearchByVector(ctx context.Context, embedding []float32, limit i
 		return results[i].similarity > results[j].similarity
 	})
 
-	// Return top N
-	var memories []*Memory
-	for i := 0; i < len(results) && i < limit; i++ {
-		memories = append(memories, results[i].memory)
+	return results, nil
+}
+
+// FindDuplicate returns an existing memory that says the same thing as content:
+// either identical after normalising case and whitespace, or with an embedding
+// at least threshold similar. Returns nil when there is no duplicate.
+func (s *Store) FindDuplicate(ctx context.Context, content string, threshold float32) (*Memory, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+memoryColumns+`
+		FROM memories
+		WHERE `+notExpired, nowExpiry())
+	if err != nil {
+		return nil, err
+	}
+	memories, err := scanMemories(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
 	}
 
-	return memories, nil
+	key := normalizeContent(content)
+	for _, m := range memories {
+		if normalizeContent(m.Content) == key {
+			return m, nil
+		}
+	}
+
+	if s.embedder == nil {
+		return nil, nil
+	}
+	embedding, err := s.embedder.GenerateEmbedding(ctx, content)
+	if err != nil {
+		return nil, err
+	}
+	results, err := s.scoreByVector(ctx, embedding)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) > 0 && results[0].similarity >= threshold {
+		return results[0].memory, nil
+	}
+	return nil, nil
+}
+
+// activeModel returns the embedding model of the configured embedder
+func (s *Store) activeModel() string {
+	if s.embedder == nil {
+		return ""
+	}
+	return s.embedder.EmbeddingModel()
 }
 
 // Delete removes a memory
@@ -280,6 +496,16 @@ func (s *Store) Delete(ctx context.Context, id string) error {
 	return err
 }
 
+// PurgeExpired deletes memories whose expiry has passed and returns how many were removed
+func (s *Store) PurgeExpired(ctx context.Context) (int64, error) {
+	result, err := s.db.ExecContext(ctx,
+		"DELETE FROM memories WHERE expires_at IS NOT NULL AND expires_at <= ?", nowExpiry())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 // updateAccess updates the access statistics for a memory
 func (s *Store) updateAccess(id string) {
 	ctx := context.Background()
@@ -293,42 +519,30 @@ func (s *Store) updateAccess(id string) {
 // GetTopImportant returns the most important memories
 func (s *Store) GetTopImportant(ctx context.Context, limit int) ([]*Memory, error) {
 	query := `
-		SELECT id, content, importance, tags, created_at, last_accessed, access_count
+		SELECT ` + memoryColumns + `
 		FROM memories
+		WHERE ` + notExpired + `
 		ORDER BY importance DESC, access_count DESC
 		LIMIT ?
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, limit)
+	rows, err := s.db.QueryContext(ctx, query, nowExpiry(), limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var memories []*Memory
-	for rows.Next() {
-		m := &Memory{}
-		var tagsJSON string
-		var createdAtStr, lastAccessedStr string
-		if err := rows.Scan(&m.ID, &m.Content, &m.Importance, &tagsJSON, &createdAtStr, &lastAccessedStr, &m.AccessCount); err != nil {
-			return nil, err
-		}
-		m.CreatedAt = parseTimeString(createdAtStr)
-		m.LastAccessed = parseTimeString(lastAccessedStr)
-		if err := json.Unmarshal([]byte(tagsJSON), &m.Tags); err != nil {
-			m.Tags = []string{}
-		}
-		memories = append(memories, m)
-	}
-
-	return memories, nil
+	return scanMemories(rows)
 }
 
-// UpdateEmbedding sets the embedding for an existing memory.
-// Used for backfilling embeddings on memories that were created before embedding support.
-func (s *Store) UpdateEmbedding(ctx context.Context, id string, embedding []float32) error {
+// UpdateEmbedding sets the embedding for an existing memory and records the
+// model that produced it.
+// Used for backfilling and re-embedding memories after a model change.
+func (s *Store) UpdateEmbedding(ctx context.Context, id string, embedding []float32, model string) error {
 	vec := Vector(embedding)
-	_, err := s.db.ExecContext(ctx, "UPDATE memories SET embedding = ? WHERE id = ?", vec, id)
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE memories SET embedding = ?, embedding_model = ?, embedding_dim = ? WHERE id = ?",
+		vec, model, len(embedding), id)
 	return err
 }
 
@@ -336,7 +550,7 @@ func (s *Store) UpdateEmbedding(ctx context.Context, id string, embedding []floa
 // Used for backfilling embeddings.
 func (s *Store) GetWithoutEmbedding(ctx context.Context, limit int) ([]*Memory, error) {
 	query := `
-		SELECT id, content, importance, tags, created_at, last_accessed, access_count
+		SELECT ` + memoryColumns + `
 		FROM memories
 		WHERE embedding IS NULL
 		ORDER BY importance DESC, created_at DESC
@@ -349,21 +563,53 @@ func (s *Store) GetWithoutEmbedding(ctx context.Context, limit int) ([]*Memory,
 	}
 	defer rows.Close()
 
-	var memories []*Memory
+	return scanMemories(rows)
+}
+
+// GetNeedingEmbedding returns memories whose embedding is missing or was
+// produced by a model other than the given one, ordered by ID.
+// Results start after afterID so callers can page through the table and
+// skip rows that failed without looping on them.
+func (s *Store) GetNeedingEmbedding(ctx context.Context, model, afterID string, limit int) ([]*Memory, error) {
+	query := `
+		SELECT ` + memoryColumns + `
+		FROM memories
+		WHERE (embedding IS NULL OR embedding_model IS NULL OR embedding_model != ?)
+		  AND id > ?
+		ORDER BY id
+		LIMIT ?
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, model, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanMemories(rows)
+}
+
+// EmbeddingStats counts memories per embedding model.
+// Memories without an embedding are reported under the empty model name.
+func (s *Store) EmbeddingStats(ctx context.Context) (map[string]int, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT CASE WHEN embedding IS NULL THEN '' ELSE COALESCE(embedding_model, 'unknown') END AS model, COUNT(*)
+		FROM memories
+		GROUP BY model
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := make(map[string]int)
 	for rows.Next() {
-		m := &Memory{}
-		var tagsJSON string
-		var createdAtStr, lastAccessedStr string
-		if err := rows.Scan(&m.ID, &m.Content, &m.Importance, &tagsJSON, &createdAtStr, &lastAccessedStr, &m.AccessCount); err != nil {
+		var model string
+		var count int
+		if err := rows.Scan(&model, &count); err != nil {
 			return nil, err
 		}
-		m.CreatedAt = parseTimeString(createdAtStr)
-		m.LastAccessed = parseTimeString(lastAccessedStr)
-		if err := json.Unmarshal([]byte(tagsJSON), &m.Tags); err != nil {
-			m.Tags = []string{}
-		}
-		memories = append(memories, m)
+		stats[model] = count
 	}
-
-	return memories, nil
+	return stats, rows.Err()
 }
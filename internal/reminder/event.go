@@ -0,0 +1,78 @@
+package reminder
+
+import (
+	"context"
+	"time"
+)
+
+// EventAnchor ties a reminder to a calendar event, Offset before its start
+type EventAnchor struct {
+	EventID string
+	Offset  time.Duration
+}
+
+// RemindAt returns the reminder time for an event starting at start
+func (a *EventAnchor) RemindAt(start time.Time) time.Time {
+	return start.Add(-a.Offset)
+}
+
+// ListByEvent returns the active reminders anchored to a calendar event
+func (s *Store) ListByEvent(ctx context.Context, eventID string) ([]*Reminder, error) {
+	query := `
+		SELECT ` + reminderColumns + `
+		FROM reminders
+		WHERE event_id = ? AND completed = 0
+		ORDER BY remind_at ASC
+	`
+
+	return s.queryReminders(ctx, query, eventID)
+}
+
+// FollowEvent moves the reminders anchored to an event after the event
+// moved to start, keeping their offsets. Returns the reminders that moved.
+func (s *Store) FollowEvent(ctx context.Context, eventID string, start time.Time) ([]*Reminder, error) {
+	reminders, err := s.ListByEvent(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	var moved []*Reminder
+	for _, r := range reminders {
+		remindAt := start.Add(-r.EventOffset)
+		if remindAt.Equal(r.RemindAt) {
+			continue
+		}
+		updated, err := s.edit(ctx, r.ID, Changes{RemindAt: &remindAt}, true)
+		if err != nil {
+			return moved, err
+		}
+		moved = append(moved, updated)
+	}
+	return moved, nil
+}
+
+// FlagEventDeleted marks the reminders anchored to a deleted event. They
+// stay scheduled at their last time so the user can decide what to do with
+// them. Returns the flagged reminders.
+func (s *Store) FlagEventDeleted(ctx context.Context, eventID string) ([]*Reminder, error) {
+	reminders, err := s.ListByEvent(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	var flagged []*Reminder
+	for _, r := range reminders {
+		if r.EventDeleted {
+			continue
+		}
+		_, err := s.db.ExecContext(ctx,
+			"UPDATE reminders SET event_deleted = 1, updated_at = ? WHERE id = ?",
+			time.Now().Format(time.RFC3339), r.ID)
+		if err != nil {
+			return flagged, err
+		}
+		r.EventDeleted = true
+		flagged = append(flagged, r)
+	}
+	return flagged, nil
+}
@@ -0,0 +1,143 @@
+package reminder
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Priorities
+const (
+	PriorityLow    = "low"
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+)
+
+// ParsePriority validates a priority name; empty means normal
+func ParsePriority(s string) (string, error) {
+	switch p := strings.ToLower(strings.TrimSpace(s)); p {
+	case "":
+		return PriorityNormal, nil
+	case PriorityLow, PriorityNormal, PriorityHigh:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid priority %q, use low, normal or high", s)
+	}
+}
+
+// Due windows for Filter.Due
+const (
+	DueToday   = "today"
+	DueWeek    = "week"
+	DueOverdue = "overdue"
+)
+
+// Filter selects reminders in Store.Query. Empty fields match everything.
+type Filter struct {
+	IncludeCompleted bool
+	// Due is DueToday, DueWeek (Monday to Sunday) or DueOverdue
+	Due string
+	// List matches the reminder's list case-insensitively, e.g. "work"
+	List     string
+	Priority string
+	Tag      string
+}
+
+// Validate checks the due window and priority of a filter
+func (f Filter) Validate() error {
+	switch f.Due {
+	case "", DueToday, DueWeek, DueOverdue:
+	default:
+		return fmt.Errorf("invalid due filter %q, use today, week or overdue", f.Due)
+	}
+	if f.Priority != "" {
+		if _, err := ParsePriority(f.Priority); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Query returns the reminders matching a filter, soonest first
+func (s *Store) Query(ctx context.Context, f Filter) ([]*Reminder, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+
+	var conditions []string
+	var args []interface{}
+	if !f.IncludeCompleted || f.Due == DueOverdue {
+		conditions = append(conditions, "completed = 0")
+	}
+	if f.List != "" {
+		conditions = append(conditions, "LOWER(list) = LOWER(?)")
+		args = append(args, strings.TrimSpace(f.List))
+	}
+	if f.Priority != "" {
+		priority, _ := ParsePriority(f.Priority)
+		conditions = append(conditions, "priority = ?")
+		args = append(args, priority)
+	}
+	if f.Tag != "" {
+		conditions = append(conditions,
+			"EXISTS (SELECT 1 FROM json_each(reminders.tags) WHERE LOWER(json_each.value) = LOWER(?))")
+		args = append(args, strings.TrimSpace(f.Tag))
+	}
+
+	// remind_at keeps the offset it was given with, so due windows compare
+	// times normalised to UTC rather than the stored strings
+	now := time.Now()
+	if f.Due == DueOverdue {
+		conditions = append(conditions, "julianday(remind_at) < julianday(?)")
+		args = append(args, now.UTC().Format(time.RFC3339Nano))
+	}
+	if from, to := dueWindow(f.Due, now); !from.IsZero() {
+		conditions = append(conditions, "julianday(remind_at) >= julianday(?) AND julianday(remind_at) < julianday(?)")
+		args = append(args, from.UTC().Format(time.RFC3339), to.UTC().Format(time.RFC3339))
+	}
+
+	query := `
+		SELECT ` + reminderColumns + `
+		FROM reminders
+	`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY julianday(remind_at) ASC"
+
+	reminders, err := s.queryReminders(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if reminders == nil {
+		reminders = []*Reminder{}
+	}
+	return reminders, nil
+}
+
+// dueWindow returns the local time range of a due filter, or zero times when
+// it has none
+func dueWindow(due string, now time.Time) (from, to time.Time) {
+	now = now.Local()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch due {
+	case DueToday:
+		return today, today.AddDate(0, 0, 1)
+	case DueWeek:
+		monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+		return monday, monday.AddDate(0, 0, 7)
+	}
+	return time.Time{}, time.Time{}
+}
+
+// normalizeTags trims tags and drops empty ones
+func normalizeTags(tags []string) []string {
+	result := []string{}
+	for _, t := range tags {
+		if t = strings.TrimSpace(t); t != "" {
+			result = append(result, t)
+		}
+	}
+	return result
+}
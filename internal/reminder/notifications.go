@@ -0,0 +1,239 @@
+package reminder
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Notification states
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	// StatusSkipped marks offsets that were already past when the reminder was scheduled
+	StatusSkipped = "skipped"
+	// StatusMissed marks notifications that passed while the scheduler was not
+	// running and were announced late in a single catch-up trigger
+	StatusMissed = "missed"
+)
+
+// AtTime is the name of the notification sent when the reminder is due
+const AtTime = "at_time"
+
+// DefaultOffsets is the notification profile used when none is configured
+var DefaultOffsets = []time.Duration{
+	24 * time.Hour,
+	12 * time.Hour,
+	3 * time.Hour,
+	1 * time.Hour,
+	10 * time.Minute,
+	0,
+}
+
+// Notification is one notification of a reminder, sent Offset before it is due
+type Notification struct {
+	Offset time.Duration `json:"-"`
+	Name   string        `json:"offset"` // e.g. "1h", "10m" or "at_time"
+	Status string        `json:"status"`
+	SentAt *time.Time    `json:"sent_at,omitempty"`
+}
+
+// ParseOffsets parses a list of notification offsets such as
+// "24h,1h,10m,0" or "3d, at_time". The at-time notification is always
+// included and the result is sorted from earliest to latest.
+func ParseOffsets(s string) ([]time.Duration, error) {
+	return ParseOffsetList(strings.Split(s, ","))
+}
+
+// ParseOffsetList parses offsets given as separate strings
+func ParseOffsetList(values []string) ([]time.Duration, error) {
+	var offsets []time.Duration
+	for _, v := range values {
+		v = strings.TrimSpace(strings.ToLower(v))
+		if v == "" {
+			continue
+		}
+		d, err := parseOffset(v)
+		if err != nil {
+			return nil, err
+		}
+		offsets = append(offsets, d)
+	}
+	return normalizeOffsets(offsets), nil
+}
+
+// ParseOffset parses a single offset such as "1h", "3d" or "at_time"
+func ParseOffset(s string) (time.Duration, error) {
+	return parseOffset(strings.TrimSpace(strings.ToLower(s)))
+}
+
+// parseOffset parses a single offset. Days ("3d") are accepted in addition
+// to Go durations.
+func parseOffset(v string) (time.Duration, error) {
+	switch v {
+	case "0", AtTime, "at time":
+		return 0, nil
+	}
+	if strings.HasSuffix(v, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(v, "d"))
+		if err != nil || days < 0 {
+			return 0, fmt.Errorf("invalid notification offset %q", v)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid notification offset %q", v)
+	}
+	return d.Truncate(time.Second), nil
+}
+
+// normalizeOffsets removes duplicates, adds the at-time notification and
+// sorts from earliest (largest offset) to latest
+func normalizeOffsets(offsets []time.Duration) []time.Duration {
+	seen := map[time.Duration]bool{0: true}
+	result := []time.Duration{0}
+	for _, d := range offsets {
+		if !seen[d] {
+			seen[d] = true
+			result = append(result, d)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] > result[j] })
+	return result
+}
+
+// FormatOffset returns the name of an offset, e.g. "3d", "1h30m" or "at_time"
+func FormatOffset(d time.Duration) string {
+	if d <= 0 {
+		return AtTime
+	}
+	if d >= 48*time.Hour && d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	name := d.String()
+	name = strings.TrimSuffix(name, "0s")
+	if strings.HasSuffix(name, "h0m") {
+		name = strings.TrimSuffix(name, "0m")
+	}
+	return name
+}
+
+// offsetsOf returns the offsets of a reminder's notifications, or the
+// default schedule if it has none
+func (s *Store) offsetsOf(r *Reminder) []time.Duration {
+	if len(r.Notifications) == 0 {
+		return s.defaultOffsets
+	}
+	offsets := make([]time.Duration, len(r.Notifications))
+	for i, n := range r.Notifications {
+		offsets[i] = n.Offset
+	}
+	return offsets
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// scheduleNotifications replaces a reminder's notifications with the given
+// offsets, skipping those whose time has already passed
+func scheduleNotifications(ctx context.Context, db execer, id string, remindAt time.Time, offsets []time.Duration) ([]*Notification, error) {
+	if _, err := db.ExecContext(ctx, "DELETE FROM reminder_notifications WHERE reminder_id = ?", id); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	notifications := make([]*Notification, 0, len(offsets))
+	for _, offset := range normalizeOffsets(offsets) {
+		status := StatusPending
+		if offset > 0 && !remindAt.Add(-offset).After(now) {
+			status = StatusSkipped
+		}
+		_, err := db.ExecContext(ctx,
+			"INSERT INTO reminder_notifications (reminder_id, offset_seconds, status) VALUES (?, ?, ?)",
+			id, int64(offset/time.Second), status)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, &Notification{Offset: offset, Name: FormatOffset(offset), Status: status})
+	}
+	return notifications, nil
+}
+
+// loadNotifications attaches notifications to the given reminders
+func (s *Store) loadNotifications(ctx context.Context, reminders []*Reminder) error {
+	if len(reminders) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*Reminder, len(reminders))
+	args := make([]interface{}, len(reminders))
+	for i, r := range reminders {
+		byID[r.ID] = r
+		args[i] = r.ID
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT reminder_id, offset_seconds, status, sent_at
+		FROM reminder_notifications
+		WHERE reminder_id IN (?`+strings.Repeat(", ?", len(reminders)-1)+`)
+		ORDER BY offset_seconds DESC
+	`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, status string
+		var seconds int64
+		var sentAt sql.NullString
+		if err := rows.Scan(&id, &seconds, &status, &sentAt); err != nil {
+			return err
+		}
+		n := &Notification{
+			Offset: time.Duration(seconds) * time.Second,
+			Status: status,
+		}
+		n.Name = FormatOffset(n.Offset)
+		if sentAt.Valid {
+			t := parseTime(sentAt.String)
+			n.SentAt = &t
+		}
+		if r := byID[id]; r != nil {
+			r.Notifications = append(r.Notifications, n)
+		}
+	}
+	return rows.Err()
+}
+
+// MarkMissed marks the notifications at the given offsets as missed,
+// announced late at the given time
+func (s *Store) MarkMissed(ctx context.Context, id string, offsets []time.Duration, at time.Time) error {
+	if len(offsets) == 0 {
+		return nil
+	}
+	args := []interface{}{StatusMissed, at.Format(time.RFC3339), id}
+	for _, offset := range offsets {
+		args = append(args, int64(offset/time.Second))
+	}
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE reminder_notifications SET status = ?, sent_at = ?
+		WHERE reminder_id = ? AND offset_seconds IN (?`+strings.Repeat(", ?", len(offsets)-1)+`)
+	`, args...)
+	return err
+}
+
+// MarkNotified marks the notification at the given offset as sent at the given time
+func (s *Store) MarkNotified(ctx context.Context, id string, offset time.Duration, at time.Time) error {
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE reminder_notifications SET status = ?, sent_at = ? WHERE reminder_id = ? AND offset_seconds = ?",
+		StatusSent, at.Format(time.RFC3339), id, int64(offset/time.Second))
+	return err
+}
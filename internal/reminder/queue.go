@@ -0,0 +1,90 @@
+package reminder
+
+import (
+	"container/heap"
+	"time"
+)
+
+// queueEntry is the next time a reminder needs attention from the scheduler
+type queueEntry struct {
+	id    string
+	at    time.Time
+	index int
+}
+
+// fireQueue is a min-heap of reminders ordered by their next fire time,
+// indexed by reminder ID so entries can be moved or removed
+type fireQueue struct {
+	entries []*queueEntry
+	byID    map[string]*queueEntry
+}
+
+func newFireQueue() *fireQueue {
+	return &fireQueue{byID: make(map[string]*queueEntry)}
+}
+
+// heap.Interface
+func (q *fireQueue) Len() int           { return len(q.entries) }
+func (q *fireQueue) Less(i, j int) bool { return q.entries[i].at.Before(q.entries[j].at) }
+func (q *fireQueue) Swap(i, j int) {
+	q.entries[i], q.entries[j] = q.entries[j], q.entries[i]
+	q.entries[i].index = i
+	q.entries[j].index = j
+}
+func (q *fireQueue) Push(x interface{}) {
+	e := x.(*queueEntry)
+	e.index = len(q.entries)
+	q.entries = append(q.entries, e)
+}
+func (q *fireQueue) Pop() interface{} {
+	old := q.entries
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	q.entries = old[:len(old)-1]
+	return e
+}
+
+// set schedules a reminder at the given time, replacing any earlier entry
+func (q *fireQueue) set(id string, at time.Time) {
+	if e, ok := q.byID[id]; ok {
+		e.at = at
+		heap.Fix(q, e.index)
+		return
+	}
+	e := &queueEntry{id: id, at: at}
+	q.byID[id] = e
+	heap.Push(q, e)
+}
+
+// remove drops a reminder from the queue
+func (q *fireQueue) remove(id string) {
+	if e, ok := q.byID[id]; ok {
+		heap.Remove(q, e.index)
+		delete(q.byID, id)
+	}
+}
+
+// peek returns the earliest entry, or nil if the queue is empty
+func (q *fireQueue) peek() *queueEntry {
+	if len(q.entries) == 0 {
+		return nil
+	}
+	return q.entries[0]
+}
+
+// popDue removes and returns the IDs of all reminders due at or before now
+func (q *fireQueue) popDue(now time.Time) []string {
+	var ids []string
+	for len(q.entries) > 0 && !q.entries[0].at.After(now) {
+		e := heap.Pop(q).(*queueEntry)
+		delete(q.byID, e.id)
+		ids = append(ids, e.id)
+	}
+	return ids
+}
+
+// reset empties the queue
+func (q *fireQueue) reset() {
+	q.entries = nil
+	q.byID = make(map[string]*queueEntry)
+}
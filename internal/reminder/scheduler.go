@@ -2,46 +2,71 @@ package reminder
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"log"
 	"sync"
 	"time"
 )
 
-// NotificationTier represents a notification time before the reminder
-type NotificationTier struct {
-	Name     string
-	Duration time.Duration
-	Field    string
-}
+// ReminderCallback is called when a reminder notification should be sent.
+// tier is the notification's offset name, e.g. "1h" or "at_time", or
+// TierSnooze or TierNag for re-announcements.
+type ReminderCallback func(reminder *Reminder, tier string, timeUntil time.Duration)
+
+// TierMissed collapses the notifications of a reminder that passed while
+// PIKA was closed or the computer was asleep into a single announcement
+const TierMissed = "missed"
+
+// missedGrace is how late a notification may still be sent normally before
+// it counts as missed
+const missedGrace = 2 * time.Minute
 
-// Standard notification tiers
-var NotificationTiers = []NotificationTier{
-	{"24h", 24 * time.Hour, "notified_24h"},
-	{"12h", 12 * time.Hour, "notified_12h"},
-	{"3h", 3 * time.Hour, "notified_3h"},
-	{"1h", 1 * time.Hour, "notified_1h"},
-	{"10m", 10 * time.Minute, "notified_10m"},
-	{"at_time", 0, "notified_at_time"},
+const (
+	// maxWait bounds how long the scheduler sleeps between wakeups. Timers
+	// run on monotonic time, which stops while the computer sleeps, so the
+	// wall clock is rechecked at least this often.
+	maxWait = 30 * time.Second
+	// retryDelay postpones a reminder that is still due after it was
+	// processed, e.g. because the database could not be updated
+	retryDelay = 10 * time.Second
+)
+
+// Clock provides the current time and timers to the scheduler. Tests can
+// supply a fake clock to control time.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
 }
 
-// ReminderCallback is called when a reminder notification should be sent
-type ReminderCallback func(reminder *Reminder, tier string, timeUntil time.Duration)
+// realClock is the system clock
+type realClock struct{}
 
-// Scheduler checks for upcoming reminders and triggers notifications
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Scheduler sends reminder notifications when they are due. It keeps a
+// min-heap of the next fire time of every reminder, built from the store on
+// start and updated through the store's change hook.
 type Scheduler struct {
-	store    *Store
-	callback ReminderCallback
-	ticker   *time.Ticker
-	stop     chan struct{}
-	mu       sync.RWMutex
-	running  bool
+	store     *Store
+	callback  ReminderCallback
+	clock     Clock
+	queue     *fireQueue
+	wake      chan struct{}
+	stop      chan struct{}
+	mu        sync.RWMutex
+	running   bool
+	lastCheck time.Time
 }
 
 // NewScheduler creates a new reminder scheduler
 func NewScheduler(store *Store) *Scheduler {
 	return &Scheduler{
 		store: store,
+		clock: realClock{},
+		queue: newFireQueue(),
+		wake:  make(chan struct{}, 1),
 		stop:  make(chan struct{}),
 	}
 }
@@ -53,7 +78,14 @@ func (s *Scheduler) SetCallback(cb ReminderCallback) {
 	s.callback = cb
 }
 
-// Start begins the scheduler, checking every minute
+// SetClock replaces the system clock, for tests. Call before Start.
+func (s *Scheduler) SetClock(c Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+// Start loads pending reminders and begins sending their notifications
 func (s *Scheduler) Start() {
 	s.mu.Lock()
 	if s.running {
@@ -61,23 +93,14 @@ func (s *Scheduler) Start() {
 		return
 	}
 	s.running = true
-	s.ticker = time.NewTicker(1 * time.Minute)
 	s.mu.Unlock()
 
 	log.Println("Reminder scheduler started")
 
-	// Run immediately on start
-	go s.checkReminders()
-
 	go func() {
-		for {
-			select {
-			case <-s.ticker.C:
-				s.checkReminders()
-			case <-s.stop:
-				return
-			}
-		}
+		// Reminders missed while PIKA was closed are due immediately
+		s.rebuild()
+		s.run()
 	}()
 }
 
@@ -91,90 +114,315 @@ func (s *Scheduler) Stop() {
 	}
 
 	s.running = false
-	if s.ticker != nil {
-		s.ticker.Stop()
-	}
 	close(s.stop)
 	log.Println("Reminder scheduler stopped")
 }
 
-// checkReminders checks all pending reminders and sends notifications
-func (s *Scheduler) checkReminders() {
-	s.mu.RLock()
-	callback := s.callback
-	s.mu.RUnlock()
-
-	if callback == nil {
+// Reschedule recomputes the next fire time of a reminder after it was
+// created, changed or deleted. It is installed as the store's change hook.
+func (s *Scheduler) Reschedule(id string) {
+	r, err := s.store.Get(context.Background(), id)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Error loading reminder %s for scheduling: %v", id, err)
 		return
 	}
 
-	ctx := context.Background()
-	reminders, err := s.store.GetPendingReminders(ctx)
+	s.mu.Lock()
+	if err == nil {
+		s.schedule(r)
+	} else {
+		s.queue.remove(id)
+	}
+	s.mu.Unlock()
+
+	// Let the run loop pick up the new earliest time
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// rebuild fills the queue from the store
+func (s *Scheduler) rebuild() {
+	reminders, err := s.store.GetPendingReminders(context.Background())
 	if err != nil {
 		log.Printf("Error fetching pending reminders: %v", err)
 		return
 	}
 
-	now := time.Now()
-
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue.reset()
 	for _, r := range reminders {
-		timeUntil := r.RemindAt.Sub(now)
+		s.schedule(r)
+	}
+	log.Printf("Scheduled %d pending reminders", s.queue.Len())
+}
+
+// schedule queues a reminder at its next fire time, or drops it if nothing
+// is left to send. Callers hold s.mu.
+func (s *Scheduler) schedule(r *Reminder) {
+	if at, ok := nextFire(r); ok {
+		s.queue.set(r.ID, at)
+	} else {
+		s.queue.remove(r.ID)
+	}
+}
+
+// run sleeps until the earliest queued reminder is due and processes it
+func (s *Scheduler) run() {
+	for {
+		s.mu.RLock()
+		clock := s.clock
+		wait := maxWait
+		if e := s.queue.peek(); e != nil {
+			if d := e.at.Sub(clock.Now()); d < wait {
+				wait = d
+			}
+		}
+		s.mu.RUnlock()
+
+		if wait > 0 {
+			select {
+			case <-clock.After(wait):
+			case <-s.wake:
+				continue
+			case <-s.stop:
+				return
+			}
+		}
+
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+		s.fireDue()
+	}
+}
+
+// fireDue processes every reminder whose fire time has passed
+func (s *Scheduler) fireDue() {
+	s.mu.Lock()
+	now := s.clock.Now()
+	lastCheck := s.lastCheck
+	s.lastCheck = now
+	s.mu.Unlock()
+
+	// After a sleep or clock change the queued times may be stale; reload
+	// them so everything that came due meanwhile is caught up on below
+	if !lastCheck.IsZero() {
+		if jump := clockJump(lastCheck, now); jump != 0 {
+			log.Printf("Wall clock jumped by %v since the last check, catching up on missed reminders", jump)
+			s.rebuild()
+		}
+	}
+
+	s.mu.Lock()
+	callback := s.callback
+	ids := s.queue.popDue(now)
+	s.mu.Unlock()
+
+	ctx := context.Background()
+	for _, id := range ids {
+		r, err := s.store.Get(ctx, id)
+		if err != nil {
+			if err != sql.ErrNoRows {
+				log.Printf("Error loading reminder %s: %v", id, err)
+			}
+			continue
+		}
+
+		if callback != nil {
+			s.process(ctx, callback, r, now)
+		}
+
+		// Queue the reminder's next fire time from its updated state
+		r, err = s.store.Get(ctx, id)
+		if err != nil {
+			continue
+		}
+		s.mu.Lock()
+		if at, ok := nextFire(r); ok {
+			if !at.After(now) {
+				at = now.Add(retryDelay)
+			}
+			s.queue.set(r.ID, at)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// process sends whatever notifications of a reminder are due at now
+func (s *Scheduler) process(ctx context.Context, callback ReminderCallback, r *Reminder, now time.Time) {
+	timeUntil := r.RemindAt.Sub(now)
+
+	// A snooze that has ended re-announces the reminder once it is due or
+	// was announced before; an earlier snooze just lets the offsets resume
+	if r.SnoozedUntil != nil && !now.Before(*r.SnoozedUntil) {
+		if !now.Before(r.RemindAt) || r.LastAnnouncedAt != nil {
+			log.Printf("Snooze ended for reminder: %s", r.Title)
+			callback(r, TierSnooze, timeUntil)
+			if err := s.store.MarkAnnounced(ctx, r.ID, now); err != nil {
+				log.Printf("Error marking reminder as announced: %v", err)
+			}
+			return
+		}
+		if err := s.store.EndSnooze(ctx, r.ID); err != nil {
+			log.Printf("Error ending snooze: %v", err)
+		}
+		r.SnoozedUntil = nil
+	}
 
-		// Check each notification tier
-		for _, tier := range NotificationTiers {
-			if s.shouldNotify(r, tier, timeUntil) {
-				log.Printf("Sending %s reminder for: %s", tier.Name, r.Title)
+	// Notifications that passed unsent are announced once, together
+	if missed := missedNotifications(r, now); len(missed) > 0 {
+		s.catchUp(ctx, callback, r, missed, now)
+		return
+	}
+
+	// Check each notification of the reminder
+	for _, n := range r.Notifications {
+		if s.shouldNotify(n, timeUntil) {
+			log.Printf("Sending %s reminder for: %s", n.Name, r.Title)
 
-				// Send notification
-				callback(r, tier.Name, timeUntil)
+			// Send notification
+			callback(r, n.Name, timeUntil)
 
-				// Mark as notified
-				if err := s.store.MarkNotified(ctx, r.ID, tier.Name); err != nil {
-					log.Printf("Error marking reminder as notified: %v", err)
+			// Mark as notified
+			if err := s.store.MarkNotified(ctx, r.ID, n.Offset, now); err != nil {
+				log.Printf("Error marking reminder as notified: %v", err)
+			}
+
+			// A passed occurrence of a recurring reminder rolls to the next one,
+			// unless it nags until acknowledged
+			if n.Offset == 0 && r.Recurrence != "" && r.NagMinutes == 0 {
+				if next, ok, err := s.store.Advance(ctx, r, now); err != nil {
+					log.Printf("Error advancing recurring reminder: %v", err)
+				} else if ok {
+					log.Printf("Recurring reminder %s rolled to %s", r.Title, next.RemindAt.Format(time.RFC3339))
 				}
+				return
 			}
 		}
 	}
+
+	if nagDue(r, now) {
+		log.Printf("Nagging about unacknowledged reminder: %s", r.Title)
+		callback(r, TierNag, timeUntil)
+		if err := s.store.MarkAnnounced(ctx, r.ID, now); err != nil {
+			log.Printf("Error marking reminder as announced: %v", err)
+		}
+	}
 }
 
-// shouldNotify determines if a notification should be sent for a tier
-func (s *Scheduler) shouldNotify(r *Reminder, tier NotificationTier, timeUntil time.Duration) bool {
-	// Already notified for this tier?
-	switch tier.Name {
-	case "24h":
-		if r.Notified24h {
-			return false
+// nextFire returns the earliest time a reminder needs attention: a pending
+// notification, the end of a snooze, or the next nag
+func nextFire(r *Reminder) (time.Time, bool) {
+	if r.Completed {
+		return time.Time{}, false
+	}
+
+	var next time.Time
+	consider := func(t time.Time) {
+		if next.IsZero() || t.Before(next) {
+			next = t
 		}
-	case "12h":
-		if r.Notified12h {
-			return false
+	}
+	for _, n := range r.Notifications {
+		if n.Status == StatusPending {
+			consider(r.RemindAt.Add(-n.Offset))
 		}
-	case "3h":
-		if r.Notified3h {
-			return false
+	}
+	if r.SnoozedUntil != nil {
+		consider(*r.SnoozedUntil)
+	} else if at, ok := nagAt(r); ok {
+		consider(at)
+	}
+	return next, !next.IsZero()
+}
+
+// catchUp sends a single announcement for the missed notifications of a
+// reminder and marks them as missed
+func (s *Scheduler) catchUp(ctx context.Context, callback ReminderCallback, r *Reminder, missed []*Notification, now time.Time) {
+	names := make([]string, len(missed))
+	offsets := make([]time.Duration, len(missed))
+	for i, n := range missed {
+		names[i] = n.Name
+		offsets[i] = n.Offset
+	}
+	log.Printf("Sending missed reminder for: %s (missed %v)", r.Title, names)
+
+	timeUntil := r.RemindAt.Sub(now)
+	callback(r, TierMissed, timeUntil)
+
+	if err := s.store.MarkMissed(ctx, r.ID, offsets, now); err != nil {
+		log.Printf("Error marking reminder notifications as missed: %v", err)
+	}
+
+	// A missed occurrence of a recurring reminder rolls to the next one,
+	// unless it nags until acknowledged
+	if timeUntil <= 0 && r.Recurrence != "" && r.NagMinutes == 0 {
+		if next, ok, err := s.store.Advance(ctx, r, now); err != nil {
+			log.Printf("Error advancing recurring reminder: %v", err)
+		} else if ok {
+			log.Printf("Recurring reminder %s rolled to %s", r.Title, next.RemindAt.Format(time.RFC3339))
 		}
-	case "1h":
-		if r.Notified1h {
-			return false
+	}
+}
+
+// missedNotifications returns the pending notifications of a reminder whose
+// window passed without them being sent. When any were missed, those that
+// are due now are included so the reminder is announced only once.
+func missedNotifications(r *Reminder, now time.Time) []*Notification {
+	var due []*Notification
+	missed := false
+	for _, n := range r.Notifications {
+		if n.Status != StatusPending {
+			continue
 		}
-	case "10m":
-		if r.Notified10m {
-			return false
+		fireAt := r.RemindAt.Add(-n.Offset)
+		if fireAt.After(now) {
+			continue
 		}
-	case "at_time":
-		if r.NotifiedAtTime {
-			return false
+		due = append(due, n)
+		if now.Sub(fireAt) >= missedGrace {
+			missed = true
 		}
 	}
+	if !missed {
+		return nil
+	}
+	return due
+}
+
+// clockJump returns how far the wall clock moved beyond the elapsed
+// monotonic time between two readings of time.Now, or 0 when the
+// difference is within a minute. Monotonic time stops while the computer
+// sleeps and ignores clock changes, so both show up as a jump.
+func clockJump(last, now time.Time) time.Duration {
+	jump := now.Round(0).Sub(last.Round(0)) - now.Sub(last)
+	if jump > -time.Minute && jump < time.Minute {
+		return 0
+	}
+	return jump
+}
+
+// shouldNotify determines if a notification should be sent
+func (s *Scheduler) shouldNotify(n *Notification, timeUntil time.Duration) bool {
+	// Already notified or skipped?
+	if n.Status != StatusPending {
+		return false
+	}
 
-	// For "at_time", trigger when the time has arrived or passed (within 1 minute window)
-	if tier.Name == "at_time" {
-		return timeUntil <= 1*time.Minute
+	// For the at-time notification, trigger up to a minute early. Later than
+	// the grace window it has been missed and is handled by catchUp.
+	if n.Offset == 0 {
+		return timeUntil <= 1*time.Minute && timeUntil > -missedGrace
 	}
 
-	// For other tiers, trigger when we're within the tier's window
-	// but haven't passed it by too much (give a 2-minute grace window)
-	return timeUntil <= tier.Duration && timeUntil > tier.Duration-2*time.Minute
+	// For other offsets, trigger when we're within the offset's window
+	// but haven't passed it by more than the grace window
+	return timeUntil <= n.Offset && timeUntil > n.Offset-missedGrace
 }
 
 // FormatTimeUntil formats the time until a reminder in a human-readable way
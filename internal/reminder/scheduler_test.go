@@ -0,0 +1,208 @@
+package reminder
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/baswilson/pika/internal/database"
+)
+
+// fakeClock is a Clock whose time only moves when the test advances it
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	timers  []fakeTimer
+	waiting chan struct{}
+}
+
+type fakeTimer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now, waiting: make(chan struct{}, 16)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	c.timers = append(c.timers, fakeTimer{at: c.now.Add(d), ch: ch})
+	select {
+	case c.waiting <- struct{}{}:
+	default:
+	}
+	return ch
+}
+
+// Set moves the clock to t and fires the timers that are due
+func (c *fakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+	var pending []fakeTimer
+	for _, timer := range c.timers {
+		if timer.at.After(t) {
+			pending = append(pending, timer)
+		} else {
+			timer.ch <- t
+		}
+	}
+	c.timers = pending
+}
+
+// firing is a notification received by the test callback
+type firing struct {
+	title string
+	tier  string
+}
+
+// newTestScheduler returns a scheduler on a fresh database, driven by a
+// fake clock, and a channel receiving its notifications
+func newTestScheduler(t *testing.T, now time.Time) (*Scheduler, *Store, *fakeClock, chan firing) {
+	t.Helper()
+
+	driver, err := database.NewSQLiteDriver(filepath.Join(t.TempDir(), "pika.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	if err := driver.Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewStore(driver.DB())
+	scheduler := NewScheduler(store)
+	store.SetChangeHook(scheduler.Reschedule)
+
+	clock := newFakeClock(now)
+	scheduler.SetClock(clock)
+
+	fired := make(chan firing, 16)
+	scheduler.SetCallback(func(r *Reminder, tier string, timeUntil time.Duration) {
+		fired <- firing{title: r.Title, tier: tier}
+	})
+	return scheduler, store, clock, fired
+}
+
+// createAt creates a reminder with only the at-time notification
+func createAt(t *testing.T, store *Store, title string, at time.Time) *Reminder {
+	t.Helper()
+	r, err := store.CreateWithOptions(context.Background(), title, "", at, CreateOptions{Offsets: []time.Duration{0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// expectFired checks the notifications received so far, in order
+func expectFired(t *testing.T, fired chan firing, want ...firing) {
+	t.Helper()
+	for _, w := range want {
+		select {
+		case got := <-fired:
+			if got != w {
+				t.Fatalf("fired %+v, want %+v", got, w)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %+v", w)
+		}
+	}
+	select {
+	case got := <-fired:
+		t.Fatalf("unexpected notification %+v", got)
+	default:
+	}
+}
+
+func TestSchedulerFiresInDueOrder(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	scheduler, store, clock, fired := newTestScheduler(t, now)
+
+	// Created out of order
+	createAt(t, store, "third", now.Add(30*time.Second))
+	createAt(t, store, "first", now.Add(10*time.Second))
+	createAt(t, store, "second", now.Add(20*time.Second))
+
+	scheduler.fireDue()
+	expectFired(t, fired)
+
+	clock.Set(now.Add(30 * time.Second))
+	scheduler.fireDue()
+	expectFired(t, fired,
+		firing{"first", AtTime},
+		firing{"second", AtTime},
+		firing{"third", AtTime},
+	)
+	if scheduler.queue.Len() != 0 {
+		t.Errorf("queue has %d entries after all notifications were sent", scheduler.queue.Len())
+	}
+}
+
+func TestSchedulerSleepsUntilFireTime(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	scheduler, store, clock, fired := newTestScheduler(t, now)
+	createAt(t, store, "soon", now.Add(10*time.Second))
+	// Start rebuilds the queue, the wakeup from creating is not needed
+	<-scheduler.wake
+
+	scheduler.Start()
+	defer scheduler.Stop()
+
+	// The run loop waits on the clock until the reminder is due
+	<-clock.waiting
+	clock.Set(now.Add(5 * time.Second))
+	expectFired(t, fired)
+
+	clock.Set(now.Add(10 * time.Second))
+	expectFired(t, fired, firing{"soon", AtTime})
+}
+
+func TestSchedulerRetriesWhenStillDue(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	scheduler, store, clock, fired := newTestScheduler(t, now)
+	r := createAt(t, store, "stuck", now.Add(10*time.Second))
+
+	// Marking the notification as sent fails, so it stays pending
+	_, err := store.db.Exec(`CREATE TRIGGER fail_notified BEFORE UPDATE ON reminder_notifications
+		BEGIN SELECT RAISE(FAIL, 'read only'); END`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	due := now.Add(10 * time.Second)
+	clock.Set(due)
+	scheduler.fireDue()
+	expectFired(t, fired, firing{"stuck", AtTime})
+
+	e := scheduler.queue.peek()
+	if e == nil || e.id != r.ID || !e.at.Equal(due.Add(retryDelay)) {
+		t.Fatalf("queued %+v, want %s at %s", e, r.ID, due.Add(retryDelay))
+	}
+
+	if _, err := store.db.Exec("DROP TRIGGER fail_notified"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing happens before the retry
+	clock.Set(due.Add(retryDelay - time.Second))
+	scheduler.fireDue()
+	expectFired(t, fired)
+
+	clock.Set(due.Add(retryDelay))
+	scheduler.fireDue()
+	expectFired(t, fired, firing{"stuck", AtTime})
+	if scheduler.queue.Len() != 0 {
+		t.Errorf("queue has %d entries after the notification was sent", scheduler.queue.Len())
+	}
+}
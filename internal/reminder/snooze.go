@@ -0,0 +1,188 @@
+package reminder
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Tiers of the announcements that are not tied to a notification offset
+const (
+	// TierSnooze re-announces a reminder when its snooze ends
+	TierSnooze = "snooze"
+	// TierNag re-announces a due reminder that has not been acknowledged
+	TierNag = "nag"
+)
+
+// DefaultSnooze is used when a snooze has no duration
+const DefaultSnooze = 10 * time.Minute
+
+// Snooze is one entry in a reminder's snooze history
+type Snooze struct {
+	SnoozedAt time.Time `json:"snoozed_at"`
+	Until     time.Time `json:"until"`
+}
+
+// Snooze postpones the next announcement of a reminder by d and records it
+// in the reminder's snooze history. Nagging pauses until the snooze ends.
+func (s *Store) Snooze(ctx context.Context, id string, d time.Duration) (*Reminder, error) {
+	if d <= 0 {
+		d = DefaultSnooze
+	}
+
+	r, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if r.Completed {
+		return nil, fmt.Errorf("reminder is already completed")
+	}
+
+	now := time.Now()
+	until := now.Add(d).Truncate(time.Second)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO reminder_snoozes (reminder_id, snoozed_at, snoozed_until) VALUES (?, ?, ?)",
+		id, now.Format(time.RFC3339), until.Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx,
+		"UPDATE reminders SET snoozed_until = ?, updated_at = ? WHERE id = ?",
+		until.Format(time.RFC3339), now.Format(time.RFC3339), id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	s.changed(id)
+
+	r.SnoozedUntil = &until
+	r.UpdatedAt = now
+	r.Snoozes = append(r.Snoozes, &Snooze{SnoozedAt: now, Until: until})
+	return r, nil
+}
+
+// Acknowledge stops nagging and cancels any snooze of a reminder. A recurring
+// reminder whose occurrence has passed rolls to its next occurrence.
+func (s *Store) Acknowledge(ctx context.Context, id string) (*Reminder, error) {
+	r, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if r.Recurrence != "" && !r.RemindAt.After(now) {
+		if err := s.EndSnooze(ctx, id); err != nil {
+			return nil, err
+		}
+		r.SnoozedUntil = nil
+		next, ok, err := s.Advance(ctx, r, now)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return next, nil
+		}
+	}
+
+	_, err = s.db.ExecContext(ctx,
+		"UPDATE reminders SET acknowledged_at = ?, snoozed_until = NULL, updated_at = ? WHERE id = ?",
+		now.Format(time.RFC3339), now.Format(time.RFC3339), id)
+	if err != nil {
+		return nil, err
+	}
+	s.changed(id)
+	r.AcknowledgedAt = &now
+	r.SnoozedUntil = nil
+	r.UpdatedAt = now
+	return r, nil
+}
+
+// EndSnooze clears the snooze of a reminder without announcing it
+func (s *Store) EndSnooze(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE reminders SET snoozed_until = NULL WHERE id = ?", id)
+	return err
+}
+
+// MarkAnnounced records that a reminder was re-announced at the given time,
+// ending any snooze
+func (s *Store) MarkAnnounced(ctx context.Context, id string, at time.Time) error {
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE reminders SET last_announced_at = ?, snoozed_until = NULL WHERE id = ?",
+		at.Format(time.RFC3339), id)
+	return err
+}
+
+// nagAt returns when a reminder should next be re-announced: nagging is on,
+// the at-time notification was sent (possibly late), and it has been
+// neither acknowledged nor snoozed since
+func nagAt(r *Reminder) (time.Time, bool) {
+	if r.NagMinutes <= 0 || r.Completed || r.AcknowledgedAt != nil || r.SnoozedUntil != nil {
+		return time.Time{}, false
+	}
+
+	var last *time.Time
+	for _, n := range r.Notifications {
+		if n.Offset == 0 && (n.Status == StatusSent || n.Status == StatusMissed) {
+			last = n.SentAt
+		}
+	}
+	if last == nil {
+		return time.Time{}, false
+	}
+	if r.LastAnnouncedAt != nil && r.LastAnnouncedAt.After(*last) {
+		last = r.LastAnnouncedAt
+	}
+	return last.Add(time.Duration(r.NagMinutes) * time.Minute), true
+}
+
+// nagDue reports whether a reminder should be re-announced at now
+func nagDue(r *Reminder, now time.Time) bool {
+	at, ok := nagAt(r)
+	return ok && !now.Before(at)
+}
+
+// loadSnoozes attaches the snooze history to the given reminders
+func (s *Store) loadSnoozes(ctx context.Context, reminders []*Reminder) error {
+	if len(reminders) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*Reminder, len(reminders))
+	args := make([]interface{}, len(reminders))
+	for i, r := range reminders {
+		byID[r.ID] = r
+		args[i] = r.ID
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT reminder_id, snoozed_at, snoozed_until
+		FROM reminder_snoozes
+		WHERE reminder_id IN (?`+strings.Repeat(", ?", len(reminders)-1)+`)
+		ORDER BY id ASC
+	`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, snoozedAt, until string
+		if err := rows.Scan(&id, &snoozedAt, &until); err != nil {
+			return err
+		}
+		if r := byID[id]; r != nil {
+			r.Snoozes = append(r.Snoozes, &Snooze{SnoozedAt: parseTime(snoozedAt), Until: parseTime(until)})
+		}
+	}
+	return rows.Err()
+}
@@ -3,177 +3,505 @@ package reminder
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
 	"time"
 
+	"github.com/baswilson/pika/internal/rrule"
 	"github.com/google/uuid"
 )
 
 // Reminder represents a reminder in the system
 type Reminder struct {
-	ID             string    `json:"id"`
-	Title          string    `json:"title"`
-	Description    string    `json:"description"`
-	RemindAt       time.Time `json:"remind_at"`
-	Notified24h    bool      `json:"notified_24h"`
-	Notified12h    bool      `json:"notified_12h"`
-	Notified3h     bool      `json:"notified_3h"`
-	Notified1h     bool      `json:"notified_1h"`
-	Notified10m    bool      `json:"notified_10m"`
-	NotifiedAtTime bool      `json:"notified_at_time"`
-	Completed      bool      `json:"completed"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID          string    `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	RemindAt    time.Time `json:"remind_at"`
+	Completed   bool      `json:"completed"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Overdue is set when the reminder is past due and not completed
+	Overdue bool `json:"overdue,omitempty"`
+
+	// Priority is low, normal or high; List groups reminders, e.g. "work"
+	Priority string   `json:"priority"`
+	List     string   `json:"list,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+
+	// EventID anchors the reminder EventOffset before a calendar event's
+	// start; it follows the event when it moves. EventDeleted is set once the
+	// event is deleted.
+	EventID         string        `json:"event_id,omitempty"`
+	EventOffset     time.Duration `json:"-"`
+	EventOffsetName string        `json:"event_offset,omitempty"` // e.g. "1h" or "at_time"
+	EventDeleted    bool          `json:"event_deleted,omitempty"`
+
+	// Notifications are sent at each offset before RemindAt
+	Notifications []*Notification `json:"notifications"`
+
+	// Recurrence is an RRULE for repeating reminders; empty for one-off reminders.
+	// Occurrence counts the occurrences so far, starting at 1.
+	Recurrence string `json:"recurrence,omitempty"`
+	Occurrence int    `json:"occurrence,omitempty"`
+	// Repeats describes the recurrence, e.g. "every Tuesday"
+	Repeats string `json:"repeats,omitempty"`
+
+	// NagMinutes re-announces a due reminder every N minutes until it is
+	// acknowledged or completed; 0 disables nagging
+	NagMinutes      int        `json:"nag_minutes,omitempty"`
+	SnoozedUntil    *time.Time `json:"snoozed_until,omitempty"`
+	AcknowledgedAt  *time.Time `json:"acknowledged_at,omitempty"`
+	LastAnnouncedAt *time.Time `json:"last_announced_at,omitempty"`
+	Snoozes         []*Snooze  `json:"snoozes,omitempty"`
+}
+
+// CreateOptions holds optional attributes for a new reminder
+type CreateOptions struct {
+	// Recurrence makes the reminder repeat
+	Recurrence *rrule.Rule
+	// Offsets overrides the store's default notification schedule
+	Offsets []time.Duration
+	// NagMinutes enables nag mode
+	NagMinutes int
+	// Priority defaults to normal
+	Priority string
+	List     string
+	Tags     []string
+	// Event anchors the reminder to a calendar event; remindAt must be
+	// computed from the event's start with EventAnchor.RemindAt
+	Event *EventAnchor
+}
+
+// reminderColumns is the column list read by scanReminder
+const reminderColumns = "id, title, description, remind_at, completed, created_at, updated_at, recurrence, occurrence, " +
+	"nag_minutes, snoozed_until, acknowledged_at, last_announced_at, priority, list, tags, " +
+	"event_id, event_offset_seconds, event_deleted"
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanReminder reads a row selected with reminderColumns
+func scanReminder(row rowScanner) (*Reminder, error) {
+	r := &Reminder{}
+	var remindAtStr, createdAtStr, updatedAtStr string
+	var recurrence, snoozedUntil, acknowledgedAt, lastAnnouncedAt, priority, list, tags, eventID sql.NullString
+	var occurrence, nagMinutes, eventOffset, eventDeleted sql.NullInt64
+	if err := row.Scan(
+		&r.ID, &r.Title, &r.Description, &remindAtStr,
+		&r.Completed, &createdAtStr, &updatedAtStr, &recurrence, &occurrence,
+		&nagMinutes, &snoozedUntil, &acknowledgedAt, &lastAnnouncedAt,
+		&priority, &list, &tags,
+		&eventID, &eventOffset, &eventDeleted,
+	); err != nil {
+		return nil, err
+	}
+
+	r.RemindAt = parseTime(remindAtStr)
+	r.CreatedAt = parseTime(createdAtStr)
+	r.UpdatedAt = parseTime(updatedAtStr)
+	r.Overdue = !r.Completed && r.RemindAt.Before(time.Now())
+	r.Recurrence = recurrence.String
+	if r.Recurrence != "" {
+		r.Occurrence = int(occurrence.Int64)
+		if rule, err := rrule.Parse(r.Recurrence, time.Local); err == nil {
+			r.Repeats = rule.Describe()
+		}
+	}
+	r.NagMinutes = int(nagMinutes.Int64)
+	r.SnoozedUntil = parseNullTime(snoozedUntil)
+	r.AcknowledgedAt = parseNullTime(acknowledgedAt)
+	r.LastAnnouncedAt = parseNullTime(lastAnnouncedAt)
+	r.Priority = priority.String
+	if r.Priority == "" {
+		r.Priority = PriorityNormal
+	}
+	r.List = list.String
+	if tags.Valid && tags.String != "" {
+		if err := json.Unmarshal([]byte(tags.String), &r.Tags); err != nil {
+			r.Tags = nil
+		}
+	}
+	if eventID.Valid && eventID.String != "" {
+		r.EventID = eventID.String
+		r.EventOffset = time.Duration(eventOffset.Int64) * time.Second
+		r.EventOffsetName = FormatOffset(r.EventOffset)
+		r.EventDeleted = eventDeleted.Int64 == 1
+	}
+	return r, nil
+}
+
+// scanReminders collects all rows selected with reminderColumns
+func scanReminders(rows *sql.Rows) ([]*Reminder, error) {
+	var reminders []*Reminder
+	for rows.Next() {
+		r, err := scanReminder(rows)
+		if err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, r)
+	}
+	return reminders, rows.Err()
 }
 
 // Store handles reminder persistence
 type Store struct {
-	db *sql.DB
+	db             *sql.DB
+	defaultOffsets []time.Duration
+	onChange       func(id string)
 }
 
 // NewStore creates a new reminder store
 func NewStore(db *sql.DB) *Store {
-	return &Store{db: db}
+	return &Store{db: db, defaultOffsets: DefaultOffsets}
+}
+
+// SetDefaultOffsets sets the notification schedule for reminders created
+// without their own
+func (s *Store) SetDefaultOffsets(offsets []time.Duration) {
+	s.defaultOffsets = normalizeOffsets(offsets)
+}
+
+// SetChangeHook sets a function called with the ID of a reminder after it
+// is created, edited, deleted, completed, snoozed or acknowledged
+func (s *Store) SetChangeHook(fn func(id string)) {
+	s.onChange = fn
+}
+
+// changed runs the change hook, if any
+func (s *Store) changed(id string) {
+	if s.onChange != nil {
+		s.onChange(id)
+	}
 }
 
 // Create creates a new reminder
 func (s *Store) Create(ctx context.Context, title, description string, remindAt time.Time) (*Reminder, error) {
+	return s.CreateWithOptions(ctx, title, description, remindAt, CreateOptions{})
+}
+
+// CreateWithOptions creates a new reminder with optional attributes such as a recurrence
+func (s *Store) CreateWithOptions(ctx context.Context, title, description string, remindAt time.Time, opts CreateOptions) (*Reminder, error) {
 	id := uuid.New().String()
 	now := time.Now()
 
+	var recurrence sql.NullString
+	if opts.Recurrence != nil && opts.Event != nil {
+		return nil, fmt.Errorf("a reminder cannot both repeat and follow a calendar event")
+	}
+	if opts.Recurrence != nil {
+		// The first occurrence must itself match the rule
+		remindAt = opts.Recurrence.Align(remindAt)
+		recurrence = sql.NullString{String: opts.Recurrence.String(), Valid: true}
+	}
+
+	offsets := opts.Offsets
+	if len(offsets) == 0 {
+		offsets = s.defaultOffsets
+	}
+
+	priority, err := ParsePriority(opts.Priority)
+	if err != nil {
+		return nil, err
+	}
+	var eventID sql.NullString
+	var eventOffset sql.NullInt64
+	if opts.Event != nil {
+		if opts.Event.Offset < 0 {
+			return nil, fmt.Errorf("event offset cannot be negative")
+		}
+		eventID = sql.NullString{String: opts.Event.EventID, Valid: true}
+		eventOffset = sql.NullInt64{Int64: int64(opts.Event.Offset / time.Second), Valid: true}
+	}
+
+	tags := normalizeTags(opts.Tags)
+	tagsJSON, err := json.Marshal(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO reminders (id, title, description, remind_at, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?)
+		INSERT INTO reminders (id, title, description, remind_at, created_at, updated_at, recurrence, occurrence, nag_minutes, priority, list, tags,
+			event_id, event_offset_seconds)
+		VALUES (?, ?, ?, ?, ?, ?, ?, 1, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := s.db.ExecContext(ctx, query, id, title, description, remindAt.Format(time.RFC3339), now.Format(time.RFC3339), now.Format(time.RFC3339))
+	_, err = tx.ExecContext(ctx, query, id, title, description, remindAt.Format(time.RFC3339), now.Format(time.RFC3339), now.Format(time.RFC3339), recurrence, opts.NagMinutes,
+		priority, opts.List, string(tagsJSON), eventID, eventOffset)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Reminder{
-		ID:          id,
-		Title:       title,
-		Description: description,
-		RemindAt:    remindAt,
-		CreatedAt:   now,
-		UpdatedAt:   now,
-	}, nil
+	notifications, err := scheduleNotifications(ctx, tx, id, remindAt, offsets)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	s.changed(id)
+
+	r := &Reminder{
+		ID:            id,
+		Title:         title,
+		Description:   description,
+		RemindAt:      remindAt,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		Notifications: notifications,
+		NagMinutes:    opts.NagMinutes,
+		Priority:      priority,
+		List:          opts.List,
+		Tags:          tags,
+	}
+	if opts.Event != nil {
+		r.EventID = opts.Event.EventID
+		r.EventOffset = opts.Event.Offset.Truncate(time.Second)
+		r.EventOffsetName = FormatOffset(r.EventOffset)
+	}
+	if opts.Recurrence != nil {
+		r.Recurrence = recurrence.String
+		r.Occurrence = 1
+		r.Repeats = opts.Recurrence.Describe()
+	}
+	return r, nil
 }
 
 // Get retrieves a reminder by ID
 func (s *Store) Get(ctx context.Context, id string) (*Reminder, error) {
 	query := `
-		SELECT id, title, description, remind_at, notified_24h, notified_12h, notified_3h, notified_1h, notified_10m, notified_at_time, completed, created_at, updated_at
+		SELECT ` + reminderColumns + `
 		FROM reminders
 		WHERE id = ?
 	`
 
-	r := &Reminder{}
-	var remindAtStr, createdAtStr, updatedAtStr string
-	err := s.db.QueryRowContext(ctx, query, id).Scan(
-		&r.ID, &r.Title, &r.Description, &remindAtStr,
-		&r.Notified24h, &r.Notified12h, &r.Notified3h, &r.Notified1h, &r.Notified10m, &r.NotifiedAtTime,
-		&r.Completed, &createdAtStr, &updatedAtStr,
-	)
+	r, err := scanReminder(s.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		return nil, err
 	}
-
-	r.RemindAt = parseTime(remindAtStr)
-	r.CreatedAt = parseTime(createdAtStr)
-	r.UpdatedAt = parseTime(updatedAtStr)
-
+	if err := s.loadDetails(ctx, []*Reminder{r}); err != nil {
+		return nil, err
+	}
 	return r, nil
 }
 
-// List returns all reminders, optionally filtered by completion status
-func (s *Store) List(ctx context.Context, includeCompleted bool) ([]*Reminder, error) {
-	query := `
-		SELECT id, title, description, remind_at, notified_24h, notified_12h, notified_3h, notified_1h, notified_10m, notified_at_time, completed, created_at, updated_at
-		FROM reminders
-	`
-	if !includeCompleted {
-		query += " WHERE completed = 0"
+// queryReminders runs a query selecting reminderColumns and loads the
+// notifications and snoozes of the results
+func (s *Store) queryReminders(ctx context.Context, query string, args ...interface{}) ([]*Reminder, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
 	}
-	query += " ORDER BY remind_at ASC"
-
-	rows, err := s.db.QueryContext(ctx, query)
+	reminders, err := scanReminders(rows)
+	rows.Close()
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var reminders []*Reminder
-	for rows.Next() {
-		r := &Reminder{}
-		var remindAtStr, createdAtStr, updatedAtStr string
-		if err := rows.Scan(
-			&r.ID, &r.Title, &r.Description, &remindAtStr,
-			&r.Notified24h, &r.Notified12h, &r.Notified3h, &r.Notified1h, &r.Notified10m, &r.NotifiedAtTime,
-			&r.Completed, &createdAtStr, &updatedAtStr,
-		); err != nil {
-			return nil, err
-		}
-		r.RemindAt = parseTime(remindAtStr)
-		r.CreatedAt = parseTime(createdAtStr)
-		r.UpdatedAt = parseTime(updatedAtStr)
-		reminders = append(reminders, r)
+	if err := s.loadDetails(ctx, reminders); err != nil {
+		return nil, err
 	}
+	return reminders, nil
+}
 
-	return reminders, rows.Err()
+// loadDetails attaches notifications and snooze history to the given reminders
+func (s *Store) loadDetails(ctx context.Context, reminders []*Reminder) error {
+	if err := s.loadNotifications(ctx, reminders); err != nil {
+		return err
+	}
+	return s.loadSnoozes(ctx, reminders)
+}
+
+// List returns all reminders, optionally filtered by completion status
+func (s *Store) List(ctx context.Context, includeCompleted bool) ([]*Reminder, error) {
+	return s.Query(ctx, Filter{IncludeCompleted: includeCompleted})
+}
+
+// ListOverdue returns reminders that are past due and not completed
+func (s *Store) ListOverdue(ctx context.Context) ([]*Reminder, error) {
+	return s.Query(ctx, Filter{Due: DueOverdue})
+}
+
+// Changes are edits to a reminder. Nil fields are left unchanged.
+type Changes struct {
+	Title       *string
+	Description *string
+	// RemindAt moves the reminder. A recurring reminder moves to the first
+	// occurrence of its rule at or after it.
+	RemindAt *time.Time
+	// Offsets replaces the notification schedule
+	Offsets    []time.Duration
+	NagMinutes *int
+	Priority   *string
+	List       *string
+	Tags       []string
 }
 
-// Update updates a reminder
+// Update changes the title, description and time of a reminder. Nil
+// arguments are left unchanged.
 func (s *Store) Update(ctx context.Context, id string, title, description *string, remindAt *time.Time) (*Reminder, error) {
-	// First get the current reminder
+	return s.Edit(ctx, id, Changes{Title: title, Description: description, RemindAt: remindAt})
+}
+
+// Edit applies changes to a reminder in a single transaction, so an
+// invalid change leaves the reminder as it was. A reminder given a new time
+// no longer follows its calendar event.
+func (s *Store) Edit(ctx context.Context, id string, c Changes) (*Reminder, error) {
+	return s.edit(ctx, id, c, false)
+}
+
+// edit applies changes to a reminder. keepEvent keeps an event-anchored
+// reminder anchored when it moves, for moves made to follow the event.
+func (s *Store) edit(ctx context.Context, id string, c Changes, keepEvent bool) (*Reminder, error) {
 	r, err := s.Get(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	// Apply updates
-	if title != nil {
-		r.Title = *title
+	// Validate everything before writing anything
+	if c.Priority != nil {
+		if r.Priority, err = ParsePriority(*c.Priority); err != nil {
+			return nil, err
+		}
+	}
+	if c.NagMinutes != nil {
+		if *c.NagMinutes < 0 {
+			return nil, fmt.Errorf("nag interval cannot be negative")
+		}
+		r.NagMinutes = *c.NagMinutes
+	}
+	if c.Title != nil {
+		r.Title = *c.Title
+	}
+	if c.Description != nil {
+		r.Description = *c.Description
 	}
-	if description != nil {
-		r.Description = *description
+	if c.List != nil {
+		r.List = *c.List
 	}
-	if remindAt != nil {
-		r.RemindAt = *remindAt
-		// Reset notification flags if time changed
-		r.Notified24h = false
-		r.Notified12h = false
-		r.Notified3h = false
-		r.Notified1h = false
-		r.Notified10m = false
-		r.NotifiedAtTime = false
+	if c.Tags != nil {
+		r.Tags = normalizeTags(c.Tags)
+	}
+	tagsJSON, err := json.Marshal(r.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	offsets := s.offsetsOf(r)
+	if c.Offsets != nil {
+		offsets = c.Offsets
+	}
+	if c.RemindAt != nil {
+		remindAt := *c.RemindAt
+		if r.Recurrence != "" {
+			// Keep the series on its rule, counting the occurrences it moved past
+			rule, err := rrule.Parse(r.Recurrence, time.Local)
+			if err != nil {
+				return nil, err
+			}
+			remindAt = rule.Align(remindAt.Local())
+			r.Occurrence = occurrenceAt(rule, r.RemindAt.Local(), r.Occurrence, remindAt)
+		}
+		r.RemindAt = remindAt
+		// A new time is a new announcement to acknowledge, and ends any snooze
+		r.AcknowledgedAt = nil
+		r.LastAnnouncedAt = nil
+		r.SnoozedUntil = nil
+		// A time the user chose would be undone by the next sync of the event
+		if !keepEvent {
+			r.EventID = ""
+			r.EventOffset = 0
+			r.EventOffsetName = ""
+			r.EventDeleted = false
+		}
 	}
 	r.UpdatedAt = time.Now()
 
+	var eventID sql.NullString
+	var eventOffset sql.NullInt64
+	if r.EventID != "" {
+		eventID = sql.NullString{String: r.EventID, Valid: true}
+		eventOffset = sql.NullInt64{Int64: int64(r.EventOffset / time.Second), Valid: true}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
 	query := `
 		UPDATE reminders
-		SET title = ?, description = ?, remind_at = ?,
-		    notified_24h = ?, notified_12h = ?, notified_3h = ?, notified_1h = ?, notified_10m = ?, notified_at_time = ?,
-		    updated_at = ?
+		SET title = ?, description = ?, remind_at = ?, occurrence = ?, updated_at = ?,
+			acknowledged_at = ?, last_announced_at = ?, snoozed_until = ?,
+			nag_minutes = ?, priority = ?, list = ?, tags = ?,
+			event_id = ?, event_offset_seconds = ?, event_deleted = ?
 		WHERE id = ?
 	`
 
-	_, err = s.db.ExecContext(ctx, query,
-		r.Title, r.Description, r.RemindAt.Format(time.RFC3339),
-		r.Notified24h, r.Notified12h, r.Notified3h, r.Notified1h, r.Notified10m, r.NotifiedAtTime,
-		r.UpdatedAt.Format(time.RFC3339), id,
+	_, err = tx.ExecContext(ctx, query,
+		r.Title, r.Description, r.RemindAt.Format(time.RFC3339), max(r.Occurrence, 1),
+		r.UpdatedAt.Format(time.RFC3339), formatNullTime(r.AcknowledgedAt),
+		formatNullTime(r.LastAnnouncedAt), formatNullTime(r.SnoozedUntil),
+		r.NagMinutes, r.Priority, r.List, string(tagsJSON),
+		eventID, eventOffset, r.EventDeleted, id,
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	// Reset notifications if the time or schedule changed
+	if c.RemindAt != nil || c.Offsets != nil {
+		r.Notifications, err = scheduleNotifications(ctx, tx, id, r.RemindAt, offsets)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	s.changed(id)
 	return r, nil
 }
 
+// occurrenceAt returns the number of the occurrence a series lands on when
+// its occurrence n moves from current to t, a date on the rule. Every date of
+// the series before t's day counts, including current's, so moving to a later
+// date uses up the occurrences in between and COUNT still ends the series on
+// the rule's last date. Moving within the day or earlier keeps n.
+func occurrenceAt(rule *rrule.Rule, current time.Time, n int, t time.Time) int {
+	if n < 1 {
+		n = 1
+	}
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	passed := 0
+	for at, m := current, n; at.Before(day); m++ {
+		passed++
+		next, ok := rule.Next(at, m)
+		if !ok {
+			break
+		}
+		at = next
+	}
+	return n + passed
+}
+
 // Delete removes a reminder
 func (s *Store) Delete(ctx context.Context, id string) error {
-	_, err := s.db.ExecContext(ctx, "DELETE FROM reminders WHERE id = ?", id)
-	return err
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM reminders WHERE id = ?", id); err != nil {
+		return err
+	}
+	s.changed(id)
+	return nil
 }
 
 // MarkCompleted marks a reminder as completed
@@ -182,103 +510,141 @@ func (s *Store) MarkCompleted(ctx context.Context, id string) error {
 		"UPDATE reminders SET completed = 1, updated_at = ? WHERE id = ?",
 		time.Now().Format(time.RFC3339), id,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	s.changed(id)
+	return nil
 }
 
-// FindByTitle finds reminders matching a title (case-insensitive partial match)
-func (s *Store) FindByTitle(ctx context.Context, title string) ([]*Reminder, error) {
-	query := `
-		SELECT id, title, description, remind_at, notified_24h, notified_12h, notified_3h, notified_1h, notified_10m, notified_at_time, completed, created_at, updated_at
-		FROM reminders
-		WHERE LOWER(title) LIKE '%' || LOWER(?) || '%' AND completed = 0
-		ORDER BY remind_at ASC
-	`
-
-	rows, err := s.db.QueryContext(ctx, query, title)
+// Complete finishes the current occurrence of a reminder. Recurring reminders
+// roll to their next occurrence; others, and recurrences that have ended, are
+// marked completed. Returns the reminder as stored afterwards.
+func (s *Store) Complete(ctx context.Context, id string) (*Reminder, error) {
+	r, err := s.Get(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var reminders []*Reminder
-	for rows.Next() {
-		r := &Reminder{}
-		var remindAtStr, createdAtStr, updatedAtStr string
-		if err := rows.Scan(
-			&r.ID, &r.Title, &r.Description, &remindAtStr,
-			&r.Notified24h, &r.Notified12h, &r.Notified3h, &r.Notified1h, &r.Notified10m, &r.NotifiedAtTime,
-			&r.Completed, &createdAtStr, &updatedAtStr,
-		); err != nil {
+	if r.Recurrence != "" {
+		next, ok, err := s.Advance(ctx, r, time.Now())
+		if err != nil {
 			return nil, err
 		}
-		r.RemindAt = parseTime(remindAtStr)
-		r.CreatedAt = parseTime(createdAtStr)
-		r.UpdatedAt = parseTime(updatedAtStr)
-		reminders = append(reminders, r)
+		if ok {
+			return next, nil
+		}
 	}
 
-	return reminders, rows.Err()
+	if err := s.MarkCompleted(ctx, id); err != nil {
+		return nil, err
+	}
+	r.Completed = true
+	return r, nil
 }
 
-// GetPendingReminders returns reminders that need notification checks
-func (s *Store) GetPendingReminders(ctx context.Context) ([]*Reminder, error) {
-	// Get all non-completed reminders where we haven't sent all notifications yet
+// Advance moves a recurring reminder to its first occurrence after both its
+// current time and now, resetting its notifications. ok is false when the
+// reminder does not recur or its recurrence has ended.
+func (s *Store) Advance(ctx context.Context, r *Reminder, now time.Time) (*Reminder, bool, error) {
+	if r.Recurrence == "" {
+		return r, false, nil
+	}
+	rule, err := rrule.Parse(r.Recurrence, time.Local)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Work in local time so occurrences keep their wall-clock time across DST changes
+	next, n := r.RemindAt.Local(), r.Occurrence
+	if n < 1 {
+		n = 1
+	}
+	for {
+		var ok bool
+		next, ok = rule.Next(next, n)
+		if !ok {
+			return r, false, nil
+		}
+		n++
+		if next.After(now) {
+			break
+		}
+	}
+
+	r.RemindAt = next
+	r.Occurrence = n
+	r.UpdatedAt = time.Now()
+	r.AcknowledgedAt = nil
+	r.LastAnnouncedAt = nil
+	r.SnoozedUntil = nil
+
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE reminders
+		SET remind_at = ?, occurrence = ?, updated_at = ?,
+			acknowledged_at = NULL, last_announced_at = NULL, snoozed_until = NULL
+		WHERE id = ?
+	`, r.RemindAt.Format(time.RFC3339), r.Occurrence, r.UpdatedAt.Format(time.RFC3339), r.ID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	r.Notifications, err = scheduleNotifications(ctx, s.db, r.ID, r.RemindAt, s.offsetsOf(r))
+	if err != nil {
+		return nil, false, err
+	}
+	s.changed(r.ID)
+	return r, true, nil
+}
+
+// FindByTitle finds reminders matching a title (case-insensitive partial match)
+func (s *Store) FindByTitle(ctx context.Context, title string) ([]*Reminder, error) {
 	query := `
-		SELECT id, title, description, remind_at, notified_24h, notified_12h, notified_3h, notified_1h, notified_10m, notified_at_time, completed, created_at, updated_at
+		SELECT ` + reminderColumns + `
 		FROM reminders
-		WHERE completed = 0 AND notified_at_time = 0
+		WHERE LOWER(title) LIKE '%' || LOWER(?) || '%' AND completed = 0
 		ORDER BY remind_at ASC
 	`
 
-	rows, err := s.db.QueryContext(ctx, query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+	return s.queryReminders(ctx, query, title)
+}
 
-	var reminders []*Reminder
-	for rows.Next() {
-		r := &Reminder{}
-		var remindAtStr, createdAtStr, updatedAtStr string
-		if err := rows.Scan(
-			&r.ID, &r.Title, &r.Description, &remindAtStr,
-			&r.Notified24h, &r.Notified12h, &r.Notified3h, &r.Notified1h, &r.Notified10m, &r.NotifiedAtTime,
-			&r.Completed, &createdAtStr, &updatedAtStr,
-		); err != nil {
-			return nil, err
-		}
-		r.RemindAt = parseTime(remindAtStr)
-		r.CreatedAt = parseTime(createdAtStr)
-		r.UpdatedAt = parseTime(updatedAtStr)
-		reminders = append(reminders, r)
-	}
+// GetPendingReminders returns reminders that need notification checks
+func (s *Store) GetPendingReminders(ctx context.Context) ([]*Reminder, error) {
+	// Get all non-completed reminders where we haven't sent all notifications
+	// yet, that are snoozed, or that may need nagging
+	query := `
+		SELECT ` + reminderColumns + `
+		FROM reminders
+		WHERE completed = 0 AND (
+			EXISTS (
+				SELECT 1 FROM reminder_notifications n
+				WHERE n.reminder_id = reminders.id AND n.status = '` + StatusPending + `'
+			)
+			OR snoozed_until IS NOT NULL
+			OR (nag_minutes > 0 AND acknowledged_at IS NULL)
+		)
+		ORDER BY remind_at ASC
+	`
 
-	return reminders, rows.Err()
+	return s.queryReminders(ctx, query)
 }
 
-// MarkNotified marks a specific notification tier as sent
-func (s *Store) MarkNotified(ctx context.Context, id string, tier string) error {
-	var column string
-	switch tier {
-	case "24h":
-		column = "notified_24h"
-	case "12h":
-		column = "notified_12h"
-	case "3h":
-		column = "notified_3h"
-	case "1h":
-		column = "notified_1h"
-	case "10m":
-		column = "notified_10m"
-	case "at_time":
-		column = "notified_at_time"
-	default:
+// parseNullTime parses an optional time column
+func parseNullTime(s sql.NullString) *time.Time {
+	if !s.Valid || s.String == "" {
 		return nil
 	}
+	t := parseTime(s.String)
+	return &t
+}
 
-	query := "UPDATE reminders SET " + column + " = 1, updated_at = ? WHERE id = ?"
-	_, err := s.db.ExecContext(ctx, query, time.Now().Format(time.RFC3339), id)
-	return err
+// formatNullTime formats an optional time for storage
+func formatNullTime(t *time.Time) sql.NullString {
+	if t == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: t.Format(time.RFC3339), Valid: true}
 }
 
 // parseTime parses a time string from SQLite
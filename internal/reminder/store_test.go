@@ -0,0 +1,204 @@
+package reminder
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/baswilson/pika/internal/database"
+	"github.com/baswilson/pika/internal/rrule"
+)
+
+// newTestStore returns a store on a fresh database
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+
+	driver, err := database.NewSQLiteDriver(filepath.Join(t.TempDir(), "pika.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	if err := driver.Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return NewStore(driver.DB())
+}
+
+// nextWeekday returns the first day after now falling on a weekday, at hour
+func nextWeekday(now time.Time, day time.Weekday, hour int) time.Time {
+	t := time.Date(now.Year(), now.Month(), now.Day()+1, hour, 0, 0, 0, time.Local)
+	for t.Weekday() != day {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+func TestEditAlignsRecurringReminder(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	rule, err := rrule.Parse("FREQ=WEEKLY;BYDAY=TU;COUNT=5", time.Local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tuesday := nextWeekday(time.Now(), time.Tuesday, 9)
+	r, err := store.CreateWithOptions(ctx, "Bins", "", tuesday, CreateOptions{Recurrence: rule})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Thursday is not on the rule; the next Tuesday is, two weeks on and
+	// the third date of the series
+	thursday := tuesday.AddDate(0, 0, 9).Add(time.Hour)
+	r, err = store.Update(ctx, r.ID, nil, nil, &thursday)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(tuesday.Year(), tuesday.Month(), tuesday.Day()+14, 10, 0, 0, 0, time.Local)
+	if !r.RemindAt.Equal(want) {
+		t.Errorf("moved to %s, want %s", r.RemindAt, want)
+	}
+	if r.Occurrence != 3 {
+		t.Errorf("occurrence %d after moving to the third date, want 3", r.Occurrence)
+	}
+
+	stored, err := store.Get(ctx, r.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.RemindAt.Equal(want) || stored.Occurrence != 3 {
+		t.Errorf("stored %s occurrence %d, want %s occurrence 3", stored.RemindAt, stored.Occurrence, want)
+	}
+
+	// The series still ends on the rule's fifth date
+	last := stored
+	for {
+		next, ok, err := store.Advance(ctx, last, last.RemindAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			break
+		}
+		last = next
+	}
+	wantLast := time.Date(tuesday.Year(), tuesday.Month(), tuesday.Day()+28, 10, 0, 0, 0, time.Local)
+	if !last.RemindAt.Equal(wantLast) || last.Occurrence != 5 {
+		t.Errorf("last reminder %s occurrence %d, want %s occurrence 5", last.RemindAt, last.Occurrence, wantLast)
+	}
+}
+
+func TestEditIsAllOrNothing(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	at := time.Now().Add(time.Hour).Truncate(time.Second)
+	r := createAt(t, store, "Call mum", at)
+
+	title := "Call dad"
+	priority := "urgent"
+	later := at.Add(time.Hour)
+	_, err := store.Edit(ctx, r.ID, Changes{Title: &title, RemindAt: &later, Priority: &priority})
+	if err == nil {
+		t.Fatal("edit with an invalid priority succeeded")
+	}
+
+	stored, err := store.Get(ctx, r.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Title != "Call mum" || !stored.RemindAt.Equal(at) {
+		t.Errorf("failed edit was partly applied: %q at %s", stored.Title, stored.RemindAt)
+	}
+}
+
+func TestEditDetachesEventReminder(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	anchor := &EventAnchor{EventID: "event-1", Offset: time.Hour}
+	followed, err := store.CreateWithOptions(ctx, "Prepare slides", "", anchor.RemindAt(start), CreateOptions{Event: anchor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited, err := store.CreateWithOptions(ctx, "Book room", "", anchor.RemindAt(start), CreateOptions{Event: anchor})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The user picks their own time for one of them
+	own := start.Add(-3 * time.Hour)
+	if _, err := store.Update(ctx, edited.ID, nil, nil, &own); err != nil {
+		t.Fatal(err)
+	}
+
+	// Then the event moves
+	moved := start.Add(2 * time.Hour)
+	if _, err := store.FollowEvent(ctx, "event-1", moved); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.Get(ctx, followed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.EventID != "event-1" || !got.RemindAt.Equal(moved.Add(-time.Hour)) {
+		t.Errorf("anchored reminder at %s with event %q, want %s with event-1", got.RemindAt, got.EventID, moved.Add(-time.Hour))
+	}
+
+	got, err = store.Get(ctx, edited.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.EventID != "" || !got.RemindAt.Equal(own) {
+		t.Errorf("edited reminder at %s with event %q, want %s detached", got.RemindAt, got.EventID, own)
+	}
+}
+
+func TestQueryComparesTimesAcrossOffsets(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	// Stored with offsets far from local time, so the text of remind_at
+	// falls on another day than the instant does
+	now := time.Now()
+	east := time.FixedZone("UTC+14", 14*60*60)
+	west := time.FixedZone("UTC-12", -12*60*60)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 12, 0, 0, 0, time.Local)
+	if _, err := store.CreateWithOptions(ctx, "Today", "", today.In(east), CreateOptions{Tags: []string{"Home"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateWithOptions(ctx, "Tomorrow", "", today.AddDate(0, 0, 1).In(west), CreateOptions{Tags: []string{"home"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateWithOptions(ctx, "Work", "", today.In(west), CreateOptions{Tags: []string{"work"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.Query(ctx, Filter{Due: DueToday, Tag: "HOME"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Title != "Today" {
+		t.Errorf("due today tagged home: %v", titles(got))
+	}
+
+	got, err = store.Query(ctx, Filter{Tag: "home"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Title != "Today" || got[1].Title != "Tomorrow" {
+		t.Errorf("tagged home: %v, want Today then Tomorrow", titles(got))
+	}
+}
+
+// titles returns the titles of reminders, for error messages
+func titles(reminders []*Reminder) []string {
+	var result []string
+	for _, r := range reminders {
+		result = append(result, r.Title)
+	}
+	return result
+}
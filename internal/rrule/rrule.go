@@ -0,0 +1,545 @@
+// Package rrule parses and expands RFC 5545 recurrence rules, as used by
+// recurring reminders and calendar events
+package rrule
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence frequencies (RFC 5545 FREQ values)
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+	Yearly  = "YEARLY"
+)
+
+// maxPeriods bounds how many periods are scanned for occurrences, so rules
+// that never match (e.g. February 30th) end
+const maxPeriods = 20000
+
+// Rule is the supported subset of an RFC 5545 RRULE: FREQ=DAILY|WEEKLY|
+// MONTHLY|YEARLY with optional INTERVAL, UNTIL or COUNT, BYDAY, BYMONTHDAY
+// and BYMONTH. BYDAY entries may be numbered in monthly and yearly rules,
+// such as 2TU for the second Tuesday or -1FR for the last Friday.
+// Occurrences keep the time of day of the first one; weeks start on Monday.
+type Rule struct {
+	Freq       string
+	Interval   int
+	Count      int
+	Until      *time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+}
+
+// WeekdayNum is a BYDAY entry; N is 0 for every such weekday of the period
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Parse parses an RRULE such as "FREQ=WEEKLY;BYDAY=TU" or
+// "RRULE:FREQ=MONTHLY;BYDAY=-1FR;COUNT=10". UNTIL values without a time
+// zone are read in loc; a date-only UNTIL includes the whole day.
+func Parse(s string, loc *time.Location) (*Rule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(strings.ToUpper(s)), "RRULE:")
+	if s == "" {
+		return nil, fmt.Errorf("empty recurrence rule")
+	}
+
+	r := &Rule{Interval: 1}
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid recurrence part %q", part)
+		}
+
+		switch key {
+		case "FREQ":
+			switch value {
+			case Daily, Weekly, Monthly, Yearly:
+				r.Freq = value
+			default:
+				return nil, fmt.Errorf("unsupported recurrence frequency %q", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL %q", value)
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid COUNT %q", value)
+			}
+			r.Count = n
+		case "UNTIL":
+			t, err := parseUntil(value, loc)
+			if err != nil {
+				return nil, err
+			}
+			r.Until = &t
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				wd, err := parseWeekdayNum(code)
+				if err != nil {
+					return nil, err
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			for _, d := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(d)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("invalid BYMONTHDAY %q", d)
+				}
+				r.ByMonthDay = append(r.ByMonthDay, n)
+			}
+		case "BYMONTH":
+			for _, m := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(m)
+				if err != nil || n < 1 || n > 12 {
+					return nil, fmt.Errorf("invalid BYMONTH %q", m)
+				}
+				r.ByMonth = append(r.ByMonth, time.Month(n))
+			}
+		case "WKST":
+			// Calendar apps often send WKST=SU; weeks are counted from
+			// Monday regardless, which only matters to weekly rules with
+			// an INTERVAL
+			if _, ok := weekdayCodes[value]; !ok {
+				return nil, fmt.Errorf("invalid WKST %q", value)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported recurrence part %q", key)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, fmt.Errorf("recurrence rule needs FREQ")
+	}
+	if r.Until != nil && r.Count > 0 {
+		return nil, fmt.Errorf("UNTIL and COUNT cannot be combined")
+	}
+	if r.Freq == Weekly && len(r.ByMonthDay) > 0 {
+		return nil, fmt.Errorf("BYMONTHDAY cannot be used with FREQ=WEEKLY")
+	}
+	if r.Freq == Daily || r.Freq == Weekly {
+		for _, wd := range r.ByDay {
+			if wd.N != 0 {
+				return nil, fmt.Errorf("numbered BYDAY values are only supported for MONTHLY and YEARLY rules")
+			}
+		}
+	}
+	return r, nil
+}
+
+// parseUntil accepts the RFC 5545 date and date-time forms as well as RFC3339
+func parseUntil(s string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("20060102T150405", s, loc); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("20060102", s, loc); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid UNTIL %q", s)
+}
+
+// parseWeekdayNum parses a BYDAY entry such as "TU", "2TU" or "-1FR"
+func parseWeekdayNum(s string) (WeekdayNum, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 {
+		return WeekdayNum{}, fmt.Errorf("unsupported BYDAY value %q", s)
+	}
+	day, ok := weekdayCodes[s[len(s)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("unsupported BYDAY value %q", s)
+	}
+	wd := WeekdayNum{Day: day}
+	if prefix := s[:len(s)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -53 || n > 53 {
+			return WeekdayNum{}, fmt.Errorf("unsupported BYDAY value %q", s)
+		}
+		wd.N = n
+	}
+	return wd, nil
+}
+
+// String formats the rule as an RRULE value
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByMonth) > 0 {
+		months := make([]string, len(r.ByMonth))
+		for i, m := range r.ByMonth {
+			months[i] = strconv.Itoa(int(m))
+		}
+		parts = append(parts, "BYMONTH="+strings.Join(months, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, len(r.ByDay))
+		for i, wd := range r.ByDay {
+			codes[i] = strings.ToUpper(wd.Day.String()[:2])
+			if wd.N != 0 {
+				codes[i] = strconv.Itoa(wd.N) + codes[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Describe returns a short spoken description such as "every Tuesday",
+// "every month on the last Friday" or "every 2 days, 5 times"
+func (r *Rule) Describe() string {
+	var desc string
+	switch r.Freq {
+	case Daily:
+		desc = plural(r.Interval, "day")
+	case Weekly:
+		desc = plural(r.Interval, "week")
+	case Monthly:
+		desc = plural(r.Interval, "month")
+	case Yearly:
+		desc = plural(r.Interval, "year")
+	}
+
+	if len(r.ByMonth) > 0 {
+		names := make([]string, len(r.ByMonth))
+		for i, m := range r.ByMonth {
+			names[i] = m.String()
+		}
+		desc += " in " + strings.Join(names, ", ")
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = "the " + ordinal(d)
+			if d < 0 {
+				days[i] += " day"
+			}
+		}
+		desc += " on " + strings.Join(days, ", ")
+	}
+	if len(r.ByDay) > 0 {
+		names := make([]string, len(r.ByDay))
+		numbered := false
+		for i, wd := range r.ByDay {
+			names[i] = wd.Day.String()
+			if wd.N != 0 {
+				names[i] = "the " + ordinal(wd.N) + " " + names[i]
+				numbered = true
+			}
+		}
+		days := strings.Join(names, ", ")
+		switch {
+		case r.Interval == 1 && !numbered && (r.Freq == Daily || r.Freq == Weekly):
+			desc = "every " + days
+		case len(r.ByMonthDay) > 0:
+			desc += " if a " + days
+		default:
+			desc += " on " + days
+		}
+	}
+
+	if r.Until != nil {
+		desc += " until " + r.Until.Local().Format("Jan 2, 2006")
+	}
+	if r.Count > 0 {
+		desc += fmt.Sprintf(", %d times", r.Count)
+	}
+	return desc
+}
+
+// plural formats "every day" or "every 3 days"
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "every " + unit
+	}
+	return fmt.Sprintf("every %d %ss", n, unit)
+}
+
+// ordinal formats 1 as "1st" and -1 as "last", for days of the month and
+// numbered weekdays
+func ordinal(n int) string {
+	switch n {
+	case -1:
+		return "last"
+	case -2:
+		return "second to last"
+	}
+	if n < 0 {
+		return ordinal(-n) + " to last"
+	}
+	suffix := "th"
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+// Each calls fn with the occurrences of the rule for a series starting at
+// start, in order, until fn returns false or the rule ends. start itself
+// is the first occurrence when it matches the rule. COUNT counts from start.
+func (r *Rule) Each(start time.Time, fn func(t time.Time) bool) {
+	r.each(start, r.Count, fn)
+}
+
+// Next returns the first occurrence after the given one, which is occurrence
+// number n (starting at 1). ok is false when the rule has ended.
+func (r *Rule) Next(current time.Time, n int) (next time.Time, ok bool) {
+	if r.Count > 0 && n >= r.Count {
+		return time.Time{}, false
+	}
+	r.each(current, 0, func(t time.Time) bool {
+		if t.After(current) {
+			next, ok = t, true
+			return false
+		}
+		return true
+	})
+	return next, ok
+}
+
+// Align returns the first occurrence at or after t for a series starting
+// at t, keeping the time of day. t is returned when the rule never matches.
+func (r *Rule) Align(t time.Time) time.Time {
+	aligned := t
+	r.each(t, 0, func(o time.Time) bool {
+		aligned = o
+		return false
+	})
+	return aligned
+}
+
+// each enumerates the occurrences from start, stopping after count of
+// them when count is positive
+func (r *Rule) each(start time.Time, count int, fn func(t time.Time) bool) {
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	n := 0
+	for i := 0; i < maxPeriods; i++ {
+		for _, t := range r.candidates(r.period(start, i*interval), start) {
+			if t.Before(start) {
+				continue
+			}
+			if r.Until != nil && t.After(*r.Until) {
+				return
+			}
+			n++
+			if count > 0 && n > count {
+				return
+			}
+			if !fn(t) {
+				return
+			}
+		}
+	}
+}
+
+// period returns the start of the nth period after the one containing start
+func (r *Rule) period(start time.Time, n int) time.Time {
+	y, m, d := start.Date()
+	loc := start.Location()
+	switch r.Freq {
+	case Daily:
+		return time.Date(y, m, d+n, 0, 0, 0, 0, loc)
+	case Weekly:
+		monday := d - (int(start.Weekday())+6)%7
+		return time.Date(y, m, monday+7*n, 0, 0, 0, 0, loc)
+	case Monthly:
+		return time.Date(y, m+time.Month(n), 1, 0, 0, 0, 0, loc)
+	default:
+		return time.Date(y+n, 1, 1, 0, 0, 0, 0, loc)
+	}
+}
+
+// candidates returns the occurrence times in the period starting at begin,
+// sorted, at the time of day of start
+func (r *Rule) candidates(begin, start time.Time) []time.Time {
+	var days []time.Time
+	switch r.Freq {
+	case Daily:
+		if r.matchesDay(begin) {
+			days = append(days, begin)
+		}
+	case Weekly:
+		weekdays := []time.Weekday{start.Weekday()}
+		if len(r.ByDay) > 0 {
+			weekdays = weekdays[:0]
+			for _, wd := range r.ByDay {
+				weekdays = append(weekdays, wd.Day)
+			}
+		}
+		for _, wd := range weekdays {
+			day := begin.AddDate(0, 0, (int(wd)+6)%7)
+			if r.inMonths(day.Month()) {
+				days = append(days, day)
+			}
+		}
+	case Monthly:
+		if r.inMonths(begin.Month()) {
+			days = r.daysInMonth(begin, start)
+		}
+	case Yearly:
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []time.Month{start.Month()}
+		}
+		for _, m := range months {
+			first := time.Date(begin.Year(), m, 1, 0, 0, 0, 0, begin.Location())
+			days = append(days, r.daysInMonth(first, start)...)
+		}
+	}
+
+	h, min, sec := start.Clock()
+	seen := make(map[int64]bool, len(days))
+	times := make([]time.Time, 0, len(days))
+	for _, day := range days {
+		t := time.Date(day.Year(), day.Month(), day.Day(), h, min, sec, 0, day.Location())
+		if !seen[t.Unix()] {
+			seen[t.Unix()] = true
+			times = append(times, t)
+		}
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	return times
+}
+
+// daysInMonth returns the days of the month starting at first selected by
+// BYMONTHDAY or BYDAY, or the day of month of start
+func (r *Rule) daysInMonth(first, start time.Time) []time.Time {
+	last := first.AddDate(0, 1, -1).Day()
+	var days []time.Time
+
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, n := range r.ByMonthDay {
+			if n < 0 {
+				n = last + n + 1
+			}
+			if n < 1 || n > last {
+				continue
+			}
+			day := first.AddDate(0, 0, n-1)
+			if len(r.ByDay) == 0 || r.matchesWeekday(day.Weekday()) {
+				days = append(days, day)
+			}
+		}
+	case len(r.ByDay) > 0:
+		for _, wd := range r.ByDay {
+			var matches []time.Time
+			for d := 1; d <= last; d++ {
+				day := first.AddDate(0, 0, d-1)
+				if day.Weekday() == wd.Day {
+					matches = append(matches, day)
+				}
+			}
+			switch {
+			case wd.N == 0:
+				days = append(days, matches...)
+			case wd.N > 0 && wd.N <= len(matches):
+				days = append(days, matches[wd.N-1])
+			case wd.N < 0 && -wd.N <= len(matches):
+				days = append(days, matches[len(matches)+wd.N])
+			}
+		}
+	default:
+		// Months without the day (e.g. the 31st) are skipped, as RFC 5545 says
+		if start.Day() <= last {
+			days = append(days, first.AddDate(0, 0, start.Day()-1))
+		}
+	}
+	return days
+}
+
+// matchesDay applies the BYMONTH, BYMONTHDAY and BYDAY filters of a daily rule
+func (r *Rule) matchesDay(day time.Time) bool {
+	if !r.inMonths(day.Month()) {
+		return false
+	}
+	if len(r.ByDay) > 0 && !r.matchesWeekday(day.Weekday()) {
+		return false
+	}
+	if len(r.ByMonthDay) > 0 {
+		last := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, day.Location()).Day()
+		for _, n := range r.ByMonthDay {
+			if n == day.Day() || (n < 0 && last+n+1 == day.Day()) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func (r *Rule) matchesWeekday(day time.Weekday) bool {
+	for _, wd := range r.ByDay {
+		if wd.Day == day {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) inMonths(m time.Month) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, bm := range r.ByMonth {
+		if bm == m {
+			return true
+		}
+	}
+	return false
+}
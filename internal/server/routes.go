@@ -1,19 +1,30 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"html/template"
 	"io"
 	"io/fs"
 	"log"
 	"net/http"
+	"net/url"
 	"os/exec"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/baswilson/pika/internal/actions"
 	"github.com/baswilson/pika/internal/ai"
+	"github.com/baswilson/pika/internal/calendar"
+	"github.com/baswilson/pika/internal/memory"
+	"github.com/baswilson/pika/internal/reminder"
+	"github.com/baswilson/pika/internal/rrule"
 	"github.com/baswilson/pika/internal/ws"
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"github.com/sashabaranov/go-openai"
 )
 
@@ -49,10 +60,32 @@ func (s *Server) setupRoutes() {
 		// Memory endpoints
 		r.Get("/memories", s.handleListMemories)
 		r.Post("/memories", s.handleCreateMemory)
+		r.Get("/memories/export", s.handleExportMemories)
+		r.Post("/memories/import", s.handleImportMemories)
+		r.Get("/memories/{id}/source", s.handleGetMemorySource)
+
+		// Conversation endpoints
+		r.Get("/conversations/{id}/memories", s.handleListConversationMemories)
+		r.Delete("/conversations/{id}/memories", s.handleDeleteConversationMemories)
+		r.Get("/conversations/{id}/learned", s.handleListLearnedMemories)
+
+		// Knowledge graph endpoints
+		r.Get("/entities", s.handleListEntities)
+		r.Get("/entities/{id}", s.handleGetEntity)
+		r.Get("/facts", s.handleGetFacts)
 
 		// Calendar endpoints
 		r.Get("/calendar/events", s.handleListCalendarEvents)
 		r.Post("/calendar/events", s.handleCreateCalendarEvent)
+		r.Get("/calendar/export.ics", s.handleExportCalendar)
+		r.Post("/calendar/import", s.handleImportCalendar)
+		r.Get("/calendar/feed", s.handleGetCalendarFeed)
+		r.Post("/calendar/feed/rotate", s.handleRotateCalendarFeed)
+		r.Get("/calendar/sync", s.handleCalendarSyncStatus)
+		r.Get("/calendar/calendars", s.handleListCalendars)
+		r.Put("/calendar/calendars/{id}", s.handleUpdateCalendar)
+		r.Post("/calendar/sync", s.handleSyncCalendar)
+		r.Get("/calendar/auth", s.handleCalendarAuthStatus)
 
 		// Reminder endpoints
 		r.Get("/reminders", s.handleListReminders)
@@ -61,6 +94,8 @@ func (s *Server) setupRoutes() {
 		r.Put("/reminders/{id}", s.handleUpdateReminder)
 		r.Delete("/reminders/{id}", s.handleDeleteReminder)
 		r.Post("/reminders/{id}/complete", s.handleCompleteReminder)
+		r.Post("/reminders/{id}/snooze", s.handleSnoozeReminder)
+		r.Post("/reminders/{id}/acknowledge", s.handleAcknowledgeReminder)
 
 		// Game endpoints
 		r.Post("/game/move", s.handleGameMove)
@@ -70,8 +105,12 @@ func (s *Server) setupRoutes() {
 	r.Route("/auth", func(r chi.Router) {
 		r.Get("/google", s.handleGoogleAuth)
 		r.Get("/google/callback", s.handleGoogleCallback)
+		r.Delete("/google", s.handleGoogleDisconnect)
 	})
 
+	// Read-only ICS feed for calendar apps; the token in the URL is the only credential
+	r.Get("/calendar/feed/{token}.ics", s.handleCalendarFeed)
+
 	// Utility routes
 	r.Post("/open-url", s.handleOpenURL)
 	r.Post("/api/reset", s.handleReset)
@@ -146,6 +185,9 @@ func (s *Server) processCommand(client *ws.Client, cmd *ws.CommandPayload, reque
 
 	// Add user message to conversation history
 	client.AddToHistory("user", cmd.Text)
+	if s.learner != nil {
+		s.learner.Record(client.ID(), "user", cmd.Text)
+	}
 
 	// Convert history to OpenAI format
 	history := convertToOpenAIMessages(client.GetHistory())
@@ -176,6 +218,9 @@ func (s *Server) processCommand(client *ws.Client, cmd *ws.CommandPayload, reque
 
 		// Add assistant response to conversation history
 		client.AddToHistory("assistant", response.Text)
+		if s.learner != nil {
+			s.learner.Record(client.ID(), "assistant", response.Text)
+		}
 	}
 
 	// Reset status
@@ -183,10 +228,22 @@ func (s *Server) processCommand(client *ws.Client, cmd *ws.CommandPayload, reque
 	client.SendMessage(status)
 	log.Printf("[FLOW] Status reset to idle")
 
+	// Record where memories saved by these actions came from
+	if requestID == "" {
+		requestID = uuid.New().String()
+	}
+	ctx := memory.WithSource(context.Background(), memory.Source{
+		Origin:         memory.OriginInferred,
+		ConversationID: client.ID(),
+		RequestID:      requestID,
+		Utterance:      cmd.Text,
+		Model:          s.ai.Model(),
+	})
+
 	// Execute actions in background (memory saves, calendar events, etc.)
 	for _, action := range actions {
 		log.Printf("[FLOW] Spawning goroutine for action: %s", action.Type)
-		go s.executeActionAsync(client, action)
+		go s.executeActionAsync(ctx, client, action)
 	}
 	log.Printf("[FLOW] All action goroutines spawned, processCommand returning")
 }
@@ -216,30 +273,50 @@ func convertToOpenAIMessages(history []ws.ConversationMessage) []openai.ChatComp
 
 // Actions that return data the user wants to see/hear or trigger frontend behavior
 var queryActions = map[string]bool{
-	"GET_WEATHER":    true,
-	"SEARCH_POKEMON": true,
-	"STOP_LISTENING": true,
-	"LIST_REMINDERS": true,
-	"START_GAME":     true,
-	"GAME_MOVE":      true,
+	"GET_WEATHER":          true,
+	"SEARCH_POKEMON":       true,
+	"STOP_LISTENING":       true,
+	"LIST_REMINDERS":       true,
+	"FIND_FREE_TIME":       true,
+	"LIST_CALENDAR_EVENTS": true,
+	"START_GAME":           true,
+	"GAME_MOVE":            true,
 }
 
 // executeActionAsync runs an action in the background and notifies the client
-func (s *Server) executeActionAsync(client *ws.Client, action ai.Action) {
+func (s *Server) executeActionAsync(ctx context.Context, client *ws.Client, action ai.Action) {
 	log.Printf("[ACTION] Starting async execution: %s", action.Type)
 	start := time.Now()
 
-	result := s.actions.Execute(action)
+	result := s.actions.ExecuteContext(ctx, action)
 
 	elapsed := time.Since(start)
 
 	if !result.Success {
 		log.Printf("[ACTION] %s failed after %v: %s", action.Type, elapsed, result.Error)
+
+		// The user is asked how to resolve the failure; their answer needs it
+		switch data := result.Data.(type) {
+		case *calendar.ConflictError:
+			client.AddToHistory("assistant", conflictNote(action.Type, data))
+		case *actions.UnknownContactsResult:
+			client.AddToHistory("assistant", unknownContactsNote(action.Type, data))
+		}
+
 		actionMsg, _ := ws.NewMessage(ws.MessageTypeAction, result)
 		client.SendMessage(actionMsg)
 	} else {
 		log.Printf("[ACTION] %s completed successfully in %v", action.Type, elapsed)
 
+		// Results the user may follow up on ("book the second one", "move
+		// the first one") go into the conversation
+		switch data := result.Data.(type) {
+		case *actions.FreeTimeResult:
+			client.AddToHistory("assistant", freeTimeNote(data))
+		case *actions.CalendarEventsResult:
+			client.AddToHistory("assistant", calendarEventsNote(data))
+		}
+
 		// For query actions (weather, pokemon), send the result back to the client
 		if queryActions[action.Type] {
 			log.Printf("[ACTION] Sending query result to client for: %s", action.Type)
@@ -260,12 +337,16 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 
 // handleStatus returns system status
 func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	auth := s.calendar.AuthStatus()
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":             "ready",
-		"connections":        s.hub.ClientCount(),
-		"ai_status":          "ready",
-		"calendar_connected": s.calendar.IsInitialized(),
+		"status":                   "ready",
+		"connections":              s.hub.ClientCount(),
+		"ai_status":                "ready",
+		"embedding_model":          s.ai.EmbeddingModel(),
+		"calendar_connected":       s.calendar.IsInitialized(),
+		"calendar_backend":         s.calendar.BackendName(),
+		"calendar_reauth_required": auth.ReauthRequired,
 	})
 }
 
@@ -287,6 +368,7 @@ func (s *Server) handleCreateMemory(w http.ResponseWriter, r *http.Request) {
 		Content    string   `json:"content"`
 		Importance float64  `json:"importance"`
 		Tags       []string `json:"tags"`
+		ExpiresAt  string   `json:"expires_at"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -294,7 +376,19 @@ func (s *Server) handleCreateMemory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	memory, err := s.memory.Create(r.Context(), req.Content, req.Importance, req.Tags)
+	opts := memory.CreateOptions{
+		Source: &memory.Source{Origin: memory.OriginUser},
+	}
+	if req.ExpiresAt != "" {
+		t, err := time.Parse(time.RFC3339, req.ExpiresAt)
+		if err != nil {
+			http.Error(w, "invalid expires_at format, use RFC3339", http.StatusBadRequest)
+			return
+		}
+		opts.ExpiresAt = &t
+	}
+
+	memory, err := s.memory.CreateWithOptions(r.Context(), req.Content, req.Importance, req.Tags, opts)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -305,18 +399,224 @@ func (s *Server) handleCreateMemory(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(memory)
 }
 
-// handleListCalendarEvents returns calendar events
-func (s *Server) handleListCalendarEvents(w http.ResponseWriter, r *http.Request) {
-	events, err := s.calendar.ListEvents(r.Context())
+// handleGetMemorySource returns where a memory came from
+func (s *Server) handleGetMemorySource(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	source, err := s.memory.GetSource(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Memory not found", http.StatusNotFound)
+		return
+	}
+	if source == nil {
+		http.Error(w, "No source recorded for this memory", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(source)
+}
+
+// handleListConversationMemories returns the memories created during a conversation
+func (s *Server) handleListConversationMemories(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	memories, err := s.memory.ListByConversation(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(memories)
+}
+
+// handleListLearnedMemories returns the memories extracted from a conversation after it went idle
+func (s *Server) handleListLearnedMemories(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	memories, err := s.memory.ListByConversation(r.Context(), id, memory.OriginExtracted)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(memories)
+}
+
+// handleDeleteConversationMemories deletes everything the model inferred during a conversation
+func (s *Server) handleDeleteConversationMemories(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	deleted, err := s.memory.DeleteInferredByConversation(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"deleted": deleted,
+	})
+}
+
+// handleExportMemories streams all memories as JSON Lines or Markdown
+func (s *Server) handleExportMemories(w http.ResponseWriter, r *http.Request) {
+	stamp := time.Now().Format("2006-01-02")
+
+	if r.URL.Query().Get("format") == "markdown" {
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="pika-memories-%s.md"`, stamp))
+		if err := s.memory.ExportMarkdown(r.Context(), w); err != nil {
+			log.Printf("Memory export failed: %v", err)
+		}
+		return
+	}
+
+	withEmbeddings := r.URL.Query().Get("embeddings") == "true"
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="pika-memories-%s.jsonl"`, stamp))
+	if err := s.memory.Export(r.Context(), w, withEmbeddings); err != nil {
+		log.Printf("Memory export failed: %v", err)
+	}
+}
+
+// handleImportMemories imports a JSON Lines memory export from the request
+// body. Knowledge is extracted from the imported memories in the background.
+func (s *Server) handleImportMemories(w http.ResponseWriter, r *http.Request) {
+	mode := r.URL.Query().Get("mode")
+
+	result, err := s.memory.Import(r.Context(), r.Body, mode)
+	if result == nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// A bad record stops the import; report what was imported before it
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// handleListEntities returns knowledge graph entities matching ?q= and ?type=
+func (s *Server) handleListEntities(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = l
+	}
+
+	entities, err := s.knowledge.List(r.Context(), r.URL.Query().Get("q"), r.URL.Query().Get("type"), limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entities)
+}
+
+// handleGetEntity returns an entity with its relations and source memories
+func (s *Server) handleGetEntity(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	entity, err := s.knowledge.Get(r.Context(), id)
+	if err != nil {
+		http.Error(w, "entity not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entity)
+}
+
+// handleGetFacts returns the knowledge graph facts PIKA would use for ?text=
+func (s *Server) handleGetFacts(w http.ResponseWriter, r *http.Request) {
+	facts, err := s.knowledge.FactsForText(r.Context(), r.URL.Query().Get("text"), 50)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(facts)
+}
+
+// handleListCalendarEvents returns the upcoming calendar events, or with
+// from, to, q or calendar parameters the events matching them. from and to
+// are RFC3339 times or dates and default to now and a week later.
+func (s *Server) handleListCalendarEvents(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	if params.Get("from") == "" && params.Get("to") == "" && params.Get("q") == "" && params.Get("calendar") == "" {
+		events, err := s.calendar.ListEvents(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(events)
+		return
+	}
+
+	q := calendar.EventQuery{
+		From:     time.Now(),
+		Text:     params.Get("q"),
+		Calendar: params.Get("calendar"),
+	}
+	if v := params.Get("from"); v != "" {
+		t, err := parseQueryTime(v)
+		if err != nil {
+			http.Error(w, "Invalid from: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		q.From = t
+	}
+	q.To = q.From.AddDate(0, 0, 7)
+	if v := params.Get("to"); v != "" {
+		t, err := parseQueryTime(v)
+		if err != nil {
+			http.Error(w, "Invalid to: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		q.To = t
+	}
+	if v := params.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		q.Limit = limit
+	}
+
+	events, err := s.calendar.SearchEvents(r.Context(), q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if events == nil {
+		events = []*calendar.Event{}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(events)
 }
 
+// parseQueryTime parses an RFC3339 time or a date, taken as local midnight
+func parseQueryTime(v string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", v, time.Local); err == nil {
+		return t, nil
+	}
+	// An unescaped "+" in the offset arrives as a space
+	return time.Parse(time.RFC3339, strings.Replace(v, " ", "+", 1))
+}
+
 // handleCreateCalendarEvent creates a calendar event
 func (s *Server) handleCreateCalendarEvent(w http.ResponseWriter, r *http.Request) {
 	var req struct {
@@ -325,6 +625,11 @@ func (s *Server) handleCreateCalendarEvent(w http.ResponseWriter, r *http.Reques
 		StartTime   string `json:"start_time"`
 		EndTime     string `json:"end_time"`
 		Location    string `json:"location"`
+		Calendar    string `json:"calendar"` // ID or name; empty for the default
+		// AllowConflicts books the event even when it overlaps others
+		AllowConflicts bool                 `json:"allow_conflicts"`
+		Recurrence     string               `json:"recurrence"` // RRULE, e.g. "FREQ=WEEKLY;BYDAY=MO"
+		Attendees      []*calendar.Attendee `json:"attendees"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -332,7 +637,30 @@ func (s *Server) handleCreateCalendarEvent(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	event, err := s.calendar.CreateEvent(r.Context(), req.Title, req.Description, req.StartTime, req.EndTime, req.Location)
+	opts := calendar.CreateOptions{Calendar: req.Calendar, AllowConflicts: req.AllowConflicts, Attendees: req.Attendees}
+	if req.Recurrence != "" {
+		recurrence, err := calendar.ParseRecurrence(req.Recurrence)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		opts.Recurrence = recurrence
+	}
+	for _, a := range req.Attendees {
+		if a == nil || a.Email == "" {
+			http.Error(w, "attendees need an email address", http.StatusBadRequest)
+			return
+		}
+	}
+
+	event, err := s.calendar.CreateEventWithOptions(r.Context(), req.Title, req.Description, req.StartTime, req.EndTime, req.Location, opts)
+	var conflict *calendar.ConflictError
+	if errors.As(err, &conflict) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(conflict)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -343,21 +671,228 @@ func (s *Server) handleCreateCalendarEvent(w http.ResponseWriter, r *http.Reques
 	json.NewEncoder(w).Encode(event)
 }
 
+// handleListCalendars returns the calendars of the account
+func (s *Server) handleListCalendars(w http.ResponseWriter, r *http.Request) {
+	calendars, err := s.calendar.Calendars(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if calendars == nil {
+		calendars = []*calendar.Calendar{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(calendars)
+}
+
+// handleUpdateCalendar chooses whether a calendar is synced and whether it
+// is the default for new events
+func (s *Server) handleUpdateCalendar(w http.ResponseWriter, r *http.Request) {
+	// Google calendar IDs contain characters such as '#' that arrive escaped
+	id, err := url.PathUnescape(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid calendar ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Selected *bool `json:"selected"`
+		Default  *bool `json:"default"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cal, err := s.calendar.UpdateCalendar(r.Context(), id, req.Selected, req.Default)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cal)
+}
+
+// handleCalendarSyncStatus reports when the calendar was last synced and
+// the last sync error
+func (s *Server) handleCalendarSyncStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := s.calendar.SyncStatus(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// handleCalendarAuthStatus reports whether the calendar is authorized and
+// whether Google Calendar has to be connected again
+func (s *Server) handleCalendarAuthStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.calendar.AuthStatus())
+}
+
+// handleSyncCalendar syncs the calendar now and returns the sync status.
+// A failed sync answers 502 with the error in the status.
+func (s *Server) handleSyncCalendar(w http.ResponseWriter, r *http.Request) {
+	if !s.calendar.IsInitialized() {
+		http.Error(w, "Calendar is not connected", http.StatusConflict)
+		return
+	}
+
+	syncErr := s.calendar.Sync(r.Context())
+	status, err := s.calendar.SyncStatus(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if syncErr != nil {
+		if status.LastError == "" {
+			status.LastError = syncErr.Error()
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	json.NewEncoder(w).Encode(status)
+}
+
+// maxCalendarImport limits the size of an imported iCalendar file
+const maxCalendarImport = 10 << 20
+
+// handleExportCalendar downloads every calendar event as an iCalendar file
+func (s *Server) handleExportCalendar(w http.ResponseWriter, r *http.Request) {
+	data, err := s.calendar.Export(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="pika.ics"`)
+	w.Write([]byte(data))
+}
+
+// handleImportCalendar adds the events of an iCalendar file, sent either as
+// the request body or as the "file" field of a multipart form
+func (s *Server) handleImportCalendar(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxCalendarImport)
+
+	var data []byte
+	var err error
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, ferr := r.FormFile("file")
+		if ferr != nil {
+			http.Error(w, "Missing file", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		data, err = io.ReadAll(file)
+	} else {
+		data, err = io.ReadAll(r.Body)
+	}
+	if err != nil {
+		http.Error(w, "Failed to read calendar file", http.StatusBadRequest)
+		return
+	}
+
+	result, err := s.calendar.Import(r.Context(), string(data))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// calendarFeedURLs returns the http and webcal URLs of the ICS feed
+func calendarFeedURLs(r *http.Request, token string) map[string]string {
+	path := r.Host + "/calendar/feed/" + token + ".ics"
+	return map[string]string{
+		"url":        "http://" + path,
+		"webcal_url": "webcal://" + path,
+	}
+}
+
+// handleGetCalendarFeed returns the URL other apps can subscribe to
+func (s *Server) handleGetCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	token, err := s.calendar.FeedToken(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(calendarFeedURLs(r, token))
+}
+
+// handleRotateCalendarFeed replaces the feed URL, revoking the old one
+func (s *Server) handleRotateCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	token, err := s.calendar.RotateFeedToken(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(calendarFeedURLs(r, token))
+}
+
+// handleCalendarFeed serves the read-only ICS feed
+func (s *Server) handleCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	if !s.calendar.ValidFeedToken(r.Context(), chi.URLParam(r, "token")) {
+		http.NotFound(w, r)
+		return
+	}
+
+	data, err := s.calendar.Export(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Write([]byte(data))
+}
+
 // handleGoogleAuth initiates Google OAuth flow
 func (s *Server) handleGoogleAuth(w http.ResponseWriter, r *http.Request) {
-	url := s.calendar.GetAuthURL()
+	url, err := s.calendar.GetAuthURL()
+	if err != nil {
+		log.Printf("OAuth start error: %v", err)
+		http.Error(w, "Failed to start authorization", http.StatusInternalServerError)
+		return
+	}
+	if url == "" {
+		http.Error(w, "Google Calendar is not the configured calendar backend", http.StatusBadRequest)
+		return
+	}
 	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
 }
 
 // handleGoogleCallback handles OAuth callback
 func (s *Server) handleGoogleCallback(w http.ResponseWriter, r *http.Request) {
-	code := r.URL.Query().Get("code")
+	query := r.URL.Query()
+	if reason := query.Get("error"); reason != "" {
+		http.Error(w, "Google Calendar was not connected: "+reason, http.StatusBadRequest)
+		return
+	}
+	code := query.Get("code")
 	if code == "" {
 		http.Error(w, "Missing authorization code", http.StatusBadRequest)
 		return
 	}
 
-	if err := s.calendar.ExchangeCode(r.Context(), code); err != nil {
+	err := s.calendar.ExchangeCode(r.Context(), query.Get("state"), code)
+	if errors.Is(err, calendar.ErrInvalidState) {
+		http.Error(w, "Authorization request expired or unknown, connect Google Calendar again", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
 		log.Printf("OAuth exchange error: %v", err)
 		http.Error(w, "Failed to exchange authorization code", http.StatusInternalServerError)
 		return
@@ -414,6 +949,25 @@ func (s *Server) handleGoogleCallback(w http.ResponseWriter, r *http.Request) {
 </html>`))
 }
 
+// handleGoogleDisconnect revokes PIKA's access to Google Calendar and
+// forgets the tokens. The tokens are forgotten even when the revocation
+// fails, which answers 502.
+func (s *Server) handleGoogleDisconnect(w http.ResponseWriter, r *http.Request) {
+	if s.calendar.BackendName() != calendar.BackendGoogle {
+		http.Error(w, "Google Calendar is not the configured calendar backend", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.calendar.Disconnect(r.Context()); err != nil {
+		log.Printf("Google disconnect error: %v", err)
+		http.Error(w, "Failed to disconnect Google Calendar: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.calendar.AuthStatus())
+}
+
 // handleOpenURL opens a URL in the system browser
 func (s *Server) handleOpenURL(w http.ResponseWriter, r *http.Request) {
 	body, err := io.ReadAll(r.Body)
@@ -450,11 +1004,26 @@ func (s *Server) handleReset(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// handleListReminders returns all reminders
+// handleListReminders returns reminders, filtered with ?due=today|week|overdue,
+// ?list=, ?priority=, ?tag= and ?include_completed=true
 func (s *Server) handleListReminders(w http.ResponseWriter, r *http.Request) {
-	includeCompleted := r.URL.Query().Get("include_completed") == "true"
+	q := r.URL.Query()
+	filter := reminder.Filter{
+		IncludeCompleted: q.Get("include_completed") == "true",
+		Due:              q.Get("due"),
+		List:             q.Get("list"),
+		Priority:         q.Get("priority"),
+		Tag:              q.Get("tag"),
+	}
+	if q.Get("overdue") == "true" {
+		filter.Due = reminder.DueOverdue
+	}
+	if err := filter.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	reminders, err := s.reminder.List(r.Context(), includeCompleted)
+	reminders, err := s.reminder.Query(r.Context(), filter)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -467,9 +1036,19 @@ func (s *Server) handleListReminders(w http.ResponseWriter, r *http.Request) {
 // handleCreateReminder creates a new reminder
 func (s *Server) handleCreateReminder(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		Title       string `json:"title"`
-		Description string `json:"description"`
-		RemindAt    string `json:"remind_at"`
+		Title        string   `json:"title"`
+		Description  string   `json:"description"`
+		RemindAt     string   `json:"remind_at"`
+		Recurrence   string   `json:"recurrence"`
+		NotifyBefore []string `json:"notify_before"`
+		NagMinutes   int      `json:"nag_minutes"`
+		Priority     string   `json:"priority"`
+		List         string   `json:"list"`
+		Tags         []string `json:"tags"`
+		// EventID anchors the reminder Before the start of a calendar event
+		// instead of at RemindAt
+		EventID string `json:"event_id"`
+		Before  string `json:"before"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -477,18 +1056,63 @@ func (s *Server) handleCreateReminder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Title == "" || req.RemindAt == "" {
-		http.Error(w, "title and remind_at are required", http.StatusBadRequest)
+	if req.Title == "" || (req.RemindAt == "" && req.EventID == "") {
+		http.Error(w, "title and remind_at or event_id are required", http.StatusBadRequest)
 		return
 	}
 
-	remindAt, err := time.Parse(time.RFC3339, req.RemindAt)
-	if err != nil {
+	var opts reminder.CreateOptions
+	var remindAt time.Time
+	var err error
+	if req.EventID != "" {
+		event, err := s.calendar.GetEventByID(r.Context(), req.EventID)
+		if err != nil {
+			http.Error(w, "event not found", http.StatusNotFound)
+			return
+		}
+		var offset time.Duration
+		if req.Before != "" {
+			if offset, err = reminder.ParseOffset(req.Before); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		opts.Event = &reminder.EventAnchor{EventID: event.ID, Offset: offset}
+		remindAt = opts.Event.RemindAt(event.StartTime)
+	} else if remindAt, err = time.Parse(time.RFC3339, req.RemindAt); err != nil {
 		http.Error(w, "invalid remind_at format, use RFC3339", http.StatusBadRequest)
 		return
 	}
 
-	reminder, err := s.reminder.Create(r.Context(), req.Title, req.Description, remindAt)
+	if req.Recurrence != "" {
+		rule, err := rrule.Parse(req.Recurrence, time.Local)
+		if err != nil {
+			http.Error(w, "invalid recurrence: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		opts.Recurrence = rule
+	}
+	if len(req.NotifyBefore) > 0 {
+		offsets, err := reminder.ParseOffsetList(req.NotifyBefore)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		opts.Offsets = offsets
+	}
+	if req.NagMinutes < 0 {
+		http.Error(w, "nag_minutes cannot be negative", http.StatusBadRequest)
+		return
+	}
+	opts.NagMinutes = req.NagMinutes
+	if opts.Priority, err = reminder.ParsePriority(req.Priority); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts.List = req.List
+	opts.Tags = req.Tags
+
+	created, err := s.reminder.CreateWithOptions(r.Context(), req.Title, req.Description, remindAt, opts)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -496,7 +1120,7 @@ func (s *Server) handleCreateReminder(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(reminder)
+	json.NewEncoder(w).Encode(created)
 }
 
 // handleGetReminder returns a specific reminder
@@ -518,9 +1142,14 @@ func (s *Server) handleUpdateReminder(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
 	var req struct {
-		Title       *string `json:"title"`
-		Description *string `json:"description"`
-		RemindAt    *string `json:"remind_at"`
+		Title        *string  `json:"title"`
+		Description  *string  `json:"description"`
+		RemindAt     *string  `json:"remind_at"`
+		NotifyBefore []string `json:"notify_before"`
+		NagMinutes   *int     `json:"nag_minutes"`
+		Priority     *string  `json:"priority"`
+		List         *string  `json:"list"`
+		Tags         []string `json:"tags"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -538,14 +1167,43 @@ func (s *Server) handleUpdateReminder(w http.ResponseWriter, r *http.Request) {
 		remindAt = &t
 	}
 
-	reminder, err := s.reminder.Update(r.Context(), id, req.Title, req.Description, remindAt)
+	if req.Priority != nil {
+		if _, err := reminder.ParsePriority(*req.Priority); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.NagMinutes != nil && *req.NagMinutes < 0 {
+		http.Error(w, "nag_minutes cannot be negative", http.StatusBadRequest)
+		return
+	}
+
+	var offsets []time.Duration
+	if req.NotifyBefore != nil {
+		var err error
+		if offsets, err = reminder.ParseOffsetList(req.NotifyBefore); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	updated, err := s.reminder.Edit(r.Context(), id, reminder.Changes{
+		Title:       req.Title,
+		Description: req.Description,
+		RemindAt:    remindAt,
+		Offsets:     offsets,
+		NagMinutes:  req.NagMinutes,
+		Priority:    req.Priority,
+		List:        req.List,
+		Tags:        req.Tags,
+	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(reminder)
+	json.NewEncoder(w).Encode(updated)
 }
 
 // handleDeleteReminder deletes a reminder
@@ -564,15 +1222,72 @@ func (s *Server) handleDeleteReminder(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleCompleteReminder(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
-	if err := s.reminder.MarkCompleted(r.Context(), id); err != nil {
+	rem, err := s.reminder.Complete(r.Context(), id)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	if !rem.Completed {
+		// Recurring reminder rolled to its next occurrence
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":   "rescheduled",
+			"reminder": rem,
+		})
+		return
+	}
 	json.NewEncoder(w).Encode(map[string]string{"status": "completed"})
 }
 
+// handleSnoozeReminder postpones the next announcement of a reminder
+func (s *Server) handleSnoozeReminder(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	// The body is optional; without minutes the default snooze is used
+	var req struct {
+		Minutes int `json:"minutes"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Minutes < 0 {
+		http.Error(w, "minutes cannot be negative", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := s.reminder.Get(r.Context(), id); err != nil {
+		http.Error(w, "reminder not found", http.StatusNotFound)
+		return
+	}
+
+	snoozed, err := s.reminder.Snooze(r.Context(), id, time.Duration(req.Minutes)*time.Minute)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snoozed)
+}
+
+// handleAcknowledgeReminder stops a reminder from nagging
+func (s *Server) handleAcknowledgeReminder(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	acknowledged, err := s.reminder.Acknowledge(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(acknowledged)
+}
+
 // handleGameMove processes a game move directly (bypasses AI)
 func (s *Server) handleGameMove(w http.ResponseWriter, r *http.Request) {
 	var req struct {
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/fs"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/baswilson/pika/internal/actions"
@@ -13,6 +14,8 @@ import (
 	"github.com/baswilson/pika/internal/calendar"
 	"github.com/baswilson/pika/internal/config"
 	"github.com/baswilson/pika/internal/database"
+	"github.com/baswilson/pika/internal/knowledge"
+	"github.com/baswilson/pika/internal/learning"
 	"github.com/baswilson/pika/internal/memory"
 	"github.com/baswilson/pika/internal/nudge"
 	"github.com/baswilson/pika/internal/reminder"
@@ -29,6 +32,9 @@ type Server struct {
 	dbDriver          *database.SQLiteDriver
 	ai                *ai.Service
 	memory            *memory.Store
+	memoryPurger      *memory.ExpiryPurger
+	learner           *learning.Learner
+	knowledge         *knowledge.Store
 	calendar          *calendar.Service
 	reminder          *reminder.Store
 	reminderScheduler *reminder.Scheduler
@@ -55,14 +61,37 @@ func New(cfg *config.Config, webFS fs.FS) (*Server, error) {
 
 	// Create services
 	memoryStore := memory.NewStore(db)
+	knowledgeStore := knowledge.NewStore(db)
 	aiService := ai.NewService(cfg, memoryStore)
 	calendarService := calendar.NewService(cfg, db)
 	reminderStore := reminder.NewStore(db)
+	if offsets, err := reminder.ParseOffsets(cfg.ReminderOffsets); err != nil {
+		log.Printf("Warning: invalid REMINDER_OFFSETS %q, using defaults: %v", cfg.ReminderOffsets, err)
+	} else {
+		reminderStore.SetDefaultOffsets(offsets)
+	}
 	reminderScheduler := reminder.NewScheduler(reminderStore)
 	actionsRegistry := actions.NewRegistry(memoryStore, calendarService, reminderStore)
 
 	// Wire up embedding generator for semantic memory search
 	memoryStore.SetEmbedder(aiService)
+	warnStaleEmbeddings(memoryStore, aiService.EmbeddingModel())
+
+	// Extract entities from new memories and ground prompts in known facts
+	knowledgeStore.SetExtractor(aiService)
+	knowledgeStore.Attach(memoryStore)
+	aiService.SetKnowledge(knowledgeStore)
+
+	// Purge temporary memories once they expire
+	memoryPurger := memory.NewExpiryPurger(memoryStore, 15*time.Minute)
+	memoryPurger.Start()
+
+	// Review idle conversations for memories the chat model did not save
+	var learner *learning.Learner
+	if cfg.MemoryExtractionIdle > 0 {
+		learner = learning.NewLearner(memoryStore, aiService, time.Duration(cfg.MemoryExtractionIdle)*time.Minute)
+		learner.Start()
+	}
 
 	// Connect calendar to AI service for context
 	aiService.SetCalendar(&calendarAdapter{calendarService})
@@ -74,10 +103,15 @@ func New(cfg *config.Config, webFS fs.FS) (*Server, error) {
 	// Set up calendar reminders to broadcast to all clients
 	calendarService.SetReminderCallback(func(event *calendar.Event, minutesBefore int) {
 		var message string
-		if minutesBefore == 5 {
+		switch {
+		case event.AllDay:
+			message = allDayAlertMessage(event)
+		case minutesBefore == 0:
+			message = fmt.Sprintf("Heads up! '%s' is starting now.", event.Title)
+		case minutesBefore == 5:
 			message = fmt.Sprintf("Heads up! '%s' starts in 5 minutes.", event.Title)
-		} else {
-			message = fmt.Sprintf("Reminder: '%s' starts in %d minutes.", event.Title, minutesBefore)
+		default:
+			message = fmt.Sprintf("Reminder: '%s' starts in %s.", event.Title, formatLeadTime(minutesBefore))
 		}
 		if event.Location != "" {
 			message += fmt.Sprintf(" Location: %s", event.Location)
@@ -95,6 +129,24 @@ func New(cfg *config.Config, webFS fs.FS) (*Server, error) {
 		}
 	})
 
+	// Move reminders anchored to events with them, and flag those whose event is deleted
+	calendarService.SetChangeCallback(func(change calendar.EventChange) {
+		followEventChange(reminderStore, hub, change)
+	})
+
+	// Say so when Google Calendar has to be connected again
+	calendarService.SetReauthCallback(func(err error) {
+		if msg, err := ws.NewResponse("I've lost access to your Google Calendar. Please connect it again so I can keep your schedule in sync.", "alert"); err == nil {
+			hub.BroadcastMessage(msg)
+		}
+		if msg, err := ws.NewMessage(ws.MessageTypeAction, ws.ActionPayload{
+			ActionType: "GOOGLE_REAUTH_REQUIRED",
+			Success:    true,
+		}); err == nil {
+			hub.BroadcastMessage(msg)
+		}
+	})
+
 	// Start calendar background sync
 	calendarService.StartBackgroundSync()
 
@@ -106,6 +158,16 @@ func New(cfg *config.Config, webFS fs.FS) (*Server, error) {
 		switch tier {
 		case "at_time":
 			message = fmt.Sprintf("Reminder: %s - it's time!", r.Title)
+		case reminder.TierSnooze:
+			message = fmt.Sprintf("Reminder: %s - your snooze is over.", r.Title)
+		case reminder.TierNag:
+			message = fmt.Sprintf("Reminder: %s - still waiting on this one.", r.Title)
+		case reminder.TierMissed:
+			if timeUntil > 0 {
+				message = fmt.Sprintf("While you were away: '%s' is coming up in %s.", r.Title, timeStr)
+			} else {
+				message = fmt.Sprintf("You missed a reminder while you were away: %s, due %s.", r.Title, r.RemindAt.Local().Format("Mon 3:04 PM"))
+			}
 		default:
 			message = fmt.Sprintf("Reminder: '%s' in %s.", r.Title, timeStr)
 		}
@@ -119,6 +181,7 @@ func New(cfg *config.Config, webFS fs.FS) (*Server, error) {
 			"title":       r.Title,
 			"remind_at":   r.RemindAt,
 			"tier":        tier,
+			"priority":    r.Priority,
 		})
 		if err == nil {
 			hub.BroadcastMessage(msg)
@@ -126,6 +189,9 @@ func New(cfg *config.Config, webFS fs.FS) (*Server, error) {
 		}
 	})
 
+	// Keep the scheduler's queue in step with reminder changes
+	reminderStore.SetChangeHook(reminderScheduler.Reschedule)
+
 	// Start reminder scheduler
 	reminderScheduler.Start()
 
@@ -149,6 +215,9 @@ func New(cfg *config.Config, webFS fs.FS) (*Server, error) {
 		dbDriver:          driver,
 		ai:                aiService,
 		memory:            memoryStore,
+		memoryPurger:      memoryPurger,
+		learner:           learner,
+		knowledge:         knowledgeStore,
 		calendar:          calendarService,
 		reminder:          reminderStore,
 		reminderScheduler: reminderScheduler,
@@ -178,6 +247,16 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		s.reminderScheduler.Stop()
 	}
 
+	// Stop memory expiry purger
+	if s.memoryPurger != nil {
+		s.memoryPurger.Stop()
+	}
+
+	// Stop memory learner
+	if s.learner != nil {
+		s.learner.Stop()
+	}
+
 	// Stop nudge scheduler
 	if s.nudgeScheduler != nil {
 		s.nudgeScheduler.Stop()
@@ -210,6 +289,208 @@ func (s *Server) BroadcastTrigger(triggerType, title, message string, data inter
 	return s.hub.BroadcastMessage(msg)
 }
 
+// warnStaleEmbeddings logs when memories are embedded with a model other than
+// the active one. Those memories are invisible to vector search until they are
+// re-embedded with cmd/backfill.
+func warnStaleEmbeddings(store *memory.Store, activeModel string) {
+	stats, err := store.EmbeddingStats(context.Background())
+	if err != nil {
+		log.Printf("Warning: failed to read embedding stats: %v", err)
+		return
+	}
+
+	stale := 0
+	for model, count := range stats {
+		if model != activeModel {
+			stale += count
+		}
+	}
+	if stale > 0 {
+		log.Printf("Warning: %d memories are not embedded with %s; run cmd/backfill to re-embed them", stale, activeModel)
+	}
+}
+
+// followEventChange moves the reminders anchored to a calendar event that
+// moved, or flags them and tells the user when the event was deleted
+func followEventChange(store *reminder.Store, hub *ws.Hub, change calendar.EventChange) {
+	ctx := context.Background()
+
+	if !change.Deleted {
+		moved, err := store.FollowEvent(ctx, change.Event.ID, change.Event.StartTime)
+		if err != nil {
+			log.Printf("Error moving reminders for event %s: %v", change.Event.Title, err)
+		}
+		for _, r := range moved {
+			log.Printf("Reminder %s moved with event %s to %s", r.Title, change.Event.Title, r.RemindAt.Format(time.RFC3339))
+		}
+		return
+	}
+
+	flagged, err := store.FlagEventDeleted(ctx, change.Event.ID)
+	if err != nil {
+		log.Printf("Error flagging reminders for deleted event %s: %v", change.Event.Title, err)
+	}
+	for _, r := range flagged {
+		message := fmt.Sprintf("'%s' was removed from your calendar. Your reminder '%s' is still set for %s - shall I delete it?",
+			change.Event.Title, r.Title, r.RemindAt.Local().Format("Mon 3:04 PM"))
+		msg, err := ws.NewTrigger("reminder", "Event Deleted", message, map[string]interface{}{
+			"reminder_id": r.ID,
+			"event_id":    change.Event.ID,
+			"title":       r.Title,
+			"tier":        "event_deleted",
+		})
+		if err == nil {
+			hub.BroadcastMessage(msg)
+			log.Printf("Reminder %s flagged: event %s was deleted", r.Title, change.Event.Title)
+		}
+	}
+}
+
+// allDayAlertMessage announces an all-day event on its day, or ahead of it
+// for an alarm of its own
+func allDayAlertMessage(event *calendar.Event) string {
+	// All-day events are stored at midnight UTC of their date
+	y, m, d := event.StartTime.UTC().Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	switch {
+	case !day.After(today):
+		return fmt.Sprintf("Today: '%s'.", event.Title)
+	case day.Equal(today.AddDate(0, 0, 1)):
+		return fmt.Sprintf("Reminder: '%s' is tomorrow.", event.Title)
+	default:
+		return fmt.Sprintf("Reminder: '%s' is on %s.", event.Title, day.Format("Monday, January 2"))
+	}
+}
+
+// formatLeadTime describes how long before an event an alert fires,
+// e.g. "15 minutes", "2 hours" or "1 day"
+func formatLeadTime(minutes int) string {
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return "1 " + unit
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+	switch {
+	case minutes >= 24*60 && minutes%(24*60) == 0:
+		return plural(minutes/(24*60), "day")
+	case minutes >= 60 && minutes%60 == 0:
+		return plural(minutes/60, "hour")
+	default:
+		return plural(minutes, "minute")
+	}
+}
+
+// freeTimeNote describes found slots for the conversation history, with
+// exact times so that a follow-up like "book the second one" can be saved
+func freeTimeNote(result *actions.FreeTimeResult) string {
+	if len(result.Slots) == 0 {
+		return fmt.Sprintf("[No free slot for %s found between %s and %s]",
+			formatLeadTime(result.DurationMinutes), result.From.Local().Format("Mon Jan 2 3:04 PM"), result.To.Local().Format("Mon Jan 2 3:04 PM"))
+	}
+
+	parts := make([]string, len(result.Slots))
+	for i, slot := range result.Slots {
+		parts[i] = fmt.Sprintf("%d) %s (start_time %s, end_time %s)", i+1,
+			slot.Start.Local().Format("Mon Jan 2 3:04 PM"), slot.Start.Local().Format(time.RFC3339), slot.End.Local().Format(time.RFC3339))
+	}
+	return fmt.Sprintf("[Free slots for %s found: %s]", formatLeadTime(result.DurationMinutes), strings.Join(parts, "; "))
+}
+
+// calendarEventsNote lists found events for the conversation history, so
+// follow-up questions and changes can refer to them
+func calendarEventsNote(result *actions.CalendarEventsResult) string {
+	const layout = "Mon Jan 2 3:04 PM"
+	span := fmt.Sprintf("%s to %s", result.From.Local().Format(layout), result.To.Local().Format(layout))
+	if result.Query != "" {
+		span += fmt.Sprintf(" matching %q", result.Query)
+	}
+	if result.Calendar != "" {
+		span += fmt.Sprintf(" in calendar %q", result.Calendar)
+	}
+	if len(result.Events) == 0 {
+		return fmt.Sprintf("[No calendar events from %s]", span)
+	}
+
+	parts := make([]string, len(result.Events))
+	for i, e := range result.Events {
+		when := e.StartTime.Local().Format(layout) + " to " + e.EndTime.Local().Format("3:04 PM")
+		if e.AllDay {
+			when = e.StartTime.UTC().Format("Mon Jan 2") + ", all day"
+		}
+		parts[i] = fmt.Sprintf("%d) %q %s (event_id %s)", i+1, e.Title, when, e.ID)
+		if len(e.Recurrence) > 0 {
+			// Occurrences share the event_id of their series
+			parts[i] += fmt.Sprintf(", repeats, occurrence_start %s", e.StartTime.Local().Format(time.RFC3339))
+		} else if e.SeriesID != "" {
+			parts[i] += ", repeats"
+		}
+		if e.Location != "" {
+			parts[i] += " at " + e.Location
+		}
+		if len(e.Attendees) > 0 {
+			parts[i] += " with " + strings.Join(attendeeNames(e.Attendees), ", ")
+		}
+	}
+	return fmt.Sprintf("[Calendar events from %s: %s]", span, strings.Join(parts, "; "))
+}
+
+// attendeeNames lists attendees by name, or email address when unnamed,
+// with their response when known
+func attendeeNames(attendees []*calendar.Attendee) []string {
+	names := make([]string, 0, len(attendees))
+	for _, a := range attendees {
+		name := a.Name
+		if name == "" {
+			name = a.Email
+		}
+		if a.Status != "" && a.Status != calendar.ResponseNeedsAction {
+			name += " (" + a.Status + ")"
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// unknownContactsNote asks the model to get the email addresses of
+// attendees that could not be invited
+func unknownContactsNote(actionType string, result *actions.UnknownContactsResult) string {
+	return fmt.Sprintf("[%s not done: no email address is known for %s. Ask the user for it, save it with SAVE_MEMORY "+
+		"and then repeat %s with the address itself in attendees]", actionType, strings.Join(result.Names, " and "), actionType)
+}
+
+// conflictNote describes a calendar conflict for the conversation history,
+// so the user's answer to "book anyway, take the next free time or move the
+// other event?" can be acted on
+func conflictNote(actionType string, conflict *calendar.ConflictError) string {
+	const layout = "Mon Jan 2 3:04 PM"
+	event := conflict.Event
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s not done: %q at %s overlaps with", actionType, event.Title, event.StartTime.Local().Format(layout))
+	for i, c := range conflict.Conflicts {
+		if i > 0 {
+			b.WriteString(" and")
+		}
+		fmt.Fprintf(&b, " %q (event_id %s, %s to %s)", c.Title, c.ID, c.StartTime.Local().Format(layout), c.EndTime.Local().Format("3:04 PM"))
+	}
+	fmt.Fprintf(&b, ". Options: repeat %s with allow_conflicts true", actionType)
+	if slot := conflict.NextFree; slot != nil {
+		fmt.Fprintf(&b, "; or use the next free time, start_time %s, end_time %s",
+			slot.Start.Local().Format(time.RFC3339), slot.End.Local().Format(time.RFC3339))
+	}
+	if slot := conflict.MoveTo; slot != nil {
+		first := conflict.Conflicts[0]
+		fmt.Fprintf(&b, "; or move %q with EDIT_CALENDAR_EVENT event_id %s to start_time %s, end_time %s, then repeat %s with allow_conflicts true",
+			first.Title, first.ID, slot.Start.Local().Format(time.RFC3339), slot.End.Local().Format(time.RFC3339), actionType)
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
 // calendarAdapter adapts calendar.Service to ai.CalendarProvider interface
 type calendarAdapter struct {
 	svc *calendar.Service
@@ -228,11 +509,30 @@ func (a *calendarAdapter) ListEvents(ctx context.Context) ([]*ai.CalendarEvent,
 			StartTime: e.StartTime,
 			EndTime:   e.EndTime,
 			Location:  e.Location,
+			AllDay:    e.AllDay,
+			Calendar:  e.Calendar,
+			Recurring: len(e.Recurrence) > 0 || e.SeriesID != "",
+			Attendees: attendeeNames(e.Attendees),
 		}
 	}
 	return result, nil
 }
 
-func (a *calendarAdapter) IsInitialized() bool {
-	return a.svc.IsInitialized()
+func (a *calendarAdapter) ReauthRequired() bool {
+	return a.svc.AuthStatus().ReauthRequired
+}
+
+func (a *calendarAdapter) Calendars(ctx context.Context) ([]*ai.CalendarInfo, error) {
+	calendars, err := a.svc.Calendars(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*ai.CalendarInfo
+	for _, c := range calendars {
+		if c.Selected {
+			result = append(result, &ai.CalendarInfo{Name: c.Name, Default: c.Default})
+		}
+	}
+	return result, nil
 }
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
@@ -32,6 +33,7 @@ type ConversationMessage struct {
 
 // Client represents a WebSocket client connection
 type Client struct {
+	id         string
 	hub        *Hub
 	conn       *websocket.Conn
 	send       chan []byte
@@ -47,6 +49,7 @@ type MessageHandler func(client *Client, msg *Message)
 // NewClient creates a new WebSocket client
 func NewClient(hub *Hub, conn *websocket.Conn, handler MessageHandler) *Client {
 	return &Client{
+		id:         uuid.New().String(),
 		hub:        hub,
 		conn:       conn,
 		send:       make(chan []byte, 256),
@@ -72,8 +75,13 @@ func ServeWs(hub *Hub, handler MessageHandler, w http.ResponseWriter, r *http.Re
 	go client.writePump()
 	go client.readPump()
 
-	// Send initial status
-	status, _ := NewStatus("idle", true, "ready")
+	// Send initial status with the ID memories from this conversation are filed under
+	status, _ := NewMessage(MessageTypeStatus, StatusPayload{
+		Status:         "idle",
+		Connected:      true,
+		AIStatus:       "ready",
+		ConversationID: client.ID(),
+	})
 	data, _ := json.Marshal(status)
 	client.Send(data)
 }
@@ -181,6 +189,11 @@ func (c *Client) SendMessage(msg *Message) error {
 	return nil
 }
 
+// ID returns the conversation ID of this connection
+func (c *Client) ID() string {
+	return c.id
+}
+
 // GetFormat returns the client's preferred response format
 func (c *Client) GetFormat() ResponseFormat {
 	return c.format
@@ -72,6 +72,9 @@ type StatusPayload struct {
 	Status    string `json:"status"`    // listening, processing, speaking, idle
 	Connected bool   `json:"connected"` // WebSocket connection status
 	AIStatus  string `json:"ai_status"` // ready, busy, error
+	// ConversationID is sent once on connect; memories saved during the
+	// connection record it, so they can be listed or removed per conversation
+	ConversationID string `json:"conversation_id,omitempty"`
 }
 
 // TriggerPayload for PIKA-initiated interactions
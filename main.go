@@ -318,6 +318,10 @@ func (h *setupHandler) handleSaveConfig(w http.ResponseWriter, r *http.Request)
 		"google_client_id":     values["google_client_id"],
 		"google_client_secret": values["google_client_secret"],
 		"google_redirect_url":  "http://localhost:8080/auth/google/callback",
+		"calendar_backend":     "auto",
+		"caldav_url":           values["caldav_url"],
+		"caldav_username":      values["caldav_username"],
+		"caldav_password":      values["caldav_password"],
 		"memory_context_limit": "2000",
 		"memory_top_k":         "10",
 	}
@@ -615,6 +619,25 @@ func (h *setupHandler) serveSetupPage(w http.ResponseWriter) {
 				</div>
 			</div>
 
+			<div class="section">
+				<div class="section-title">CalDAV Calendar <span class="optional">(Optional, instead of Google)</span></div>
+
+				<div class="form-group">
+					<label>Calendar URL</label>
+					<input type="text" name="caldav_url" placeholder="https://caldav.fastmail.com/dav/calendars/user/you@example.com/Default/">
+				</div>
+
+				<div class="form-group">
+					<label>Username</label>
+					<input type="text" name="caldav_username">
+				</div>
+
+				<div class="form-group">
+					<label>App Password</label>
+					<input type="password" name="caldav_password">
+				</div>
+			</div>
+
 			<button type="submit">Save & Start PIKA</button>
 		</form>
 	</div>
@@ -115,6 +115,10 @@ class PikaApp {
 
         this.ws.on('status', (msg) => {
             const payload = msg.payload;
+            if (payload.conversation_id) {
+                // Memories saved in this conversation are filed under this ID
+                this.conversationId = payload.conversation_id;
+            }
             if (payload.status) {
                 this.setStatus(this.capitalizeFirst(payload.status));
             }
@@ -296,6 +300,23 @@ class PikaApp {
         if (payload.message) {
             this.speech.speak(payload.message);
         }
+
+        // Due reminders can be snoozed or acknowledged
+        const data = payload.data || {};
+        if (payload.trigger_type === 'reminder' && ['at_time', 'snooze', 'nag', 'missed'].includes(data.tier)) {
+            this.addReminderButtons(data.reminder_id);
+        }
+    }
+
+    addReminderButtons(reminderId) {
+        const id = this.escapeHtml(reminderId);
+        const html = `
+            <div class="flex justify-start gap-2">
+                <button onclick="respondToReminder('${id}', 'snooze', this)" class="bg-pika-400/10 border border-pika-400/30 text-pika-400 rounded-lg px-3 py-1 text-xs font-mono uppercase tracking-wider hover:bg-pika-400/20 transition-colors">Snooze 10m</button>
+                <button onclick="respondToReminder('${id}', 'acknowledge', this)" class="bg-black/50 border border-gray-800 text-gray-400 rounded-lg px-3 py-1 text-xs font-mono uppercase tracking-wider hover:text-gray-200 transition-colors">Got it</button>
+            </div>
+        `;
+        this.appendMessage(html);
     }
 
     // Orb state management
@@ -460,12 +481,36 @@ class PikaApp {
             return;
         }
 
+        // Handle Google Calendar access lost
+        if (success && actionType === 'GOOGLE_REAUTH_REQUIRED') {
+            showCalendarNotice(true);
+            return;
+        }
+
         // Handle list reminders
         if (success && actionType === 'LIST_REMINDERS') {
             this.displayRemindersResult(payload.data);
             return;
         }
 
+        // Handle free time search
+        if (success && actionType === 'FIND_FREE_TIME' && payload.data) {
+            this.displayFreeTimeResult(payload.data);
+            return;
+        }
+
+        // Handle calendar queries
+        if (success && actionType === 'LIST_CALENDAR_EVENTS' && payload.data) {
+            this.displayCalendarEventsResult(payload.data);
+            return;
+        }
+
+        // Handle calendar conflicts
+        if (!success && payload.data && payload.data.conflicts) {
+            this.displayConflictResult(payload.data);
+            return;
+        }
+
         // Handle game actions
         if (success && actionType === 'START_GAME' && payload.data) {
             this.displayGameUI(payload.data);
@@ -613,13 +658,17 @@ class PikaApp {
                         </svg>
                     </div>
                     <div class="flex-1">
-                        <div class="text-white font-medium text-sm">${this.escapeHtml(reminder.title)}</div>
-                        <div class="text-purple-300/60 text-xs mt-0.5">${timeStr}</div>
+                        <div class="text-white font-medium text-sm">${reminder.priority === 'high' ? '<span class="text-red-400">!</span> ' : ''}${this.escapeHtml(reminder.title)}${reminder.list ? ` <span class="text-xs text-purple-400/60 font-mono">${this.escapeHtml(reminder.list)}</span>` : ''}</div>
+                        <div class="text-purple-300/60 text-xs mt-0.5">${timeStr}${reminder.repeats ? ` &middot; ${this.escapeHtml(reminder.repeats)}` : ''}${reminder.overdue ? ' &middot; <span class="text-red-400">overdue</span>' : ''}${reminder.event_deleted ? ' &middot; <span class="text-yellow-400">event deleted</span>' : ''}</div>
                         ${reminder.description ? `<div class="text-purple-200/50 text-xs mt-1">${this.escapeHtml(reminder.description)}</div>` : ''}
                     </div>
                 </div>
             `;
-            speechParts.push(`${reminder.title}, ${timeStr}`);
+            let speech = reminder.repeats ? `${reminder.title}, ${timeStr}, repeating ${reminder.repeats}` : `${reminder.title}, ${timeStr}`;
+            if (reminder.overdue) {
+                speech += ', overdue';
+            }
+            speechParts.push(speech);
         });
 
         const html = `
@@ -641,6 +690,173 @@ class PikaApp {
         this.speech.speak(speechText);
     }
 
+    displayCalendarEventsResult(data) {
+        const events = data.events || [];
+        const timeOptions = { hour: 'numeric', minute: '2-digit', hour12: true };
+        const label = data.query ? `Events: ${this.escapeHtml(data.query)}` : 'Events';
+
+        if (events.length === 0) {
+            const html = `
+                <div class="flex justify-start">
+                    <div class="max-w-md w-full">
+                        <div class="text-xs text-purple-400/60 mb-1 font-mono uppercase tracking-wider">${label}</div>
+                        <div class="bg-gradient-to-br from-purple-900/30 to-purple-800/20 border border-purple-500/30 rounded-lg px-4 py-3">
+                            <p class="text-purple-200/80 text-sm">Nothing on the calendar.</p>
+                        </div>
+                    </div>
+                </div>
+            `;
+            this.appendMessage(html);
+            this.speech.speak(data.query ? `I couldn't find any events matching ${data.query}.` : "There's nothing on your calendar then.");
+            return;
+        }
+
+        let eventListHtml = '';
+        const speechParts = [];
+
+        events.forEach((event, index) => {
+            // All-day events are stored at midnight UTC of their date
+            const timeStr = event.all_day
+                ? `${new Date(event.start_time).toLocaleDateString('en-US', { weekday: 'short', month: 'short', day: 'numeric', timeZone: 'UTC' })}, all day`
+                : `${this.formatReminderTime(event.start_time)} until ${new Date(event.end_time).toLocaleTimeString('en-US', timeOptions)}`;
+            const repeats = (event.recurrence && event.recurrence.length > 0) || event.series_id;
+            const attendees = (event.attendees || [])
+                .map(a => (a.name || a.email) + (a.status && a.status !== 'needsAction' ? ` (${a.status})` : ''))
+                .join(', ');
+            eventListHtml += `
+                <div class="flex items-start gap-3 ${index > 0 ? 'mt-3 pt-3 border-t border-purple-500/20' : ''}">
+                    <div class="w-2 h-2 rounded-full mt-1.5" style="background-color: ${this.escapeHtml(event.color || '#a855f7')}"></div>
+                    <div class="flex-1">
+                        <div class="text-white font-medium text-sm">${this.escapeHtml(event.title)}${event.calendar ? ` <span class="text-xs text-purple-400/60 font-mono">${this.escapeHtml(event.calendar)}</span>` : ''}</div>
+                        <div class="text-purple-300/60 text-xs mt-0.5">${timeStr}${repeats ? ' &middot; repeats' : ''}${event.location ? ` &middot; ${this.escapeHtml(event.location)}` : ''}</div>
+                        ${attendees ? `<div class="text-purple-300/40 text-xs mt-0.5">with ${this.escapeHtml(attendees)}</div>` : ''}
+                    </div>
+                </div>
+            `;
+            speechParts.push(event.all_day ? `${event.title}, all day` : `${event.title}, ${this.formatReminderTime(event.start_time)}`);
+        });
+
+        const html = `
+            <div class="flex justify-start">
+                <div class="max-w-md w-full">
+                    <div class="text-xs text-purple-400/60 mb-1 font-mono uppercase tracking-wider">${label} (${events.length})</div>
+                    <div class="bg-gradient-to-br from-purple-900/30 to-purple-800/20 border border-purple-500/30 rounded-lg px-4 py-3">
+                        ${eventListHtml}
+                    </div>
+                </div>
+            </div>
+        `;
+        this.appendMessage(html);
+
+        const intro = events.length === 1 ? 'You have 1 event:' : `You have ${events.length} events:`;
+        this.speech.speak(`${intro} ${speechParts.join('. ')}`);
+    }
+
+    displayFreeTimeResult(data) {
+        const slots = data.slots || [];
+        const length = this.formatDuration(data.duration_minutes);
+
+        if (slots.length === 0) {
+            const html = `
+                <div class="flex justify-start">
+                    <div class="max-w-md w-full">
+                        <div class="text-xs text-purple-400/60 mb-1 font-mono uppercase tracking-wider">Free Time</div>
+                        <div class="bg-gradient-to-br from-purple-900/30 to-purple-800/20 border border-purple-500/30 rounded-lg px-4 py-3">
+                            <p class="text-purple-200/80 text-sm">No free slot of ${length} found.</p>
+                        </div>
+                    </div>
+                </div>
+            `;
+            this.appendMessage(html);
+            this.speech.speak(`I couldn't find ${length} free in that time.`);
+            return;
+        }
+
+        let slotListHtml = '';
+        const speechParts = [];
+
+        slots.forEach((slot, index) => {
+            const timeStr = this.formatReminderTime(slot.start);
+            const endStr = new Date(slot.end).toLocaleTimeString('en-US', { hour: 'numeric', minute: '2-digit', hour12: true });
+            slotListHtml += `
+                <div class="flex items-start gap-3 ${index > 0 ? 'mt-3 pt-3 border-t border-purple-500/20' : ''}">
+                    <div class="text-purple-400 font-mono text-sm mt-0.5">${index + 1}</div>
+                    <div class="flex-1">
+                        <div class="text-white font-medium text-sm">${timeStr}</div>
+                        <div class="text-purple-300/60 text-xs mt-0.5">until ${endStr}</div>
+                    </div>
+                </div>
+            `;
+            speechParts.push(`${index + 1}, ${timeStr}`);
+        });
+
+        const html = `
+            <div class="flex justify-start">
+                <div class="max-w-md w-full">
+                    <div class="text-xs text-purple-400/60 mb-1 font-mono uppercase tracking-wider">Free Time (${length})</div>
+                    <div class="bg-gradient-to-br from-purple-900/30 to-purple-800/20 border border-purple-500/30 rounded-lg px-4 py-3">
+                        ${slotListHtml}
+                    </div>
+                </div>
+            </div>
+        `;
+        this.appendMessage(html);
+
+        const intro = slots.length === 1 ? `You have ${length} free at one time:` : `Here are ${slots.length} times you have ${length} free:`;
+        this.speech.speak(`${intro} ${speechParts.join('. ')}. Which one should I book?`);
+    }
+
+    displayConflictResult(data) {
+        const event = data.event;
+        const first = data.conflicts[0];
+        const timeOptions = { hour: 'numeric', minute: '2-digit', hour12: true };
+
+        let conflictListHtml = '';
+        data.conflicts.forEach((conflict, index) => {
+            const endStr = new Date(conflict.end_time).toLocaleTimeString('en-US', timeOptions);
+            conflictListHtml += `
+                <div class="${index > 0 ? 'mt-2 pt-2 border-t border-yellow-500/20' : ''}">
+                    <div class="text-white font-medium text-sm">${this.escapeHtml(conflict.title)}</div>
+                    <div class="text-yellow-200/60 text-xs mt-0.5">${this.formatReminderTime(conflict.start_time)} until ${endStr}${conflict.calendar ? ` &middot; ${this.escapeHtml(conflict.calendar)}` : ''}</div>
+                </div>
+            `;
+        });
+
+        const options = ['book it anyway'];
+        if (data.next_free) {
+            options.push(`move it to ${this.formatReminderTime(data.next_free.start)}`);
+        }
+        if (data.move_to) {
+            options.push(`move ${first.title} to ${this.formatReminderTime(data.move_to.start)}`);
+        }
+        const question = `Should I ${options.slice(0, -1).join(', ')}${options.length > 1 ? ', or ' : ''}${options[options.length - 1]}?`;
+
+        const html = `
+            <div class="flex justify-start">
+                <div class="max-w-md w-full">
+                    <div class="text-xs text-yellow-400/60 mb-1 font-mono uppercase tracking-wider">Conflict</div>
+                    <div class="bg-gradient-to-br from-yellow-900/30 to-yellow-800/20 border border-yellow-500/30 rounded-lg px-4 py-3">
+                        <p class="text-yellow-200/80 text-sm mb-2">${this.escapeHtml(event.title)} at ${this.formatReminderTime(event.start_time)} overlaps with:</p>
+                        ${conflictListHtml}
+                        <p class="text-yellow-200/80 text-sm mt-3">${this.escapeHtml(question)}</p>
+                    </div>
+                </div>
+            </div>
+        `;
+        this.appendMessage(html);
+
+        const others = data.conflicts.length > 1 ? ` and ${data.conflicts.length - 1} more` : '';
+        this.speech.speak(`${event.title} overlaps with ${first.title}${others}. ${question}`);
+    }
+
+    formatDuration(minutes) {
+        if (minutes >= 60 && minutes % 60 === 0) {
+            const hours = minutes / 60;
+            return hours === 1 ? '1 hour' : `${hours} hours`;
+        }
+        return minutes === 1 ? '1 minute' : `${minutes} minutes`;
+    }
+
     formatReminderTime(isoString) {
         try {
             const date = new Date(isoString);
@@ -1302,6 +1518,29 @@ async function makeGameMove(move) {
     }
 }
 
+// Snooze or acknowledge a reminder from its trigger buttons
+async function respondToReminder(id, action, button) {
+    // Disable both buttons so a reminder is only answered once
+    button.parentElement.querySelectorAll('button').forEach(b => b.disabled = true);
+
+    try {
+        const response = await fetch(`/api/reminders/${encodeURIComponent(id)}/${action}`, { method: 'POST' });
+        if (!response.ok) {
+            throw new Error(await response.text());
+        }
+        const reminder = await response.json();
+        if (action === 'snooze') {
+            const until = new Date(reminder.snoozed_until).toLocaleTimeString([], { hour: '2-digit', minute: '2-digit' });
+            window.pikaApp.addPikaMessage(`Snoozed until ${until}.`, 'helpful');
+        } else {
+            window.pikaApp.addPikaMessage('Got it.', 'helpful');
+        }
+    } catch (error) {
+        console.error('Reminder response failed:', error);
+        window.pikaApp.addErrorMessage('Could not update the reminder.');
+    }
+}
+
 // Load saved settings
 function loadVoiceSettings() {
     const savedRate = localStorage.getItem('pika_voice_rate');
@@ -1341,17 +1580,36 @@ setTimeout(() => {
     loadVoiceSettings();
 }, 500);
 
+// Show the calendar connect notice; reconnect asks to connect again after
+// Google access was revoked or expired, even if the notice was dismissed
+function showCalendarNotice(reconnect) {
+    const notice = document.getElementById('calendar-notice');
+    if (!notice) return;
+    if (reconnect) {
+        localStorage.removeItem('pika_calendar_notice_dismissed');
+        const title = notice.querySelector('h3');
+        const text = notice.querySelector('p');
+        if (title) title.textContent = 'Reconnect Calendar';
+        if (text) text.textContent = 'Google Calendar access was revoked or expired.';
+    }
+    notice.classList.remove('hidden');
+}
+
 // Check calendar status
 async function checkCalendarAndShowNotice() {
-    if (localStorage.getItem('pika_calendar_notice_dismissed')) return;
-
     try {
         const response = await fetch('/api/status');
         const status = await response.json();
 
-        if (!status.calendar_connected) {
-            const notice = document.getElementById('calendar-notice');
-            if (notice) notice.classList.remove('hidden');
+        if (status.calendar_reauth_required) {
+            showCalendarNotice(true);
+            return;
+        }
+        if (localStorage.getItem('pika_calendar_notice_dismissed')) return;
+
+        // Only Google needs connecting from here; CalDAV is set up in config
+        if (!status.calendar_connected && status.calendar_backend === 'google') {
+            showCalendarNotice(false);
         }
     } catch (error) {
         console.error('Failed to check calendar status:', error);